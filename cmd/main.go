@@ -6,10 +6,14 @@ import (
 	"log"
 	"net/url"
 
+	"fverify_be/internal/audit"
 	"fverify_be/internal/auth"
 	"fverify_be/internal/controllers"
+	"fverify_be/internal/models"
 	"fverify_be/internal/repositories"
 	"fverify_be/internal/services"
+	"fverify_be/internal/sms"
+	"fverify_be/internal/storage"
 
 	"fverify_be/cmd/docs"
 
@@ -39,6 +43,15 @@ func main() {
 		log.Fatalf("Error reading config file: %v", err)
 	}
 
+	// Load the JWT signing keys (see auth.signingKeys)
+	if err := auth.LoadSigningKeys(); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	// Load the key of stored one-time code hashes (see auth.codeHashSecret)
+	if err := services.LoadCodeHashKey(); err != nil {
+		log.Fatalf("Failed to load one-time code hash key: %v", err)
+	}
+
 	// Get MongoDB credentials from config
 	username := viper.GetString("mongodb.username")
 	password := viper.GetString("mongodb.password")
@@ -72,16 +85,91 @@ func main() {
 	prospectRepo := repositories.NewProspectRepository(client, "fverify_db", "prospects")
 	userRepo := repositories.NewUserRepository(client, "fverify_db", "users")
 	orgRepo := repositories.NewOrganisationRepository(client, "fverify_db", "orgs")
+	reportRepo := repositories.NewReportRepository(client, "fverify_db", "reports")
+	sessionRepo := repositories.NewSessionRepository(client, "fverify_db", "sessions")
+	loginAttemptRepo := repositories.NewLoginAttemptRepository(client, "fverify_db", "login_attempts")
+	apiKeyRepo := repositories.NewAPIKeyRepository(client, "fverify_db", "api_keys")
+	roleRepo := repositories.NewCustomRoleRepository(client, "fverify_db", "custom_roles")
+	auditRepo := repositories.NewAuditRepository(client, "fverify_db", "audit_events")
+	historyAnchorRepo := repositories.NewHistoryAnchorRepository(client, "fverify_db", "history_anchors")
+
+	// Enforce per-organisation uniqueness of userid and username
+	if err := userRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create user indexes: %v", err)
+	}
+	if err := prospectRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create prospect indexes: %v", err)
+	}
+	if err := sessionRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create session indexes: %v", err)
+	}
+	if err := loginAttemptRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create login attempt indexes: %v", err)
+	}
+	if err := apiKeyRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create API key indexes: %v", err)
+	}
+	if err := roleRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create custom role indexes: %v", err)
+	}
+	if err := auditRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create audit indexes: %v", err)
+	}
+	if err := historyAnchorRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create history anchor indexes: %v", err)
+	}
+
+	// Initialize media storage (local filesystem or S3-compatible, see storage.backend)
+	mediaStorage, err := storage.NewFromConfig(context.TODO())
+	if err != nil {
+		log.Fatalf("Failed to initialise media storage: %v", err)
+	}
+
+	// Initialize the SMS sender for login codes (server log, file or HTTP gateway, see sms.backend)
+	smsSender, err := sms.NewFromConfig()
+	if err != nil {
+		log.Fatalf("Failed to initialise SMS sender: %v", err)
+	}
+	if smsSender == nil {
+		log.Println("sms.backend is not configured, SMS login is disabled")
+	}
 
 	// Initialize services
-	prospectService := services.NewProspectService(prospectRepo)
-	userService := services.NewUserService(userRepo)
+	assignmentService := services.NewAssignmentService(prospectRepo, userRepo, orgRepo)
+	prospectService := services.NewProspectService(prospectRepo, userRepo, assignmentService)
+	loginGuard := services.NewLoginGuard(loginAttemptRepo, userRepo)
+	userService := services.NewUserService(userRepo, loginGuard, services.NewPasswordPolicies(orgRepo))
 	orgService := services.NewOrganisationService(orgRepo, userRepo)
+	sessionService := services.NewSessionService(sessionRepo, userRepo, orgRepo)
+	twoFactorService := services.NewTwoFactorService(userRepo, orgRepo, loginGuard)
+	otpLoginService := services.NewOTPLoginService(userRepo, loginGuard, smsSender)
+	mediaService := services.NewMediaService(prospectRepo, mediaStorage, services.NewGeocoderFromConfig())
+	visitService := services.NewVisitService(prospectRepo)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	roleService := services.NewRoleService(roleRepo, userRepo)
+	auditService := services.NewAuditService(auditRepo)
+	historyService := services.NewHistoryService(prospectRepo, historyAnchorRepo)
+	reportService := services.NewReportService(prospectRepo, reportRepo, orgRepo, mediaStorage)
+
+	// Assign organisations to prospects created before org scoping
+	if err := prospectService.BackfillOrgUUID(context.TODO()); err != nil {
+		log.Printf("Failed to backfill prospect organisations: %v", err)
+	}
+
+	// Anchor the heads of prospect history chains every history.anchorIntervalMinutes
+	go historyService.RunAnchoring(context.Background())
 
 	// Initialize controllers
 	prospectController := controllers.NewProspectController(prospectService)
-	userController := controllers.NewUserController(userService, orgService)
-	organisationController := controllers.NewOrganisationController(orgService)
+	userController := controllers.NewUserController(userService, orgService, sessionService, twoFactorService, otpLoginService, roleService)
+	organisationController := controllers.NewOrganisationController(orgService, roleService)
+	mediaController := controllers.NewMediaController(mediaService)
+	reportController := controllers.NewReportController(reportService)
+	visitController := controllers.NewVisitController(visitService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	roleController := controllers.NewRoleController(roleService)
+	auditController := controllers.NewAuditController(auditService)
+	historyController := controllers.NewHistoryController(historyService)
 
 	// Set up Gin router
 	router := gin.Default()
@@ -105,29 +193,71 @@ func main() {
 	// Swagger endpoint
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", userController.GetJWKS)
+
 	api := router.Group("/api/v1")
+	// Record every request that changes data or authenticates a user
+	api.Use(audit.Middleware(auditRepo))
 	{
 		api.POST("/organisations", auth.OrgAPIKeyMiddleware(), organisationController.CreateOrganisation)
 		api.PUT("/organisations/:org_id", auth.OrgAPIKeyMiddleware(), organisationController.UpdateOrganisation)
 		// api.DELETE("/organisations/:org_id", auth.OrgAPIKeyMiddleware(), organisationController.DeleteOrganisation)
 		api.GET("/organisations", auth.OrgAPIKeyMiddleware(), organisationController.GetAllOrganisations)
 		api.POST("/users/login", userController.LoginUser)
-		api.POST("/users", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead"), userController.CreateUser)
-		api.PUT("/users/uid/:uId", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive"), userController.UpdateUser)
-		api.GET("/users", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive"), userController.GetAllUsers)
-		api.GET("/users/:userId", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive"), userController.GetUserByUserID)
-		// api.DELETE("/users/uid/:uId", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner"), userController.DeleteUserByUId)
-		// api.DELETE("/users/userid/:userId", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner"), userController.DeleteUserByUserId)
-		// api.PUT("/users/uid/:uId/setpassword", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive"), userController.SetPassword)
+		api.POST("/users/login/2fa", userController.CompleteTwoFactorLogin)
+		api.POST("/users/login/2fa/enrol", userController.StartLoginEnrolment)
+		api.POST("/users/login/otp/request", userController.RequestLoginOTP)
+		api.POST("/users/login/otp/verify", userController.VerifyLoginOTP)
+		api.POST("/users/token/refresh", userController.RefreshToken)
+		api.POST("/users/logout", userController.Logout)
+		api.POST("/users", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserCreate), userController.CreateUser)
+		api.PUT("/users/uid/:uId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserUpdate), userController.UpdateUser)
+		api.POST("/users/uid/:uId/unlock", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserUnlock), userController.UnlockUser)
+		api.GET("/users", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserRead), userController.GetAllUsers)
+		api.GET("/users/:userId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserRead), userController.GetUserByUserID)
+		// api.DELETE("/users/uid/:uId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserUpdate), userController.DeleteUserByUId)
+		// api.DELETE("/users/userid/:userId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserUpdate), userController.DeleteUserByUserId)
+		api.POST("/users/uid/:uId/password/reset", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserPasswordReset), userController.IssuePasswordReset)
+		api.POST("/users/password/change", auth.PasswordChangeMiddleware(*orgRepo, *userRepo), userController.ChangePassword)
+		api.POST("/users/password/reset", userController.ResetPassword)
+		api.POST("/users/2fa/enrol", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermAccountTwoFactor), userController.StartTwoFactorEnrolment)
+		api.POST("/users/2fa/confirm", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermAccountTwoFactor), userController.ConfirmTwoFactorEnrolment)
+		api.POST("/users/2fa/recovery-codes", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermAccountTwoFactor), userController.RegenerateRecoveryCodes)
+		api.POST("/users/uid/:uId/2fa/reset", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserTwoFactorReset), userController.ResetTwoFactor)
+		api.POST("/api-keys", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermAPIKeyManage), apiKeyController.CreateAPIKey)
+		api.GET("/api-keys", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermAPIKeyManage), apiKeyController.GetAPIKeys)
+		api.DELETE("/api-keys/:keyId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermAPIKeyManage), apiKeyController.RevokeAPIKey)
+		api.POST("/roles", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermRoleManage), roleController.CreateRole)
+		api.GET("/roles", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermRoleManage), roleController.GetRoles)
+		api.PUT("/roles/:roleId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermRoleManage), roleController.UpdateRole)
+		api.DELETE("/roles/:roleId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermRoleManage), roleController.DeleteRole)
+		api.GET("/audit-events", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermAuditRead), auditController.GetAuditEvents)
 		api.POST("/users/admin/create", auth.APIKeyMiddleware(), userController.CreateAdmin)
 		api.POST("/users/owner/create", auth.APIKeyMiddleware(), userController.CreateOwner)
-		api.GET("/users/roles", userController.GetUserRoles)
+		api.GET("/users/roles", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermUserRead), userController.GetUserRoles)
 		api.GET("/users/statuses", userController.GetUserStatuses)
-		api.POST("/prospects", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive", "Field Lead"), prospectController.CreateProspect)
-		api.GET("/prospects/:uid", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive", "Field Lead", "Field Executive"), prospectController.GetProspect)
-		api.PUT("/prospects/:uid", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive", "Field Lead", "Field Executive"), prospectController.UpdateProspect)
-		api.GET("/prospects", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive", "Field Lead", "Field Executive"), prospectController.GetProspects)
-		api.GET("/prospects/count", auth.AuthMiddleware(*orgRepo, *userRepo, "Admin", "Owner", "Operations Lead", "Operations Executive", "Field Lead", "Field Executive"), prospectController.GetProspectsCount)
+		api.POST("/prospects", auth.AuthOrAPIKeyMiddleware(*orgRepo, *userRepo, roleRepo, apiKeyRepo, models.PermProspectCreate), prospectController.CreateProspect)
+		api.GET("/prospects/:uid", auth.AuthOrAPIKeyMiddleware(*orgRepo, *userRepo, roleRepo, apiKeyRepo, models.PermProspectRead), prospectController.GetProspect)
+		api.PUT("/prospects/:uid", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectUpdate), prospectController.UpdateProspect)
+		api.POST("/prospects/:uid/transitions", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectRead), prospectController.TransitionProspect)
+		api.POST("/prospects/auto-assign", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectAssign), prospectController.AutoAssignProspects)
+		api.POST("/prospects/:uid/assign", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectAssign), prospectController.AssignProspect)
+		api.POST("/prospects/:uid/reassign", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectAssign), prospectController.ReassignProspect)
+		api.POST("/prospects/:uid/unassign", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectAssign), prospectController.UnassignProspect)
+		api.POST("/prospects/:uid/media", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermMediaUpload), mediaController.UploadMedia)
+		api.GET("/prospects/:uid/media", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectRead), mediaController.ListMedia)
+		api.GET("/prospects/:uid/media/:mediaId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectRead), mediaController.DownloadMedia)
+		api.DELETE("/prospects/:uid/media/:mediaId", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermMediaDelete), mediaController.DeleteMedia)
+		api.POST("/prospects/:uid/visits", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectVisit), visitController.CheckIn)
+		api.POST("/prospects/:uid/visits/:visitId/check-out", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectVisit), visitController.CheckOut)
+		api.GET("/prospects/:uid/visits", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectRead), visitController.ListVisits)
+		api.GET("/prospects/:uid/history/verify", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermProspectRead), historyController.VerifyProspectHistory)
+		api.GET("/prospects/:uid/report.pdf", auth.AuthMiddleware(*orgRepo, *userRepo, roleRepo, models.PermReportGenerate), reportController.GetProspectReport)
+		api.GET("/reports/:reportId/verify", reportController.VerifyReport)
+		api.POST("/reports/verify", reportController.VerifyReportFile)
+		api.GET("/prospects", auth.AuthOrAPIKeyMiddleware(*orgRepo, *userRepo, roleRepo, apiKeyRepo, models.PermProspectRead), prospectController.GetProspects)
+		api.GET("/prospects/count", auth.AuthOrAPIKeyMiddleware(*orgRepo, *userRepo, roleRepo, apiKeyRepo, models.PermProspectRead), prospectController.GetProspectsCount)
 	}
 
 	// Start the server
@@ -0,0 +1,110 @@
+// Package audit records every request that changes data or authenticates a user in the append-only
+// audit collection. Middleware writes one event per request; handlers add what only they know, such as
+// the record a request created and the fields it changed.
+package audit
+
+import (
+	"context"
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// contextKey is the gin context key of the event of the current request
+const contextKey = "auditEvent"
+
+// Middleware records an audit event for every request with a method that can change data, whatever its
+// outcome. The actor is taken from the authenticated user unless the handler named one with SetActor.
+func Middleware(repo *repositories.AuditRepositoryImpl) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		event := &models.AuditEvent{
+			EventId:   uuid.New().String(),
+			Time:      time.Now().UTC().Format(time.RFC3339),
+			Action:    c.Request.Method + " " + c.FullPath(),
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+		c.Set(contextKey, event)
+
+		c.Next()
+
+		event.Status = c.Writer.Status()
+		if claims, ok := c.Get("user"); ok {
+			authUser := claims.(*auth.AuthTokenClaims)
+			event.OrgUUID = authUser.OrgUUID
+			event.ActorId = authUser.UId
+			event.Actor = authUser.Username
+		}
+		if event.EntityType == "" {
+			event.EntityType = entityType(c.FullPath())
+		}
+		if event.EntityId == "" && len(c.Params) > 0 {
+			event.EntityId = c.Params[0].Value
+		}
+
+		// The request may already be cancelled once the response is written
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := repo.Insert(ctx, event); err != nil {
+			log.Printf("Failed to record audit event %s %s: %v", event.Action, event.EntityId, err)
+		}
+	}
+}
+
+// SetActor names the organisation and actor of a request made without an access token, such as a login.
+// actorId may be empty while the user is not yet known.
+func SetActor(c *gin.Context, orgUUID string, actorId string, actor string) {
+	if event := current(c); event != nil {
+		event.OrgUUID = orgUUID
+		event.ActorId = actorId
+		event.Actor = actor
+	}
+}
+
+// SetEntity names the record a request acted on, when it is not the first path parameter
+func SetEntity(c *gin.Context, entityType string, entityId string) {
+	if event := current(c); event != nil {
+		event.EntityType = entityType
+		event.EntityId = entityId
+	}
+}
+
+// SetChanges records the fields that differ between a record before and after the request. Pass nil as
+// before for created records and as after for deleted ones. A request that changes several records calls
+// it once for each; their changes are recorded one after the other.
+func SetChanges(c *gin.Context, before interface{}, after interface{}) {
+	if event := current(c); event != nil {
+		event.Changes = append(event.Changes, models.Diff(before, after)...)
+	}
+}
+
+func current(c *gin.Context) *models.AuditEvent {
+	event, ok := c.Get(contextKey)
+	if !ok {
+		return nil
+	}
+	return event.(*models.AuditEvent)
+}
+
+// entityType is the first segment of a route after the API version, e.g. prospects for
+// /api/v1/prospects/:uid/media
+func entityType(route string) string {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	if len(segments) >= 3 && segments[0] == "api" {
+		return segments[2]
+	}
+	return strings.Join(segments, "/")
+}
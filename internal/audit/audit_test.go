@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"fverify_be/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEntityType(t *testing.T) {
+	tests := []struct {
+		route string
+		want  string
+	}{
+		{"/api/v1/prospects", "prospects"},
+		{"/api/v1/prospects/:uid/media", "prospects"},
+		{"/api/v1/users/login", "users"},
+		{"/health", "health"},
+		{"/api", "api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			if got := entityType(tt.route); got != tt.want {
+				t.Errorf("entityType(%q) = %q, want %q", tt.route, got, tt.want)
+			}
+		})
+	}
+}
+
+type record struct {
+	Name     string `bson:"name"`
+	Password string `bson:"password,omitempty"`
+}
+
+func TestSetChanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls [][2]interface{}
+		want  []models.FieldChange
+	}{
+		{
+			name:  "update",
+			calls: [][2]interface{}{{&record{Name: "a"}, &record{Name: "b"}}},
+			want:  []models.FieldChange{{Field: "name", Old: "a", New: "b"}},
+		},
+		{
+			name:  "masked field",
+			calls: [][2]interface{}{{&record{Name: "a", Password: "old"}, &record{Name: "a", Password: "new"}}},
+			want:  []models.FieldChange{{Field: "password", Old: models.MaskedValue, New: models.MaskedValue}},
+		},
+		{
+			name: "several records",
+			calls: [][2]interface{}{
+				{nil, &record{Name: "created"}},
+				{&record{Name: "deleted"}, nil},
+			},
+			want: []models.FieldChange{
+				{Field: "name", Old: nil, New: "created"},
+				{Field: "name", Old: "deleted", New: nil},
+			},
+		},
+		{
+			name:  "unchanged",
+			calls: [][2]interface{}{{&record{Name: "a"}, &record{Name: "a"}}},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			event := &models.AuditEvent{}
+			c.Set(contextKey, event)
+			for _, call := range tt.calls {
+				SetChanges(c, call[0], call[1])
+			}
+			if !reflect.DeepEqual(event.Changes, tt.want) {
+				t.Errorf("Changes = %+v, want %+v", event.Changes, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetChangesWithoutEvent(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	SetChanges(c, &record{Name: "a"}, &record{Name: "b"})
+	if _, ok := c.Get(contextKey); ok {
+		t.Error("SetChanges created an event outside the audit middleware")
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const (
+	// APIKeyPrefix starts every organisation API key, so leaked keys are easy to recognise
+	APIKeyPrefix = "fvk_"
+	// APIKeyActorPrefix marks the user name and uid of requests made with an API key
+	APIKeyActorPrefix = "api-key:"
+	// apiKeyDisplayLength is the number of characters of a key kept to recognise it by
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+)
+
+// GenerateAPIKey returns a new random API key, its stored hash and the prefix shown in listings
+func GenerateAPIKey() (key string, keyHash string, prefix string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashAPIKey(key), key[:apiKeyDisplayLength], nil
+}
+
+// HashAPIKey returns the hash an API key is stored and looked up by
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
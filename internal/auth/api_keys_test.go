@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, keyHash, prefix, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix) || len(key) != len(APIKeyPrefix)+43 {
+		t.Errorf("key %q is not %s followed by 32 random bytes", key, APIKeyPrefix)
+	}
+	if keyHash != HashAPIKey(key) {
+		t.Errorf("stored hash does not match HashAPIKey of the key")
+	}
+	if strings.Contains(keyHash, key[len(APIKeyPrefix):]) {
+		t.Errorf("stored hash contains the key")
+	}
+	if prefix != key[:apiKeyDisplayLength] {
+		t.Errorf("prefix = %q, want the first %d characters of the key", prefix, apiKeyDisplayLength)
+	}
+	if other, _, _, _ := GenerateAPIKey(); other == key {
+		t.Errorf("two generated keys are equal")
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"empty", "", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"key", "fvk_test", "d29e1d13ec729c7e9e986a2d89ebb620ef6abc0882808f6c69640105a6a24a6c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HashAPIKey(tt.key)
+			if got != tt.want || len(got) != 64 {
+				t.Errorf("HashAPIKey(%q) = %s, want %s", tt.key, got, tt.want)
+			}
+		})
+	}
+	if HashAPIKey("fvk_test") == HashAPIKey("fvk_Test") {
+		t.Errorf("keys differing in case share a hash")
+	}
+}
@@ -1,15 +1,33 @@
 package auth
 
 import (
+	"crypto/subtle"
+	"fverify_be/internal/models"
 	"fverify_be/internal/repositories"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 )
 
-func AuthMiddleware(orgRepo repositories.OrganisationRepositoryImpl, userRepo repositories.UserRepositoryImpl, requiredRoles ...string) gin.HandlerFunc {
+// AuthMiddleware admits users whose role holds the permission in their organisation's role policy,
+// including the organisation's custom roles
+func AuthMiddleware(orgRepo repositories.OrganisationRepositoryImpl, userRepo repositories.UserRepositoryImpl, roleRepo *repositories.CustomRoleRepositoryImpl, permission models.Permission) gin.HandlerFunc {
+	return authenticate(orgRepo, userRepo, roleRepo, false, permission)
+}
+
+// PasswordChangeMiddleware authenticates users of any role for changing their own password, including
+// users who must change their password before they can use any other endpoint
+func PasswordChangeMiddleware(orgRepo repositories.OrganisationRepositoryImpl, userRepo repositories.UserRepositoryImpl) gin.HandlerFunc {
+	return authenticate(orgRepo, userRepo, nil, true, "")
+}
+
+// authenticate checks the bearer token and the user's permission. The role repository is not used when
+// authenticating for a password change, which needs no permission.
+func authenticate(orgRepo repositories.OrganisationRepositoryImpl, userRepo repositories.UserRepositoryImpl, roleRepo *repositories.CustomRoleRepositoryImpl, passwordChange bool, permission models.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract the token from the Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -59,8 +77,8 @@ func AuthMiddleware(orgRepo repositories.OrganisationRepositoryImpl, userRepo re
 			return
 		}
 
-		// Step 4: Get user from claims.UserId
-		user, err := userRepo.GetByUserID(c.Request.Context(), claims.UserId)
+		// Step 4: Get user from claims.UserId within the token's organisation
+		user, err := userRepo.GetByUserID(c.Request.Context(), claims.OrgUUID, claims.UserId)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 			c.Abort()
@@ -74,28 +92,71 @@ func AuthMiddleware(orgRepo repositories.OrganisationRepositoryImpl, userRepo re
 			return
 		}
 
-		// Check if the user's role is allowed
-		for _, role := range requiredRoles {
-			if claims.Role == role {
-				// Add user and org details to the context
-				c.Set("user", claims)
-				c.Set("org", org)
-				c.Next()
-				return
-			}
+		// Step 6: Reject tokens issued before the user was last changed
+		if claims.TokenVersion != user.TokenVersion {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked, refresh it or log in again"})
+			c.Abort()
+			return
+		}
+
+		// Authorize against the live role rather than the one captured in the token
+		claims.Role = string(user.Role)
+		claims.Status = string(user.Status)
+
+		if passwordChange {
+			c.Set("user", claims)
+			c.Set("org", org)
+			c.Next()
+			return
+		}
+
+		// Step 7: Users whose password was set by someone else must change it first
+		if user.MustChangePassword {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password change required", "must_change_password": true})
+			c.Abort()
+			return
+		}
+
+		// Step 8: Check that the user's role holds the permission
+		customRoles, err := roleRepo.GetAllByOrg(c.Request.Context(), org.OrgUUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load organisation roles"})
+			c.Abort()
+			return
+		}
+		policy := org.RolePolicy(customRoles)
+		if !policy.Allows(user.Role, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions to access this resource", "permission": permission})
+			c.Abort()
+			return
 		}
 
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions to access this resource"})
-		c.Abort()
+		// Add user, org and role policy to the context
+		c.Set("user", claims)
+		c.Set("org", org)
+		c.Set("policy", policy)
+		c.Next()
 	}
 }
 
+// APIKeyMiddleware admits platform requests, such as creating an organisation's first users, carrying
+// the global apikeys.userAPIKey. Organisations use their own API keys, see AuthOrAPIKeyMiddleware.
 func APIKeyMiddleware() gin.HandlerFunc {
+	return platformKeyMiddleware("apikeys.userAPIKey")
+}
+
+// OrgAPIKeyMiddleware admits organisation management requests carrying the global apikeys.orgAPIKey
+func OrgAPIKeyMiddleware() gin.HandlerFunc {
+	return platformKeyMiddleware("apikeys.orgAPIKey")
+}
+
+func platformKeyMiddleware(configKey string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		apiKey := viper.GetString("apikeys.userAPIKey")
+		apiKey := viper.GetString(configKey)
 		// Extract the API key from the header
 		providedKey := c.GetHeader("X-API-Key")
-		if providedKey != apiKey {
+		// An unset key admits nobody; compare in constant time so the key cannot be guessed byte by byte
+		if apiKey == "" || subtle.ConstantTimeCompare([]byte(providedKey), []byte(apiKey)) != 1 {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 			c.Abort()
 			return
@@ -104,16 +165,59 @@ func APIKeyMiddleware() gin.HandlerFunc {
 	}
 }
 
-func OrgAPIKeyMiddleware() gin.HandlerFunc {
+// AuthOrAPIKeyMiddleware admits users whose role holds the permission, like AuthMiddleware, and requests
+// carrying an organisation API key in X-API-Key that was granted it. Requests made with a key act as a
+// user named api-key:<name> without a role.
+func AuthOrAPIKeyMiddleware(orgRepo repositories.OrganisationRepositoryImpl, userRepo repositories.UserRepositoryImpl, roleRepo *repositories.CustomRoleRepositoryImpl, apiKeyRepo *repositories.APIKeyRepositoryImpl, permission models.Permission) gin.HandlerFunc {
+	userAuth := authenticate(orgRepo, userRepo, roleRepo, false, permission)
 	return func(c *gin.Context) {
-		orgAPIKey := viper.GetString("apikeys.orgAPIKey")
-		// Extract the API key from the header
 		providedKey := c.GetHeader("X-API-Key")
-		if providedKey != orgAPIKey {
+		if providedKey == "" || c.GetHeader("Authorization") != "" {
+			userAuth(c)
+			return
+		}
+
+		key, err := apiKeyRepo.GetActiveByHash(c.Request.Context(), HashAPIKey(providedKey))
+		if err != nil || !key.Active(time.Now().UTC()) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			c.Abort()
+			return
+		}
+
+		org, err := orgRepo.GetOrganisationByUUID(c.Request.Context(), key.OrgUUID)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 			c.Abort()
 			return
 		}
+		// The key decides the organisation; an org_id header, if sent, must name the same one
+		if orgId := c.GetHeader("org_id"); orgId != "" && orgId != org.OrgId {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Access denied: Organisation mismatch"})
+			c.Abort()
+			return
+		}
+		if org.Status != models.OrgActive {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Inactive organisation"})
+			c.Abort()
+			return
+		}
+		if !key.Allows(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + string(permission) + " permission"})
+			c.Abort()
+			return
+		}
+
+		if err := apiKeyRepo.Touch(c.Request.Context(), key.KeyId, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			log.Printf("Failed to record use of API key %s: %v", key.KeyId, err)
+		}
+		c.Set("user", &AuthTokenClaims{
+			UId:      APIKeyActorPrefix + key.KeyId,
+			Username: APIKeyActorPrefix + key.Name,
+			Status:   string(models.Active),
+			OrgUUID:  key.OrgUUID,
+		})
+		c.Set("org", org)
+		c.Set("apiKey", key)
 		c.Next()
 	}
 }
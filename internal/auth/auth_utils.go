@@ -5,10 +5,20 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("your_secret_key") // Replace with a secure secret key
+const defaultAccessTokenTTLMinutes = 15
+
+// AccessTokenTTL is the lifetime of access tokens, configured by auth.accessTokenTTLMinutes
+func AccessTokenTTL() time.Duration {
+	minutes := viper.GetInt("auth.accessTokenTTLMinutes")
+	if minutes <= 0 {
+		minutes = defaultAccessTokenTTLMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
 
 type AuthTokenClaims struct {
 	UserId       string `json:"user_id"`
@@ -18,11 +28,14 @@ type AuthTokenClaims struct {
 	Status       string `json:"status"`
 	MobileNumber string `json:"mobile_number"`
 	OrgUUID      string `json:"org_uuid"`
+	SessionId    string `json:"sid"`
+	TokenVersion int    `json:"tver"`
 	jwt.RegisteredClaims
 }
 
-// GenerateAuthToken generates a JWT token for the user
-func GenerateAuthToken(userId, username, uid, role, status, mobileNumber, orgUUID string) (string, error) {
+// GenerateAuthToken generates a short-lived access token for the user's session. The token version must
+// match the user's current token version for the token to be accepted.
+func GenerateAuthToken(userId, username, uid, role, status, mobileNumber, orgUUID, sessionId string, tokenVersion int) (string, error) {
 	claims := AuthTokenClaims{
 		UserId:       userId,
 		UId:          uid,
@@ -31,20 +44,20 @@ func GenerateAuthToken(userId, username, uid, role, status, mobileNumber, orgUUI
 		Status:       status,
 		MobileNumber: mobileNumber,
 		OrgUUID:      orgUUID,
+		SessionId:    sessionId,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(90 * 24 * time.Hour)), // Token valid for 24 hours
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signToken(claims)
 }
 
 // ParseAuthToken parses and validates a JWT token
 func ParseAuthToken(tokenString string) (*AuthTokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &AuthTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &AuthTokenClaims{}, verificationKey)
 
 	if err != nil {
 		return nil, err
@@ -54,7 +67,53 @@ func ParseAuthToken(tokenString string) (*AuthTokenClaims, error) {
 	if !ok || !token.Valid {
 		return nil, errors.New("invalid token")
 	}
+	// Access tokens have no audience; anything else, such as a two-factor challenge, is not an access token
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// challengeAudience marks tokens that prove the password step of a two-factor login
+const challengeAudience = "two-factor-challenge"
+
+// ChallengeTTL is the time a user has to complete the second login step
+const ChallengeTTL = 5 * time.Minute
+
+// ChallengeTokenClaims identify a user who passed the password step of a two-factor login
+type ChallengeTokenClaims struct {
+	UId          string `json:"uid"`
+	OrgUUID      string `json:"org_uuid"`
+	TokenVersion int    `json:"tver"`
+	jwt.RegisteredClaims
+}
+
+// GenerateChallengeToken issues the intermediate token exchanged for access tokens with a second factor
+func GenerateChallengeToken(uid, orgUUID string, tokenVersion int) (string, error) {
+	claims := ChallengeTokenClaims{
+		UId:          uid,
+		OrgUUID:      orgUUID,
+		TokenVersion: tokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ChallengeTTL)),
+		},
+	}
+	return signToken(claims)
+}
 
+// ParseChallengeToken parses and validates a two-factor challenge token
+func ParseChallengeToken(tokenString string) (*ChallengeTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ChallengeTokenClaims{}, verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*ChallengeTokenClaims)
+	if !ok || !token.Valid || !claims.VerifyAudience(challengeAudience, true) {
+		return nil, errors.New("invalid challenge token")
+	}
 	return claims, nil
 }
 
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+)
+
+// signingKeyConfig is one entry of auth.signingKeys. RS256 and ES256 keys take PEM either inline or
+// from a file; a key with only a public part verifies tokens but cannot be made active.
+type signingKeyConfig struct {
+	Kid            string `mapstructure:"kid"`
+	Alg            string `mapstructure:"alg"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKey     string `mapstructure:"privateKey"`
+	PrivateKeyFile string `mapstructure:"privateKeyFile"`
+	PublicKey      string `mapstructure:"publicKey"`
+	PublicKeyFile  string `mapstructure:"publicKeyFile"`
+}
+
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+type keyRing struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var keys *keyRing
+
+// LoadSigningKeys reads auth.signingKeys and auth.activeKid. Tokens are signed with the active key and
+// accepted from any configured key, so a new key can be added and activated before an old one is removed.
+func LoadSigningKeys() error {
+	var configs []signingKeyConfig
+	if err := viper.UnmarshalKey("auth.signingKeys", &configs); err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return errors.New("auth.signingKeys must contain at least one key")
+	}
+
+	ring := &keyRing{keys: map[string]*signingKey{}}
+	for _, config := range configs {
+		key, err := loadSigningKey(config)
+		if err != nil {
+			return fmt.Errorf("signing key %q: %w", config.Kid, err)
+		}
+		if _, exists := ring.keys[key.kid]; exists {
+			return fmt.Errorf("signing key %q is configured twice", key.kid)
+		}
+		ring.keys[key.kid] = key
+	}
+
+	activeKid := viper.GetString("auth.activeKid")
+	if activeKid == "" {
+		activeKid = configs[0].Kid
+	}
+	ring.active = ring.keys[activeKid]
+	if ring.active == nil {
+		return fmt.Errorf("auth.activeKid %q is not a configured signing key", activeKid)
+	}
+	if ring.active.signKey == nil {
+		return fmt.Errorf("active signing key %q has no private key or secret", activeKid)
+	}
+	keys = ring
+	return nil
+}
+
+func loadSigningKey(config signingKeyConfig) (*signingKey, error) {
+	if config.Kid == "" {
+		return nil, errors.New("kid is required")
+	}
+	key := &signingKey{kid: config.Kid}
+	switch config.Alg {
+	case "HS256":
+		if len(config.Secret) < 32 {
+			return nil, errors.New("HS256 secret must be at least 32 characters")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(config.Secret)
+		key.verifyKey = key.signKey
+		return key, nil
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+	case "ES256":
+		key.method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("unsupported alg %q, use HS256, RS256 or ES256", config.Alg)
+	}
+
+	privatePEM, err := pemValue(config.PrivateKey, config.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	publicPEM, err := pemValue(config.PublicKey, config.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case privatePEM != nil && config.Alg == "RS256":
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey, key.verifyKey = private, &private.PublicKey
+	case privatePEM != nil:
+		private, err := jwt.ParseECPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey, key.verifyKey = private, &private.PublicKey
+	case publicPEM != nil && config.Alg == "RS256":
+		key.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+	case publicPEM != nil:
+		key.verifyKey, err = jwt.ParseECPublicKeyFromPEM(publicPEM)
+	default:
+		return nil, errors.New("a private or public key is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ec, ok := key.verifyKey.(*ecdsa.PublicKey); ok && ec.Curve != elliptic.P256() {
+		return nil, errors.New("ES256 keys must use the P-256 curve")
+	}
+	return key, nil
+}
+
+func pemValue(inline string, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// signToken signs claims with the active key and sets its kid header
+func signToken(claims jwt.Claims) (string, error) {
+	if keys == nil {
+		return "", errors.New("signing keys are not loaded")
+	}
+	token := jwt.NewWithClaims(keys.active.method, claims)
+	token.Header["kid"] = keys.active.kid
+	return token.SignedString(keys.active.signKey)
+}
+
+// verificationKey resolves the key of a token from its kid header, rejecting a mismatched algorithm
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if keys == nil {
+		return nil, errors.New("signing keys are not loaded")
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := keys.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty" example:"RSA"`
+	Kid string `json:"kid" example:"2024-01"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"RS256"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty" example:"AQAB"`
+	Crv string `json:"crv,omitempty" example:"P-256"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the response of the JWKS endpoint.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS returns the public keys of every asymmetric signing key. HS256 secrets are never published.
+func PublicJWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if keys == nil {
+		return set
+	}
+	for kid, key := range keys.keys {
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA", Kid: kid, Use: "sig", Alg: key.method.Alg(),
+				N: base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "EC", Kid: kid, Use: "sig", Alg: key.method.Alg(), Crv: "P-256",
+				X: base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, 32))),
+				Y: base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fverify_be/internal/audit"
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type APIKeyController struct {
+	Service *services.APIKeyService
+}
+
+func NewAPIKeyController(service *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{Service: service}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key with which a partner system can call the API for the caller's organisation, sending it as X-API-Key. The key is returned only once.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param key body models.APIKeyReq true "Name, permissions and lifetime"
+// @Success 201 {object} models.APIKeyCreatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/api-keys [post]
+func (kc *APIKeyController) CreateAPIKey(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	var req models.APIKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := kc.Service.CreateKey(c.Request.Context(), authUser.OrgUUID, req, authUser.Username)
+	if errors.Is(err, services.ErrUnknownPermission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "allowed": models.APIKeyPermissions})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	audit.SetEntity(c, "api-keys", created.KeyId)
+	audit.SetChanges(c, nil, created.APIKey)
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description Retrieve the API keys of the caller's organisation, including revoked and expired keys. The keys themselves are never returned.
+// @Tags API Keys
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/api-keys [get]
+func (kc *APIKeyController) GetAPIKeys(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	keys, err := kc.Service.GetKeys(c.Request.Context(), authUser.OrgUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Stop an API key of the caller's organisation from being accepted. Revoked keys stay listed.
+// @Tags API Keys
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param keyId path string true "API key ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/api-keys/{keyId} [delete]
+func (kc *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	before, revoked, err := kc.Service.RevokeKey(c.Request.Context(), authUser.OrgUUID, c.Param("keyId"), authUser.Username)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found or already revoked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	audit.SetChanges(c, before, revoked)
+	c.JSON(http.StatusOK, SuccessResponse{Message: "API key revoked"})
+}
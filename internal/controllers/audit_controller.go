@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	Service *services.AuditService
+}
+
+func NewAuditController(service *services.AuditService) *AuditController {
+	return &AuditController{Service: service}
+}
+
+// GetAuditEvents godoc
+// @Summary Query the audit log
+// @Description Retrieve a page of the audit events of the caller's organisation, newest first: every request that changed data or authenticated a user, with its actor, client, outcome and changed fields. Follow next_cursor for subsequent pages.
+// @Tags Audit
+// @Produce json
+// @Param actor query string false "Username of the actor"
+// @Param actor_id query string false "UId of the actor"
+// @Param action query string false "Method and route, e.g. PUT /api/v1/users/uid/:uId"
+// @Param entity_type query string false "Kind of record, e.g. users or prospects"
+// @Param entity_id query string false "Identifier of the record"
+// @Param from query string false "Oldest time, inclusive (RFC 3339)"
+// @Param to query string false "Newest time, exclusive (RFC 3339)"
+// @Param skip query int false "Number of records to skip" default(0)
+// @Param limit query int false "Number of records to retrieve, at most 100" default(10)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param include_total query bool false "Include the total number of records" default(false)
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {object} models.Page[models.AuditEvent]
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/audit-events [get]
+func (ac *AuditController) GetAuditEvents(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	page, ok := pageRequest(c)
+	if !ok {
+		return
+	}
+
+	filter := models.AuditFilter{
+		Actor:      c.Query("actor"),
+		ActorId:    c.Query("actor_id"),
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityId:   c.Query("entity_id"),
+	}
+	for param, bound := range map[string]*string{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time, use RFC 3339"})
+			return
+		}
+		// Events are stored in UTC, so bounds must be too for the comparison to hold
+		*bound = parsed.UTC().Format(time.RFC3339)
+	}
+
+	events, err := ac.Service.GetEvents(c.Request.Context(), authUser.OrgUUID, filter, page)
+	if err != nil {
+		respondPageError(c, err, "Failed to retrieve audit events")
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
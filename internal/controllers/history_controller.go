@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fverify_be/internal/auth"
+	"fverify_be/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HistoryController struct {
+	Service *services.HistoryService
+}
+
+func NewHistoryController(service *services.HistoryService) *HistoryController {
+	return &HistoryController{Service: service}
+}
+
+// VerifyProspectHistory godoc
+// @Summary Verify the update history of a prospect
+// @Description Recompute the hash chain of the prospect's update history and compare it with the periodic per-organisation anchors, whose own digest chain is checked from the first anchor that recorded the prospect to the newest. The response reports the first entry that was changed, removed or inserted and the first anchor that fails; entries written before chaining was introduced are counted as unsealed. Pass a digest copied from the anchoring log to also detect a rewrite of the whole anchor chain, or compare latest_digest with the log.
+// @Tags Prospects
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param digest query string false "Anchor digest published outside the database"
+// @Success 200 {object} models.HistoryVerification
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/history/verify [get]
+func (hc *HistoryController) VerifyProspectHistory(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	verification, err := hc.Service.VerifyProspect(c.Request.Context(), authUser.OrgUUID, c.Param("uid"), c.Query("digest"))
+	if err != nil {
+		if errors.Is(err, services.ErrProspectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Prospect not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify prospect history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, verification)
+}
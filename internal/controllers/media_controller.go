@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"fverify_be/internal/audit"
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MediaController struct {
+	Service *services.MediaService
+}
+
+func NewMediaController(service *services.MediaService) *MediaController {
+	return &MediaController{Service: service}
+}
+
+// UploadMedia godoc
+// @Summary Upload files for a prospect
+// @Description Upload one or more images or documents for a prospect. The content type is detected from the file and must be JPEG, PNG or PDF; the size of each file and of the whole request is limited by configuration.
+// @Tags Media
+// @Accept multipart/form-data
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param category formData string true "Media category" Enums(ResidenceFront, ResidenceInterior, OfficeFront, OfficeInterior, IDCard, Selfie, Document, Other)
+// @Param file formData file true "File to upload (repeat for multiple files)"
+// @Success 201 {array} models.ProspectMedia
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/media [post]
+func (mc *MediaController) UploadMedia(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	uId := c.Param("uid")
+
+	limitBody(c, mc.Service.MaxRequestSize())
+	form, err := c.MultipartForm()
+	if bodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request exceeds the maximum upload size"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		return
+	}
+	files := form.File["file"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one file is required"})
+		return
+	}
+	category := models.MediaCategory(c.PostForm("category"))
+
+	uploaded := []*models.ProspectMedia{}
+	for _, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file " + fileHeader.Filename})
+			return
+		}
+		media, err := mc.Service.Upload(c.Request.Context(), authUser.OrgUUID, uId, category, fileHeader.Filename, file, fileHeader.Size, authUser.Username)
+		file.Close()
+		if err != nil {
+			respondMediaError(c, err, "Failed to upload "+fileHeader.Filename)
+			return
+		}
+		audit.SetChanges(c, nil, media)
+		uploaded = append(uploaded, media)
+	}
+
+	c.JSON(http.StatusCreated, uploaded)
+}
+
+// ListMedia godoc
+// @Summary List files of a prospect
+// @Description Retrieve the metadata of every file uploaded for a prospect
+// @Tags Media
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {array} models.ProspectMedia
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/media [get]
+func (mc *MediaController) ListMedia(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	media, err := mc.Service.ListMedia(c.Request.Context(), authUser.OrgUUID, c.Param("uid"))
+	if err != nil {
+		respondMediaError(c, err, "Failed to retrieve media")
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// DownloadMedia godoc
+// @Summary Download a file of a prospect
+// @Description Stream the content of an uploaded file
+// @Tags Media
+// @Produce octet-stream
+// @Param uid path string true "Prospect UId"
+// @Param mediaId path string true "Media UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {file} binary
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/media/{mediaId} [get]
+func (mc *MediaController) DownloadMedia(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	media, content, err := mc.Service.OpenMedia(c.Request.Context(), authUser.OrgUUID, c.Param("uid"), c.Param("mediaId"))
+	if err != nil {
+		respondMediaError(c, err, "Failed to retrieve media")
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Disposition", "inline; filename="+strconv.Quote(media.FileName))
+	c.DataFromReader(http.StatusOK, media.Size, media.ContentType, content, nil)
+}
+
+// DeleteMedia godoc
+// @Summary Delete a file of a prospect
+// @Description Remove an uploaded file from storage and from the prospect
+// @Tags Media
+// @Param uid path string true "Prospect UId"
+// @Param mediaId path string true "Media UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 204 "No Content"
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/media/{mediaId} [delete]
+func (mc *MediaController) DeleteMedia(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	deleted, err := mc.Service.DeleteMedia(c.Request.Context(), authUser.OrgUUID, c.Param("uid"), c.Param("mediaId"), authUser.Username)
+	if err != nil {
+		respondMediaError(c, err, "Failed to delete media")
+		return
+	}
+	audit.SetChanges(c, deleted, nil)
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondMediaError maps media service errors to HTTP responses
+func respondMediaError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrProspectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prospect not found"})
+	case errors.Is(err, services.ErrMediaNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+	case errors.Is(err, services.ErrInvalidMediaCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMediaTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnsupportedMediaType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"net/http"
+	"slices"
 
+	"fverify_be/internal/audit"
 	"fverify_be/internal/models"
 	"fverify_be/internal/services"
 
@@ -11,11 +13,12 @@ import (
 )
 
 type OrganisationController struct {
-	Service *services.OrganisationService
+	Service     *services.OrganisationService
+	RoleService *services.RoleService
 }
 
-func NewOrganisationController(service *services.OrganisationService) *OrganisationController {
-	return &OrganisationController{Service: service}
+func NewOrganisationController(service *services.OrganisationService, roleService *services.RoleService) *OrganisationController {
+	return &OrganisationController{Service: service, RoleService: roleService}
 }
 
 // CreateOrganisation godoc
@@ -38,10 +41,34 @@ func (oc *OrganisationController) CreateOrganisation(c *gin.Context) {
 		return
 	}
 
+	if !isValidAssignmentStrategy(reqOrg.AssignmentStrategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment strategy"})
+		return
+	}
+	if reqOrg.PasswordPolicy != nil {
+		if err := services.ValidatePasswordPolicy(reqOrg.PasswordPolicy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	// A new organisation has no custom roles yet
+	if !areValidRoles(reqOrg.TwoFactorRoles, nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role in two_factor_roles"})
+		return
+	}
+	if !isValidRolePolicy(reqOrg.RolePermissions) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role or permission in role_permissions"})
+		return
+	}
+
 	var org models.Organisation
 	org.OrgId = reqOrg.OrgId
 	org.OrgName = reqOrg.OrgName
 	org.Status = reqOrg.Status
+	org.AssignmentStrategy = reqOrg.AssignmentStrategy
+	org.PasswordPolicy = reqOrg.PasswordPolicy
+	org.TwoFactorRoles = reqOrg.TwoFactorRoles
+	org.RolePermissions = reqOrg.RolePermissions
 	org.OrgUUID = uuid.New().String()
 	// Generate a new UUID for the organisation
 	createdOrg, err := oc.Service.CreateOrganisation(c.Request.Context(), &org)
@@ -49,13 +76,16 @@ func (oc *OrganisationController) CreateOrganisation(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organisation"})
 		return
 	}
+	audit.SetActor(c, createdOrg.OrgUUID, "", "System")
+	audit.SetEntity(c, "organisations", createdOrg.OrgId)
+	audit.SetChanges(c, nil, createdOrg)
 
 	c.JSON(http.StatusCreated, createdOrg)
 }
 
 // UpdateOrganisation godoc
 // @Summary Update an organisation
-// @Description Update an existing organisation's details
+// @Description Update an existing organisation's details. Omitted assignment_strategy, password_policy, two_factor_roles and role_permissions keep their current values.
 // @Tags Organisations
 // @Accept json
 // @Produce json
@@ -77,16 +107,54 @@ func (oc *OrganisationController) UpdateOrganisation(c *gin.Context) {
 		return
 	}
 
+	if !isValidAssignmentStrategy(org.AssignmentStrategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment strategy"})
+		return
+	}
+	if org.PasswordPolicy != nil {
+		if err := services.ValidatePasswordPolicy(org.PasswordPolicy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !isValidRolePolicy(org.RolePermissions) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role or permission in role_permissions"})
+		return
+	}
+
 	// Fetch the existing organisation to validate org_uuid
 	existingOrg, err := oc.Service.GetOrganisationByID(c.Request.Context(), org_id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Organisation not found"})
 		return
 	}
+	customRoles, err := oc.RoleService.GetRoles(c.Request.Context(), existingOrg.OrgUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve custom roles"})
+		return
+	}
+	if !areValidRoles(org.TwoFactorRoles, customRoles) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role in two_factor_roles"})
+		return
+	}
+	audit.SetActor(c, existingOrg.OrgUUID, "", "System")
+	before := *existingOrg
 
 	existingOrg.OrgName = org.OrgName
 	existingOrg.Status = org.Status
 	existingOrg.OrgId = org.OrgId
+	if org.AssignmentStrategy != "" {
+		existingOrg.AssignmentStrategy = org.AssignmentStrategy
+	}
+	if org.PasswordPolicy != nil {
+		existingOrg.PasswordPolicy = org.PasswordPolicy
+	}
+	if org.TwoFactorRoles != nil {
+		existingOrg.TwoFactorRoles = org.TwoFactorRoles
+	}
+	if org.RolePermissions != nil {
+		existingOrg.RolePermissions = org.RolePermissions
+	}
 
 	// Update the organisation
 	err = oc.Service.UpdateOrganisation(c.Request.Context(), org_id, existingOrg)
@@ -94,6 +162,7 @@ func (oc *OrganisationController) UpdateOrganisation(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organisation"})
 		return
 	}
+	audit.SetChanges(c, before, existingOrg)
 
 	// If the organisation status is updated to Inactive, update all users' status to Inactive
 	if org.Status == models.OrgInActive {
@@ -122,33 +191,87 @@ func (oc *OrganisationController) UpdateOrganisation(c *gin.Context) {
 func (oc *OrganisationController) DeleteOrganisation(c *gin.Context) {
 	org_id := c.Param("org_id")
 
-	err := oc.Service.DeleteOrganisation(c.Request.Context(), org_id)
+	existingOrg, err := oc.Service.GetOrganisationByID(c.Request.Context(), org_id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organisation not found"})
+		return
+	}
+	audit.SetActor(c, existingOrg.OrgUUID, "", "System")
+
+	err = oc.Service.DeleteOrganisation(c.Request.Context(), org_id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organisation"})
 		return
 	}
+	audit.SetChanges(c, existingOrg, nil)
 
 	c.Status(http.StatusNoContent)
 }
 
 // GetAllOrganisations godoc
 // @Summary Get all organisations
-// @Description Retrieve all organisations in the system
+// @Description Retrieve a page of organisations, oldest first. Follow next_cursor for subsequent pages.
 // @Tags Organisations
 // @Accept json
 // @Produce json
+// @Param skip query int false "Number of records to skip" default(0)
+// @Param limit query int false "Number of records to retrieve, at most 100" default(10)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param include_total query bool false "Include the total number of records" default(false)
 // @Param X-API-Key header string true "API key"
-// @Success 200 {array} models.Organisation
+// @Success 200 {object} models.Page[models.Organisation]
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} InvalidAPIKeyResponse
 // @Failure 404 {object} NotFoundResponse
 // @Failure 500 {object} InternalErrorResponse
 // @Router /api/v1/organisations [get]
 func (oc *OrganisationController) GetAllOrganisations(c *gin.Context) {
-	organisations, err := oc.Service.GetAllOrganisations(c.Request.Context())
+	page, ok := pageRequest(c)
+	if !ok {
+		return
+	}
+	organisations, err := oc.Service.GetAllOrganisations(c.Request.Context(), page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organisations"})
+		respondPageError(c, err, "Failed to retrieve organisations")
 		return
 	}
 
 	c.JSON(http.StatusOK, organisations)
 }
+
+// isValidAssignmentStrategy accepts an empty strategy as manual assignment
+func isValidAssignmentStrategy(strategy models.AssignmentStrategy) bool {
+	switch strategy {
+	case "", models.AssignManual, models.AssignRoundRobin, models.AssignLeastOpenCases, models.AssignNearest:
+		return true
+	}
+	return false
+}
+
+// areValidRoles reports whether every role is a built-in role or one of the organisation's custom roles
+func areValidRoles(roles []models.Role, customRoles []models.CustomRole) bool {
+	for _, role := range roles {
+		if models.IsBuiltInRole(role) {
+			continue
+		}
+		if !slices.ContainsFunc(customRoles, func(customRole models.CustomRole) bool { return customRole.Name == role }) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidRolePolicy reports whether a role policy override names only built-in roles and known permissions
+func isValidRolePolicy(policy models.RolePolicy) bool {
+	for role, permissions := range policy {
+		if !models.IsBuiltInRole(role) {
+			return false
+		}
+		for _, permission := range permissions {
+			if !models.IsValidPermission(permission) {
+				return false
+			}
+		}
+	}
+	return true
+}
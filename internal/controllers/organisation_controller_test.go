@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"testing"
+
+	"fverify_be/internal/models"
+)
+
+func TestAreValidRoles(t *testing.T) {
+	customRoles := []models.CustomRole{{Name: "Quality Auditor"}}
+	tests := []struct {
+		name        string
+		roles       []models.Role
+		customRoles []models.CustomRole
+		want        bool
+	}{
+		{"none", nil, nil, true},
+		{"built-in roles", []models.Role{models.Owner, models.FieldExecutive}, nil, true},
+		{"custom role of the organisation", []models.Role{models.Admin, "Quality Auditor"}, customRoles, true},
+		{"custom role of no organisation", []models.Role{"Quality Auditor"}, nil, false},
+		{"unknown role", []models.Role{models.Admin, "Intern"}, customRoles, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := areValidRoles(tt.roles, tt.customRoles); got != tt.want {
+				t.Errorf("areValidRoles(%v) = %v, want %v", tt.roles, got, tt.want)
+			}
+		})
+	}
+}
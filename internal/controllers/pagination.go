@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// pageRequest reads the skip, limit, cursor and include_total query parameters shared by the list endpoints.
+// Limits above maxPageLimit are lowered to it.
+// It writes a 400 response and returns false when a parameter is invalid.
+func pageRequest(c *gin.Context) (models.PageRequest, bool) {
+	page := models.PageRequest{Limit: defaultPageLimit, Cursor: c.Query("cursor")}
+
+	if s := c.Query("skip"); s != "" {
+		parsedSkip, err := strconv.Atoi(s)
+		if err != nil || parsedSkip < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skip value"})
+			return page, false
+		}
+		page.Skip = parsedSkip
+	}
+	if l := c.Query("limit"); l != "" {
+		parsedLimit, err := strconv.Atoi(l)
+		if err != nil || parsedLimit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+			return page, false
+		}
+		page.Limit = min(parsedLimit, maxPageLimit)
+	}
+	if page.Cursor != "" && page.Skip > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use either skip or cursor, not both"})
+		return page, false
+	}
+	if t := c.Query("include_total"); t != "" {
+		includeTotal, err := strconv.ParseBool(t)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid include_total value"})
+			return page, false
+		}
+		page.IncludeTotal = includeTotal
+	}
+	return page, true
+}
+
+// respondPageError maps listing errors to HTTP responses
+func respondPageError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor, it may belong to a different sort order"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPageRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query     string
+		wantOK    bool
+		wantLimit int
+		wantSkip  int
+	}{
+		{"", true, defaultPageLimit, 0},
+		{"?limit=25&skip=50", true, 25, 50},
+		{"?limit=100", true, 100, 0},
+		{"?limit=100000000", true, maxPageLimit, 0},
+		{"?limit=0", false, 0, 0},
+		{"?limit=ten", false, 0, 0},
+		{"?skip=-1", false, 0, 0},
+		{"?skip=10&cursor=abc", false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/items"+tt.query, nil)
+
+			page, ok := pageRequest(c)
+			if ok != tt.wantOK {
+				t.Fatalf("pageRequest() ok = %v, want %v (status %d)", ok, tt.wantOK, recorder.Code)
+			}
+			if !ok {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, want 400", recorder.Code)
+				}
+				return
+			}
+			if page.Limit != tt.wantLimit || page.Skip != tt.wantSkip {
+				t.Errorf("pageRequest() = limit %d skip %d, want limit %d skip %d", page.Limit, page.Skip, tt.wantLimit, tt.wantSkip)
+			}
+		})
+	}
+}
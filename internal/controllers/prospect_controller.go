@@ -1,13 +1,16 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"fverify_be/internal/audit"
 	"fverify_be/internal/auth"
 	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
 	"fverify_be/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -28,20 +31,36 @@ func NewProspectController(service *services.ProspectService) *ProspectControlle
 
 // GetProspectsCount godoc
 // @Summary Get total count of prospects
-// @Description Retrieve the total count of prospects in the system
+// @Description Retrieve the total count of prospects in the caller's organisation matching the same filters as the prospect list
 // @Tags Prospects
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param status query []string false "Statuses to include; repeat or comma-separate for several" collectionFormat(multi)
+// @Param employment_type query string false "Employment type" Enums(Employee, Business)
+// @Param created_from query string false "Created on or after (RFC3339 or YYYY-MM-DD)"
+// @Param created_to query string false "Created on or before (RFC3339 or YYYY-MM-DD)"
+// @Param updated_from query string false "Updated on or after (RFC3339 or YYYY-MM-DD)"
+// @Param updated_to query string false "Updated on or before (RFC3339 or YYYY-MM-DD)"
+// @Param assigned_to query string false "UId of the assignee"
+// @Param assigned_to_me query bool false "Only include prospects assigned to the caller" default(false)
+// @Param created_by query string false "Username of the creator"
+// @Param q query string false "Search applicant name, mobile number and prospect ID"
+// @Param Authorization header string false "Bearer token; or send X-API-Key"
+// @Param X-API-Key header string false "Organisation API key"
 // @Param org_id header string true "Organisation Id"
 // @Success 200 {object} ProspectCountMessage
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} InternalErrorResponse
 // @Router /api/v1/prospects/count [get]
 func (pc *ProspectController) GetProspectsCount(c *gin.Context) {
-	// claims, _ := c.Get("user")
-	// authUser := claims.(*auth.AuthTokenClaims)
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	filter, ok := prospectFilter(c, authUser)
+	if !ok {
+		return
+	}
 	// Call the service to get the total count of prospects
-	count, err := pc.Service.GetProspectsCount(c.Request.Context())
+	count, err := pc.Service.GetProspectsCount(c.Request.Context(), authUser.OrgUUID, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve prospects count"})
 		return
@@ -52,47 +71,50 @@ func (pc *ProspectController) GetProspectsCount(c *gin.Context) {
 
 // GetProspects godoc
 // @Summary Get a list of prospects
-// @Description Retrieve a list of prospects with pagination using skip and limit values
+// @Description Retrieve a filtered, sorted page of prospects. Follow next_cursor for subsequent pages; skip and limit remain supported.
 // @Tags Prospects
 // @Accept json
 // @Produce json
 // @Param skip query int false "Number of records to skip" default(0)
-// @Param limit query int false "Number of records to retrieve" default(10)
-// @Param Authorization header string true "Bearer token"
+// @Param limit query int false "Number of records to retrieve, at most 100" default(10)
+// @Param cursor query string false "next_cursor of the previous page, with the same filters and sort"
+// @Param include_total query bool false "Include the total number of matching prospects" default(false)
+// @Param status query []string false "Statuses to include; repeat or comma-separate for several" collectionFormat(multi)
+// @Param employment_type query string false "Employment type" Enums(Employee, Business)
+// @Param created_from query string false "Created on or after (RFC3339 or YYYY-MM-DD)"
+// @Param created_to query string false "Created on or before (RFC3339 or YYYY-MM-DD)"
+// @Param updated_from query string false "Updated on or after (RFC3339 or YYYY-MM-DD)"
+// @Param updated_to query string false "Updated on or before (RFC3339 or YYYY-MM-DD)"
+// @Param assigned_to query string false "UId of the assignee"
+// @Param assigned_to_me query bool false "Only include prospects assigned to the caller" default(false)
+// @Param created_by query string false "Username of the creator"
+// @Param q query string false "Search applicant name, mobile number and prospect ID"
+// @Param sort_by query string false "Sort field" Enums(created_time, updated_time, applicant_name, prospect_id, status) default(created_time)
+// @Param sort_order query string false "Sort order" Enums(asc, desc) default(desc)
+// @Param Authorization header string false "Bearer token; or send X-API-Key"
+// @Param X-API-Key header string false "Organisation API key"
 // @Param org_id header string true "Organisation Id"
-// @Success 200 {array} models.Prospect
+// @Success 200 {object} models.Page[models.Prospect]
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} InternalErrorResponse
 // @Router /api/v1/prospects [get]
 func (pc *ProspectController) GetProspects(c *gin.Context) {
-	// claims, _ := c.Get("user")
-	// authUser := claims.(*auth.AuthTokenClaims)
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
 	// Parse query parameters
-	skip := 0
-	limit := 10
-
-	if s := c.Query("skip"); s != "" {
-		if parsedSkip, err := strconv.Atoi(s); err == nil {
-			skip = parsedSkip
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skip value"})
-			return
-		}
+	page, ok := pageRequest(c)
+	if !ok {
+		return
 	}
-
-	if l := c.Query("limit"); l != "" {
-		if parsedLimit, err := strconv.Atoi(l); err == nil {
-			limit = parsedLimit
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
-			return
-		}
+	filter, ok := prospectFilter(c, authUser)
+	if !ok {
+		return
 	}
 
 	// Call the service to get prospects
-	prospects, err := pc.Service.GetProspects(c.Request.Context(), skip, limit)
+	prospects, err := pc.Service.GetProspects(c.Request.Context(), authUser.OrgUUID, filter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve prospects"})
+		respondPageError(c, err, "Failed to retrieve prospects")
 		return
 	}
 
@@ -105,7 +127,8 @@ func (pc *ProspectController) GetProspects(c *gin.Context) {
 // @Tags Prospects
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string false "Bearer token; or send X-API-Key"
+// @Param X-API-Key header string false "Organisation API key"
 // @Param org_id  header string true "Organisation Id"
 // @Param prospect body models.ProspecReq true "Prospect data"
 // @Success 201 {object} models.Prospect
@@ -136,6 +159,8 @@ func (pc *ProspectController) CreateProspect(c *gin.Context) {
 		ReferenceMobile:       reqProspect.ReferenceMobile,
 		EmploymentType:        reqProspect.EmploymentType,
 		OfficeAddress:         reqProspect.OfficeAddress,
+		ResidentialLocation:   reqProspect.ResidentialLocation,
+		OfficeLocation:        reqProspect.OfficeLocation,
 		YearsInCurrentOffice:  reqProspect.YearsInCurrentOffice,
 		Role:                  reqProspect.Role,
 		EmpId:                 reqProspect.EmpId,
@@ -151,12 +176,14 @@ func (pc *ProspectController) CreateProspect(c *gin.Context) {
 	}
 	// Assign unique ID and timestamps
 	prospect.UId = uuid.New().String()
+	prospect.Status = models.Pending // Every prospect enters the verification workflow as Pending
 	prospect.NameVerified = false
 	prospect.MobileVerified = false
 	prospect.ResAddressVerified = false
 	prospect.OffAddressVerified = false
 	prospect.RoleVerified = false
 	prospect.EmpIdVerified = false
+	prospect.OrgUUID = authUser.OrgUUID
 	prospect.CreatedBy = authUser.Username
 	prospect.CreatedTime = time.Now().UTC().Format(time.RFC3339) // Get current UTC time as string
 	prospect.UpdatedBy = authUser.Username
@@ -171,6 +198,8 @@ func (pc *ProspectController) CreateProspect(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prospect"})
 		return
 	}
+	audit.SetEntity(c, "prospects", prospect.UId)
+	audit.SetChanges(c, nil, prospect)
 
 	c.JSON(http.StatusCreated, prospect)
 }
@@ -182,7 +211,8 @@ func (pc *ProspectController) CreateProspect(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param uid path string true "Prospect UID"
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string false "Bearer token; or send X-API-Key"
+// @Param X-API-Key header string false "Organisation API key"
 // @Param org_id  header string true "Organisation Id"
 // @Success 200 {object} models.Prospect
 // @Failure 400 {object} ErrorResponse
@@ -191,9 +221,9 @@ func (pc *ProspectController) CreateProspect(c *gin.Context) {
 // @Router /api/v1/prospects/{id} [get]
 func (pc *ProspectController) GetProspect(c *gin.Context) {
 	uid := c.Param("uid")
-	// claims, _ := c.Get("user")
-	// authUser := claims.(*auth.AuthTokenClaims)
-	prospect, err := pc.Service.GetProspectByID(c.Request.Context(), uid)
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	prospect, err := pc.Service.GetProspectByID(c.Request.Context(), authUser.OrgUUID, uid)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Prospect not found"})
 		return
@@ -204,7 +234,7 @@ func (pc *ProspectController) GetProspect(c *gin.Context) {
 
 // UpdateProspect godoc
 // @Summary Update an existing prospect
-// @Description Update an existing prospect in the system. Update comments are generated based on differences from the earlier prospect state.
+// @Description Update an existing prospect in the system. Update comments are generated based on differences from the earlier prospect state. The status cannot be changed here; use the transitions endpoint. Approved, Rejected, Completed and Cancelled prospects can no longer be edited. Omitted locations are kept unless the matching address changes, in which case they are cleared.
 // @Tags Prospects
 // @Accept json
 // @Produce json
@@ -215,6 +245,7 @@ func (pc *ProspectController) GetProspect(c *gin.Context) {
 // @Success 200 {object} models.Prospect
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} NotFoundResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} InternalErrorResponse
 // @Router /api/v1/prospects/{uid} [put]
 func (pc *ProspectController) UpdateProspect(c *gin.Context) {
@@ -223,7 +254,7 @@ func (pc *ProspectController) UpdateProspect(c *gin.Context) {
 	uId := c.Param("uid")
 
 	// Fetch the existing prospect
-	existingProspect, err := pc.Service.GetProspectByID(c.Request.Context(), uId)
+	existingProspect, err := pc.Service.GetProspectByID(c.Request.Context(), authUser.OrgUUID, uId)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Prospect not found"})
 		return
@@ -234,99 +265,12 @@ func (pc *ProspectController) UpdateProspect(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	before := *existingProspect
 
-	// Generate update comments by comparing the existing and new prospect data
-	// Generate update comments by comparing the existing and new prospect data
-	var updateComments []string
-	if existingProspect.ProspectId != reqProspect.ProspectId && reqProspect.ProspectId != "" {
-		updateComments = append(updateComments, "ProspectId updated")
-	}
-	if existingProspect.ApplicantName != reqProspect.ApplicantName && reqProspect.ProspectId != "" {
-		updateComments = append(updateComments, "ApplicantName updated")
-	}
-	if existingProspect.MobileNumber != reqProspect.MobileNumber && reqProspect.ProspectId != "" {
-		updateComments = append(updateComments, "MobileNumber updated")
-	}
-	if existingProspect.Gender != reqProspect.Gender && reqProspect.Gender != "" {
-		updateComments = append(updateComments, "Gender updated")
-	}
-	if existingProspect.Age != reqProspect.Age && reqProspect.Age > 0 {
-		updateComments = append(updateComments, "Age updated")
-	}
-	if existingProspect.ResidentialAddress != reqProspect.ResidentialAddress && reqProspect.ResidentialAddress != "" {
-		updateComments = append(updateComments, "ResidentialAddress updated")
-	}
-	if existingProspect.YearsOfStay != reqProspect.YearsOfStay && reqProspect.YearsOfStay > 0 {
-		updateComments = append(updateComments, "YearsOfStay updated")
-	}
-	if existingProspect.NumberOfFamilyMembers != reqProspect.NumberOfFamilyMembers && reqProspect.NumberOfFamilyMembers > 0 {
-		updateComments = append(updateComments, "NumberOfFamilyMembers updated")
-	}
-	if existingProspect.ReferenceName != reqProspect.ReferenceName && reqProspect.ReferenceName != "" {
-		updateComments = append(updateComments, "ReferenceName updated")
-	}
-	if existingProspect.ReferenceRelation != reqProspect.ReferenceRelation && reqProspect.ReferenceRelation != "" {
-		updateComments = append(updateComments, "ReferenceRelation updated")
-	}
-	if existingProspect.ReferenceMobile != reqProspect.ReferenceMobile && reqProspect.ReferenceMobile != "" {
-		updateComments = append(updateComments, "ReferenceMobile updated")
-	}
-	if existingProspect.EmploymentType != reqProspect.EmploymentType && reqProspect.EmploymentType != "" {
-		updateComments = append(updateComments, "EmploymentType updated")
-	}
-	if existingProspect.OfficeAddress != reqProspect.OfficeAddress && reqProspect.OfficeAddress != "" {
-		updateComments = append(updateComments, "OfficeAddress updated")
-	}
-	if existingProspect.YearsInCurrentOffice != reqProspect.YearsInCurrentOffice && reqProspect.YearsInCurrentOffice > 0 {
-		updateComments = append(updateComments, "YearsInCurrentOffice updated")
-	}
-	if existingProspect.Role != reqProspect.Role && reqProspect.Role != "" {
-		updateComments = append(updateComments, "Role updated")
-	}
-	if existingProspect.EmpId != reqProspect.EmpId && reqProspect.EmpId != "" {
-		updateComments = append(updateComments, "EmpId updated")
-	}
-	if existingProspect.Status != reqProspect.Status && reqProspect.Status != "" {
-		updateComments = append(updateComments, "Status updated")
-	}
-	if existingProspect.PreviousExperience != reqProspect.PreviousExperience {
-		updateComments = append(updateComments, "PreviousExperience updated")
-	}
-	if existingProspect.GrossSalary != reqProspect.GrossSalary && reqProspect.GrossSalary > 0 {
-		updateComments = append(updateComments, "GrossSalary updated")
-	}
-	if existingProspect.NetSalary != reqProspect.NetSalary && reqProspect.NetSalary > 0 {
-		updateComments = append(updateComments, "NetSalary updated")
-	}
-	if existingProspect.ColleagueName != reqProspect.ColleagueName && reqProspect.ColleagueName != "" {
-		updateComments = append(updateComments, "ColleagueName updated")
-	}
-	if existingProspect.ColleagueDesignation != reqProspect.ColleagueDesignation && reqProspect.ColleagueDesignation != "" {
-		updateComments = append(updateComments, "ColleagueDesignation updated")
-	}
-	if existingProspect.ColleagueMobile != reqProspect.ColleagueMobile && reqProspect.ColleagueMobile != "" {
-		updateComments = append(updateComments, "ColleagueMobile updated")
-	}
-	if existingProspect.Remarks != reqProspect.Remarks && reqProspect.Remarks != "" {
-		updateComments = append(updateComments, "Remarks updated")
-	}
-	if existingProspect.NameVerified != reqProspect.NameVerified {
-		updateComments = append(updateComments, "NameVerified updated")
-	}
-	if existingProspect.MobileVerified != reqProspect.MobileVerified {
-		updateComments = append(updateComments, "MobileVerified updated")
-	}
-	if existingProspect.ResAddressVerified != reqProspect.ResAddressVerified {
-		updateComments = append(updateComments, "Residential Address updated")
-	}
-	if existingProspect.OffAddressVerified != reqProspect.OffAddressVerified {
-		updateComments = append(updateComments, "Office Address updated")
-	}
-	if existingProspect.RoleVerified != reqProspect.RoleVerified {
-		updateComments = append(updateComments, "Role / Business updated")
-	}
-	if existingProspect.ResAddressVerified != reqProspect.ResAddressVerified {
-		updateComments = append(updateComments, "Employee Id / Business Id updated")
+	// Status changes go through the workflow so they are checked against the caller's role
+	if reqProspect.Status != "" && reqProspect.Status != existingProspect.Status {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status can only be changed through /prospects/{uid}/transitions"})
+		return
 	}
 
 	// Map updated fields from ProspecReq to Prospect
@@ -343,10 +287,11 @@ func (pc *ProspectController) UpdateProspect(c *gin.Context) {
 	existingProspect.ReferenceMobile = reqProspect.ReferenceMobile
 	existingProspect.EmploymentType = reqProspect.EmploymentType
 	existingProspect.OfficeAddress = reqProspect.OfficeAddress
+	existingProspect.ResidentialLocation = updatedLocation(before.ResidentialAddress, reqProspect.ResidentialAddress, before.ResidentialLocation, reqProspect.ResidentialLocation)
+	existingProspect.OfficeLocation = updatedLocation(before.OfficeAddress, reqProspect.OfficeAddress, before.OfficeLocation, reqProspect.OfficeLocation)
 	existingProspect.YearsInCurrentOffice = reqProspect.YearsInCurrentOffice
 	existingProspect.Role = reqProspect.Role
 	existingProspect.EmpId = reqProspect.EmpId
-	existingProspect.Status = reqProspect.Status
 	existingProspect.PreviousExperience = reqProspect.PreviousExperience
 	existingProspect.GrossSalary = reqProspect.GrossSalary
 	existingProspect.NetSalary = reqProspect.NetSalary
@@ -362,20 +307,316 @@ func (pc *ProspectController) UpdateProspect(c *gin.Context) {
 	existingProspect.RoleVerified = reqProspect.RoleVerified
 	existingProspect.EmpIdVerified = reqProspect.EmpIdVerified
 
-	// Update timestamps and history
+	// Update timestamps and record the changed fields in the history. Locations that were not sent are
+	// kept above and cleared ones are unset by the repository, so the prospect is stored exactly as diffed.
 	existingProspect.UpdatedBy = authUser.Username
 	existingProspect.UpdatedTime = time.Now().UTC().Format(time.RFC3339)
-	existingProspect.UpdateHistory = append(existingProspect.UpdateHistory, models.UpdateHistory{
-		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
-		UpdatedComments: strings.Join(updateComments, ", "),
-		UpdateBy:        authUser.Username,
-	})
+	existingProspect.UpdateHistory = append(existingProspect.UpdateHistory, models.NewUpdateHistory(
+		existingProspect.UpdatedTime, authUser.Username, models.Diff(before, existingProspect)))
 
 	// Call the service to update the prospect
-	if err := pc.Service.UpdateProspect(c.Request.Context(), existingProspect); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prospect"})
+	if err := pc.Service.UpdateProspect(c.Request.Context(), authUser.OrgUUID, &before, existingProspect); err != nil {
+		respondProspectError(c, err, "Failed to update prospect")
 		return
 	}
+	audit.SetChanges(c, before, existingProspect)
 
 	c.JSON(http.StatusOK, existingProspect)
 }
+
+// TransitionProspect godoc
+// @Summary Move a prospect to another workflow status
+// @Description Apply a verification workflow transition. Each transition is only allowed from specific statuses and needs its own permission; the reason is recorded in the update history.
+// @Tags Prospects
+// @Accept json
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param transition body models.ProspectTransitionReq true "Target status and reason"
+// @Success 200 {object} models.Prospect
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/transitions [post]
+func (pc *ProspectController) TransitionProspect(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	policy, _ := c.Get("policy")
+	uId := c.Param("uid")
+
+	var req models.ProspectTransitionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := pc.auditedProspect(c, authUser.OrgUUID, uId)
+	prospect, err := pc.Service.TransitionProspect(c.Request.Context(), authUser.OrgUUID, uId, req.Status, policy.(models.RolePolicy), models.Role(authUser.Role), authUser.Username, req.Reason)
+	if err != nil {
+		respondProspectError(c, err, "Failed to update prospect status")
+		return
+	}
+	audit.SetChanges(c, before, prospect)
+
+	c.JSON(http.StatusOK, prospect)
+}
+
+// AssignProspect godoc
+// @Summary Assign a prospect to a field executive
+// @Description Assign an unassigned prospect to an active field executive of the caller's organisation
+// @Tags Prospects
+// @Accept json
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param assignment body models.ProspectAssignReq true "Field executive to assign"
+// @Success 200 {object} models.Prospect
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/assign [post]
+func (pc *ProspectController) AssignProspect(c *gin.Context) {
+	pc.assign(c, false)
+}
+
+// ReassignProspect godoc
+// @Summary Reassign a prospect to another field executive
+// @Description Move an assigned prospect to a different active field executive of the caller's organisation
+// @Tags Prospects
+// @Accept json
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param assignment body models.ProspectAssignReq true "Field executive to assign"
+// @Success 200 {object} models.Prospect
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/reassign [post]
+func (pc *ProspectController) ReassignProspect(c *gin.Context) {
+	pc.assign(c, true)
+}
+
+func (pc *ProspectController) assign(c *gin.Context, reassign bool) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	uId := c.Param("uid")
+
+	var req models.ProspectAssignReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := pc.auditedProspect(c, authUser.OrgUUID, uId)
+	prospect, err := pc.Service.AssignProspect(c.Request.Context(), authUser.OrgUUID, uId, req.AssigneeUId, authUser.Username, reassign)
+	if err != nil {
+		respondProspectError(c, err, "Failed to assign prospect")
+		return
+	}
+	audit.SetChanges(c, before, prospect)
+
+	c.JSON(http.StatusOK, prospect)
+}
+
+// UnassignProspect godoc
+// @Summary Unassign a prospect
+// @Description Remove the field executive currently assigned to a prospect
+// @Tags Prospects
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {object} models.Prospect
+// @Failure 404 {object} NotFoundResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/unassign [post]
+func (pc *ProspectController) UnassignProspect(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	uId := c.Param("uid")
+
+	before := pc.auditedProspect(c, authUser.OrgUUID, uId)
+	prospect, err := pc.Service.UnassignProspect(c.Request.Context(), authUser.OrgUUID, uId, authUser.Username)
+	if err != nil {
+		respondProspectError(c, err, "Failed to unassign prospect")
+		return
+	}
+	audit.SetChanges(c, before, prospect)
+
+	c.JSON(http.StatusOK, prospect)
+}
+
+// AutoAssignProspects godoc
+// @Summary Auto-assign prospects
+// @Description Assign unassigned Pending and RePending prospects using the organisation's assignment strategy
+// @Tags Prospects
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param request body models.ProspectAutoAssignReq false "Prospect UIds to assign (all unassigned when empty)"
+// @Success 200 {array} models.Prospect
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/auto-assign [post]
+func (pc *ProspectController) AutoAssignProspects(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	var req models.ProspectAutoAssignReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	prospects, err := pc.Service.AutoAssignProspects(c.Request.Context(), authUser.OrgUUID, req.UIds, authUser.Username)
+	if err != nil {
+		respondProspectError(c, err, "Failed to auto-assign prospects")
+		return
+	}
+
+	c.JSON(http.StatusOK, prospects)
+}
+
+// auditedProspect returns a prospect as it is before a change, to record what the change did. A prospect
+// that cannot be read is left to the change itself to report.
+func (pc *ProspectController) auditedProspect(c *gin.Context, orgUUID string, uid string) *models.Prospect {
+	prospect, err := pc.Service.GetProspectByID(c.Request.Context(), orgUUID, uid)
+	if err != nil {
+		return nil
+	}
+	return prospect
+}
+
+// prospectFilter reads the list filter and sort query parameters shared by the prospect list and count endpoints.
+// It writes a 400 response and returns false when a parameter is invalid.
+func prospectFilter(c *gin.Context, authUser *auth.AuthTokenClaims) (models.ProspectFilter, bool) {
+	filter := models.ProspectFilter{
+		EmploymentType: models.EmploymentType(c.Query("employment_type")),
+		AssignedTo:     c.Query("assigned_to"),
+		CreatedBy:      c.Query("created_by"),
+		Search:         strings.TrimSpace(c.Query("q")),
+		SortBy:         c.Query("sort_by"),
+	}
+
+	for _, value := range c.QueryArray("status") {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.Statuses = append(filter.Statuses, models.ProspectStatus(status))
+			}
+		}
+	}
+	if filter.EmploymentType != "" && filter.EmploymentType != models.Employee && filter.EmploymentType != models.Business {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employment_type value"})
+		return filter, false
+	}
+
+	if mine := c.Query("assigned_to_me"); mine != "" {
+		assignedToMe, err := strconv.ParseBool(mine)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assigned_to_me value"})
+			return filter, false
+		}
+		if assignedToMe {
+			filter.AssignedTo = authUser.UId
+		}
+	}
+
+	bounds := []struct {
+		param   string
+		target  *string
+		dayEnds bool
+	}{
+		{"created_from", &filter.CreatedFrom, false},
+		{"created_to", &filter.CreatedTo, true},
+		{"updated_from", &filter.UpdatedFrom, false},
+		{"updated_to", &filter.UpdatedTo, true},
+	}
+	for _, bound := range bounds {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseTimeBound(value, bound.dayEnds)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + bound.param + " value, use RFC3339 or YYYY-MM-DD"})
+			return filter, false
+		}
+		*bound.target = parsed
+	}
+
+	if filter.SortBy != "" && !models.ProspectSortFields[filter.SortBy] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort_by value"})
+		return filter, false
+	}
+	switch c.DefaultQuery("sort_order", "desc") {
+	case "asc":
+		filter.SortAscending = true
+	case "desc":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort_order value, use asc or desc"})
+		return filter, false
+	}
+	return filter, true
+}
+
+// parseTimeBound normalises an RFC3339 timestamp or a YYYY-MM-DD date to the stored UTC RFC3339 format.
+// A date used as an upper bound covers the whole day.
+func parseTimeBound(value string, dayEnds bool) (string, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return "", err
+	}
+	if dayEnds {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+// updatedLocation keeps an unsent location unless its address changed, so a new address is geocoded again
+func updatedLocation(storedAddress string, address string, stored *models.GeoPoint, sent *models.GeoPoint) *models.GeoPoint {
+	if sent != nil {
+		return sent
+	}
+	if address != storedAddress {
+		return nil
+	}
+	return stored
+}
+
+// respondProspectError maps prospect service errors to HTTP responses
+func respondProspectError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrProspectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prospect not found"})
+	case errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, services.ErrAlreadyAssigned),
+		errors.Is(err, services.ErrNotAssigned),
+		errors.Is(err, services.ErrNoFieldExecutives),
+		errors.Is(err, services.ErrNoCompletedVisit),
+		errors.Is(err, services.ErrProspectClosed),
+		errors.Is(err, repositories.ErrProspectChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTransitionForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAssignee):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
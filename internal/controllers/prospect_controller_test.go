@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProspectFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	caller := &auth.AuthTokenClaims{UId: "user-1"}
+	tests := []struct {
+		query  string
+		wantOK bool
+		want   models.ProspectFilter
+	}{
+		{"", true, models.ProspectFilter{}},
+		{"?status=Pending,RePending&status=OnVisit", true, models.ProspectFilter{Statuses: []models.ProspectStatus{models.Pending, models.RePending, models.OnVisit}}},
+		{"?employment_type=Business&q=+john+", true, models.ProspectFilter{EmploymentType: models.Business, Search: "john"}},
+		{"?assigned_to=user-2&assigned_to_me=true", true, models.ProspectFilter{AssignedTo: "user-1"}},
+		{"?assigned_to=user-2&assigned_to_me=false", true, models.ProspectFilter{AssignedTo: "user-2"}},
+		{
+			"?created_from=2023-04-01&created_to=2023-04-30", true,
+			models.ProspectFilter{CreatedFrom: "2023-04-01T00:00:00Z", CreatedTo: "2023-04-30T23:59:59Z"},
+		},
+		{"?updated_from=2023-04-12T15:30:00%2B05:30", true, models.ProspectFilter{UpdatedFrom: "2023-04-12T10:00:00Z"}},
+		{"?sort_by=applicant_name&sort_order=asc", true, models.ProspectFilter{SortBy: "applicant_name", SortAscending: true}},
+		{"?employment_type=Retired", false, models.ProspectFilter{}},
+		{"?assigned_to_me=sometimes", false, models.ProspectFilter{}},
+		{"?created_to=12/04/2023", false, models.ProspectFilter{}},
+		{"?sort_by=password", false, models.ProspectFilter{}},
+		{"?sort_order=up", false, models.ProspectFilter{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/prospects"+tt.query, nil)
+
+			filter, ok := prospectFilter(c, caller)
+			if ok != tt.wantOK {
+				t.Fatalf("prospectFilter() ok = %v, want %v (status %d)", ok, tt.wantOK, recorder.Code)
+			}
+			if !ok {
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, want 400", recorder.Code)
+				}
+				return
+			}
+			if !reflect.DeepEqual(filter, tt.want) {
+				t.Errorf("prospectFilter() = %+v, want %+v", filter, tt.want)
+			}
+		})
+	}
+}
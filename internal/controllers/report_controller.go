@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"fverify_be/internal/auth"
+	"fverify_be/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportController struct {
+	Service *services.ReportService
+}
+
+func NewReportController(service *services.ReportService) *ReportController {
+	return &ReportController{Service: service}
+}
+
+// GetProspectReport godoc
+// @Summary Download the verification report of a prospect
+// @Description Generate a PDF report with the verified fields, photos, timeline and approver of a prospect. The report carries a content hash, a signature and a QR code linking to the public verification endpoint. Reports for prospects without a final decision are marked DRAFT.
+// @Tags Reports
+// @Produce application/pdf
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {file} binary
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/report.pdf [get]
+func (rc *ReportController) GetProspectReport(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	report, pdf, err := rc.Service.GenerateReport(c.Request.Context(), authUser.OrgUUID, c.Param("uid"), authUser.Username)
+	if err != nil {
+		respondReportError(c, err, "Failed to generate report")
+		return
+	}
+
+	c.Header("Content-Disposition", "inline; filename="+strconv.Quote("report-"+report.ProspectId+".pdf"))
+	c.Header("X-Report-Id", report.ReportId)
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// VerifyReport godoc
+// @Summary Verify an issued report
+// @Description Public endpoint linked from the report QR code. Confirms the report was issued and, when given, that the content hash printed on it matches.
+// @Tags Reports
+// @Produce json
+// @Param reportId path string true "Report Id"
+// @Param hash query string false "Content hash printed on the report"
+// @Success 200 {object} models.ReportVerificationResp
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/reports/{reportId}/verify [get]
+func (rc *ReportController) VerifyReport(c *gin.Context) {
+	result, err := rc.Service.VerifyReport(c.Request.Context(), c.Param("reportId"), c.Query("hash"))
+	if err != nil {
+		respondReportError(c, err, "Failed to verify report")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// VerifyReportFile godoc
+// @Summary Verify a report file
+// @Description Public endpoint to check that an uploaded PDF is an unaltered report issued by this service. The upload is limited to reports.maxVerifyBytes.
+// @Tags Reports
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Report PDF"
+// @Success 200 {object} models.ReportVerificationResp
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/reports/verify [post]
+func (rc *ReportController) VerifyReportFile(c *gin.Context) {
+	limitBody(c, rc.Service.MaxVerifySize())
+	fileHeader, err := c.FormFile("file")
+	if bodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Report file is too large"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A report file is required"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	result, err := rc.Service.VerifyPdf(c.Request.Context(), file)
+	if err != nil {
+		respondReportError(c, err, "Failed to verify report")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondReportError maps report service errors to HTTP responses
+func respondReportError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrProspectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prospect not found"})
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
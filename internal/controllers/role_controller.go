@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fverify_be/internal/audit"
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"fverify_be/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type RoleController struct {
+	Service *services.RoleService
+}
+
+func NewRoleController(service *services.RoleService) *RoleController {
+	return &RoleController{Service: service}
+}
+
+// CreateRole godoc
+// @Summary Create a custom role
+// @Description Define a role for the caller's organisation with its own set of permissions, out of those the caller's role holds. Users can then be given the role like a built-in one.
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param role body models.CustomRoleReq true "Name and permissions"
+// @Success 201 {object} models.CustomRole
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/roles [post]
+func (rc *RoleController) CreateRole(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	var req models.CustomRoleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, _ := c.Get("policy")
+	role, err := rc.Service.CreateRole(c.Request.Context(), authUser.OrgUUID, req, policy.(models.RolePolicy), models.Role(authUser.Role), authUser.Username)
+	if respondRoleError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+	audit.SetEntity(c, "roles", role.RoleId)
+	audit.SetChanges(c, nil, role)
+	c.JSON(http.StatusCreated, role)
+}
+
+// GetRoles godoc
+// @Summary List custom roles
+// @Description Retrieve the custom roles of the caller's organisation. GET /api/v1/users/roles lists them together with the built-in roles.
+// @Tags Roles
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {array} models.CustomRole
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/roles [get]
+func (rc *RoleController) GetRoles(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	roles, err := rc.Service.GetRoles(c.Request.Context(), authUser.OrgUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// UpdateRole godoc
+// @Summary Change the permissions of a custom role
+// @Description Replace the permissions of a custom role of the caller's organisation. Its users hold the new permissions from their next request. The caller's role must hold the old and new permissions. The name can not be changed.
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param roleId path string true "Role ID"
+// @Param role body models.CustomRolePermissionsReq true "Permissions"
+// @Success 200 {object} models.CustomRole
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/roles/{roleId} [put]
+func (rc *RoleController) UpdateRole(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	var req models.CustomRolePermissionsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, _ := c.Get("policy")
+	before, role, err := rc.Service.UpdateRolePermissions(c.Request.Context(), authUser.OrgUUID, c.Param("roleId"), req, policy.(models.RolePolicy), models.Role(authUser.Role), authUser.Username)
+	if respondRoleError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	audit.SetChanges(c, before, role)
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole godoc
+// @Summary Delete a custom role
+// @Description Delete a custom role of the caller's organisation. Roles still held by users, active or not, can not be deleted, nor roles granting permissions the caller's role does not hold.
+// @Tags Roles
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param roleId path string true "Role ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/roles/{roleId} [delete]
+func (rc *RoleController) DeleteRole(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	policy, _ := c.Get("policy")
+	deleted, err := rc.Service.DeleteRole(c.Request.Context(), authUser.OrgUUID, c.Param("roleId"), policy.(models.RolePolicy), models.Role(authUser.Role))
+	if respondRoleError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	audit.SetChanges(c, deleted, nil)
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Role deleted"})
+}
+
+// respondRoleError writes the response for custom role errors the client can act on and reports whether
+// it did
+func respondRoleError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrBlankRoleName), errors.Is(err, services.ErrBuiltInRoleName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "allowed": models.Permissions})
+	case errors.Is(err, services.ErrPermissionNotHeld):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrRoleExists), errors.Is(err, services.ErrRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	default:
+		return false
+	}
+	return true
+}
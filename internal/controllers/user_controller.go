@@ -1,12 +1,18 @@
 package controllers
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"fverify_be/internal/audit"
 	"fverify_be/internal/auth"
 	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
 	"fverify_be/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -14,8 +20,12 @@ import (
 )
 
 type UserController struct {
-	Service    *services.UserService
-	OrgService *services.OrganisationService // Add this field
+	Service          *services.UserService
+	OrgService       *services.OrganisationService // Add this field
+	SessionService   *services.SessionService
+	TwoFactorService *services.TwoFactorService
+	OTPLoginService  *services.OTPLoginService
+	RoleService      *services.RoleService
 }
 
 type ErrorResponse struct {
@@ -47,10 +57,14 @@ type InvalidAPIKeyResponse struct {
 	Details string `json:"details" example:"API key is invalid"` // Additional details about the error
 }
 
-func NewUserController(userService *services.UserService, orgService *services.OrganisationService) *UserController {
+func NewUserController(userService *services.UserService, orgService *services.OrganisationService, sessionService *services.SessionService, twoFactorService *services.TwoFactorService, otpLoginService *services.OTPLoginService, roleService *services.RoleService) *UserController {
 	return &UserController{
-		Service:    userService,
-		OrgService: orgService, // Initialize OrgService
+		Service:          userService,
+		OrgService:       orgService, // Initialize OrgService
+		SessionService:   sessionService,
+		TwoFactorService: twoFactorService,
+		OTPLoginService:  otpLoginService,
+		RoleService:      roleService,
 	}
 }
 
@@ -66,6 +80,8 @@ func NewUserController(userService *services.UserService, orgService *services.O
 // @Success 201 {object} models.UserResp
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} InternalErrorResponse
 // @Router /api/v1/users [post]
 func (uc *UserController) CreateUser(c *gin.Context) {
@@ -93,37 +109,20 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Organisation is inactive"})
 		return
 	}
+	if existingOrg.OrgUUID != authUser.OrgUUID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Users can only be created in your own organisation"})
+		return
+	}
 
 	// Role-based access control
-	switch authUser.Role {
-	case string(models.Owner):
-		// Owner can update all roles, no restrictions
-	case string(models.Admin):
-		if reqUser.Role != models.Admin &&
-			reqUser.Role != models.OperationsLead &&
-			reqUser.Role != models.FieldLead &&
-			reqUser.Role != models.FieldExecutive &&
-			reqUser.Role != models.OperationsExecutive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admins can not create owner"})
-			return
-		}
-	case string(models.OperationsLead):
-		if reqUser.Role != models.OperationsLead &&
-			reqUser.Role != models.FieldLead &&
-			reqUser.Role != models.FieldExecutive &&
-			reqUser.Role != models.OperationsExecutive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Operations Leads can not create owner / admin"})
-			return
-		}
-	case string(models.OperationsExecutive):
-		if reqUser.Role != models.FieldLead &&
-			reqUser.Role != models.FieldExecutive &&
-			reqUser.Role != models.OperationsExecutive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Operations Executives can not create owner / admin / operations lead"})
-			return
-		}
-	default:
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	policyData, _ := c.Get("policy")
+	policy := policyData.(models.RolePolicy)
+	if !policy.HasRole(reqUser.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+	if !policy.CanManageRole(models.Role(authUser.Role), reqUser.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role can not create users with role " + string(reqUser.Role)})
 		return
 	}
 	var user models.User
@@ -134,8 +133,10 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 	user.Status = reqUser.Status
 	user.Remarks = reqUser.Remarks
 	user.MobileNumber = reqUser.MobileNumber
+	user.BaseLocation = reqUser.BaseLocation
 	user.OrgStatus = existingOrg.Status
 	user.OrgUUID = authUser.OrgUUID
+	user.MustChangePassword = true // The password was chosen by the creator, not the user
 	user.CreatedTime = time.Now().UTC().Format(time.RFC3339)
 	user.UpdatedTime = time.Now().UTC().Format(time.RFC3339)
 
@@ -148,17 +149,26 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 	user.UId = uuid.New().String()
 
 	createdUser, err := uc.Service.CreateUser(c.Request.Context(), &user)
+	if respondWeakPassword(c, err) {
+		return
+	}
+	if err == repositories.ErrUserExists {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
+	audit.SetEntity(c, "users", createdUser.UId)
+	audit.SetChanges(c, nil, createdUser)
 
 	c.JSON(http.StatusCreated, createdUser)
 }
 
 // GetByUserID godoc
 // @Summary Get a user by userId
-// @Description Retrieve a user by their unique ID
+// @Description Retrieve a user of the caller's organisation by their user ID
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -172,8 +182,10 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 // @Failure 500 {object} InternalErrorResponse
 // @Router /api/v1/users/{userId} [get]
 func (uc *UserController) GetUserByUserID(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
 	idParam := c.Param("userId")
-	user, err := uc.Service.GetByUserID(c.Request.Context(), idParam)
+	user, err := uc.Service.GetByUserID(c.Request.Context(), authUser.OrgUUID, idParam)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -184,18 +196,36 @@ func (uc *UserController) GetUserByUserID(c *gin.Context) {
 
 // GetAllUsers godoc
 // @Summary Get all users
-// @Description Retrieve all users in the system
+// @Description Retrieve a page of users in the caller's organisation, oldest first. Follow next_cursor for subsequent pages.
 // @Tags Users
 // @Accept json
 // @Produce json
+// @Param skip query int false "Number of records to skip" default(0)
+// @Param limit query int false "Number of records to retrieve, at most 100" default(10)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param include_total query bool false "Include the total number of records" default(false)
 // @Param Authorization header string true "Bearer token"
 // @Param org_id  header string true "Organisation Id"
-// @Success 200 {array} models.UserResp
+// @Success 200 {object} models.Page[models.UserResp]
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} InvalidAuthResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/users [get]
 func (uc *UserController) GetAllUsers(c *gin.Context) {
-	users, err := uc.Service.GetAllUsers(c.Request.Context())
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	page, ok := pageRequest(c)
+	if !ok {
+		return
+	}
+	users, err := uc.Service.GetAllUsers(c.Request.Context(), authUser.OrgUUID, page)
+	if err == repositories.ErrInvalidCursor {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid cursor",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Internal Server Error",
@@ -223,7 +253,7 @@ func (uc *UserController) GetAllUsers(c *gin.Context) {
 // func (uc *UserController) DeleteUserByUId(c *gin.Context) {
 // 	uIdParam := c.Param("uId")
 
-// 	err = uc.Service.DeleteByUId(c.Request.Context(), uIdParam)
+// 	err = uc.Service.DeleteByUId(c.Request.Context(), authUser.OrgUUID, uIdParam)
 // 	if err != nil {
 // 		c.JSON(http.StatusNotFound, ErrorResponse{
 // 			Error:   "User not found",
@@ -249,9 +279,11 @@ func (uc *UserController) GetAllUsers(c *gin.Context) {
 // @Failure 500 {object} InternalErrorResponse
 // @Router /api/v1/users/userid/{userId} [delete]
 func (uc *UserController) DeleteUserByUserId(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
 	userId := c.Param("userId")
 
-	err := uc.Service.DeleteByUserId(c.Request.Context(), userId)
+	deleted, err := uc.Service.DeleteByUserId(c.Request.Context(), authUser.OrgUUID, userId)
 	if err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{
 			Error:   "User not found",
@@ -259,20 +291,22 @@ func (uc *UserController) DeleteUserByUserId(c *gin.Context) {
 		})
 		return
 	}
+	audit.SetEntity(c, "users", deleted.UId)
+	audit.SetChanges(c, deleted, nil)
 
 	c.Status(http.StatusNoContent)
 }
 
 // UpdateUser godoc
 // @Summary Update a user
-// @Description Update an existing user's details
+// @Description Update an existing user's details. The password can not be set here; reset it with POST /api/v1/users/uid/{uId}/password/reset.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer token"
 // @Param org_id  header string true "Organisation Id"
 // @Param uId path string true "User uId"
-// @Param user body models.UserReq true "User data (all fields are mandatory)"
+// @Param user body models.UserReq true "User data (all fields except password are mandatory; password must be empty)"
 // @Success 200 {object} models.UserResp
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} InvalidAuthResponse
@@ -292,42 +326,31 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 	}
 
 	// Fetch the target user to validate roles
-	targetUser, err := uc.Service.GetByUserUID(c.Request.Context(), uIdParam)
+	targetUser, err := uc.Service.GetByUserUID(c.Request.Context(), authUser.OrgUUID, uIdParam)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	// Role-based access control
-	switch authUser.Role {
-	case string(models.Owner):
-		// Owner can update all roles, no restrictions
-	case string(models.Admin):
-		if targetUser.Role != models.Admin &&
-			targetUser.Role != models.OperationsLead &&
-			targetUser.Role != models.FieldLead &&
-			targetUser.Role != models.FieldExecutive &&
-			targetUser.Role != models.OperationsExecutive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admins can only update specific roles"})
-			return
-		}
-	case string(models.OperationsLead):
-		if targetUser.Role != models.OperationsLead &&
-			targetUser.Role != models.FieldLead &&
-			targetUser.Role != models.FieldExecutive &&
-			targetUser.Role != models.OperationsExecutive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Operations Leads can only update specific roles"})
-			return
-		}
-	case string(models.OperationsExecutive):
-		if targetUser.Role != models.FieldLead &&
-			targetUser.Role != models.FieldExecutive &&
-			targetUser.Role != models.OperationsExecutive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Operations Executives can only update specific roles"})
-			return
-		}
-	default:
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	policyData, _ := c.Get("policy")
+	policy := policyData.(models.RolePolicy)
+	if !policy.CanManageRole(models.Role(authUser.Role), targetUser.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role can not update users with role " + string(targetUser.Role)})
+		return
+	}
+	if !policy.HasRole(reqUser.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+	if !policy.CanManageRole(models.Role(authUser.Role), reqUser.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role can not assign role " + string(reqUser.Role)})
+		return
+	}
+
+	// Passwords are only set by their owner, directly or with a reset code, so the user's sessions end with them
+	if reqUser.Password != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passwords can not be updated here; issue a reset code with /users/uid/{uId}/password/reset"})
 		return
 	}
 
@@ -350,30 +373,35 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 	user.Status = reqUser.Status
 	user.Remarks = reqUser.Remarks
 	user.MobileNumber = reqUser.MobileNumber
+	user.BaseLocation = reqUser.BaseLocation
 	user.OrgUUID = authUser.OrgUUID
-	if reqUser.Password != "" {
-		user.Password = reqUser.Password
-	}
 
 	uUser, err := uc.Service.UpdateUser(c.Request.Context(), &user, authUser.Username)
+	if err == repositories.ErrUserExists {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
+	audit.SetChanges(c, targetUser, uUser)
 
 	c.JSON(http.StatusOK, uUser)
 }
 
 // LoginUser godoc
 // @Summary Login a user
-// @Description Validate username and password, and return user details with a token
+// @Description Validate username and password, and return user details with a short-lived access token and a refresh token. Users with a second factor, or whose role requires one, instead get a two-factor challenge to complete at POST /api/v1/users/login/2fa.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param login body models.LoginRequest true "Login credentials"
 // @Success 200 {object} models.LoginResponse
+// @Success 200 {object} models.TwoFactorChallenge
 // @Failure 401 {object} InvalidAuthResponse
 // @Failure 404 {object} NotFoundResponse
+// @Failure 429 {object} ErrorResponse "Too many failed attempts; see the Retry-After header"
 // @Failure 500 {object} InternalErrorResponse
 // @Router /api/v1/users/login [post]
 func (uc *UserController) LoginUser(c *gin.Context) {
@@ -394,8 +422,15 @@ func (uc *UserController) LoginUser(c *gin.Context) {
 		return
 	}
 
+	audit.SetActor(c, existingOrg.OrgUUID, "", loginRequest.Username)
+
 	// Validate the user
-	user, err := uc.Service.LoginUser(c.Request.Context(), loginRequest.Username, loginRequest.Password, existingOrg.OrgUUID)
+	user, err := uc.Service.LoginUser(c.Request.Context(), loginRequest.Username, loginRequest.Password, existingOrg.OrgUUID, c.ClientIP())
+	var blocked *services.LoginBlockedError
+	if errors.As(err, &blocked) {
+		respondLoginBlocked(c, blocked)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
@@ -405,9 +440,137 @@ func (uc *UserController) LoginUser(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Your account is inactive, please contact support"})
 		return
 	}
+
+	uc.challengeOrCompleteLogin(c, user)
+}
+
+// challengeOrCompleteLogin completes the login of a user whose first factor was accepted. Users with a
+// second factor, or whose role requires one, instead get a challenge to finish at /users/login/2fa.
+func (uc *UserController) challengeOrCompleteLogin(c *gin.Context, user *models.User) {
+	audit.SetActor(c, user.OrgUUID, user.UId, user.Username)
+	challenge, err := uc.TwoFactorService.Challenge(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor login"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	uc.completeLogin(c, user, nil)
+}
+
+// RequestLoginOTP godoc
+// @Summary Request an SMS login code
+// @Description Send a one-time login code by SMS to the mobile number of a user who may log in by SMS (Field Executives by default). The response is the same whether or not the number is registered. Complete the login at POST /api/v1/users/login/otp/verify.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param login body models.OTPLoginRequest true "Organisation and mobile number"
+// @Success 200 {object} models.OTPSentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse "SMS login is not enabled"
+// @Failure 429 {object} ErrorResponse "Codes requested too often or too many failed attempts; see the Retry-After header"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/login/otp/request [post]
+func (uc *UserController) RequestLoginOTP(c *gin.Context) {
+	var request models.OTPLoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	isActive, existingOrg := uc.OrgService.IsOrgActive(c.Request.Context(), request.OrgId)
+	if existingOrg == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate organisation, please contact support"})
+		return
+	}
+	if !isActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid organisation, please contact support"})
+		return
+	}
+
+	audit.SetActor(c, existingOrg.OrgUUID, "", request.MobileNumber)
+	sent, err := uc.OTPLoginService.RequestCode(c.Request.Context(), existingOrg.OrgUUID, request.MobileNumber, c.ClientIP())
+	var blocked *services.LoginBlockedError
+	if errors.As(err, &blocked) {
+		respondLoginBlocked(c, blocked)
+		return
+	}
+	if errors.Is(err, services.ErrOTPLoginDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to send login code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send login code"})
+		return
+	}
+	c.JSON(http.StatusOK, sent)
+}
+
+// VerifyLoginOTP godoc
+// @Summary Log in with an SMS code
+// @Description Exchange the code sent by POST /api/v1/users/login/otp/request for access and refresh tokens. Codes expire after a few minutes, can be used once and stop working after too many wrong guesses. Users with a second factor, or whose role requires one, instead get a two-factor challenge.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param login body models.OTPVerifyRequest true "Organisation, mobile number and code"
+// @Success 200 {object} models.LoginResponse
+// @Success 200 {object} models.TwoFactorChallenge
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse "SMS login is not enabled"
+// @Failure 429 {object} ErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/login/otp/verify [post]
+func (uc *UserController) VerifyLoginOTP(c *gin.Context) {
+	var request models.OTPVerifyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	isActive, existingOrg := uc.OrgService.IsOrgActive(c.Request.Context(), request.OrgId)
+	if existingOrg == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate organisation, please contact support"})
+		return
+	}
+	if !isActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid organisation, please contact support"})
+		return
+	}
+
+	audit.SetActor(c, existingOrg.OrgUUID, "", request.MobileNumber)
+	user, err := uc.OTPLoginService.VerifyCode(c.Request.Context(), existingOrg.OrgUUID, request.MobileNumber, request.Code, c.ClientIP())
+	var blocked *services.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		respondLoginBlocked(c, blocked)
+		return
+	case errors.Is(err, services.ErrInvalidOTP):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrOTPLoginDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify login code"})
+		return
+	}
+
+	uc.challengeOrCompleteLogin(c, user)
+}
+
+// completeLogin activates a newly logged in user, starts their session and responds with its tokens
+func (uc *UserController) completeLogin(c *gin.Context, user *models.User, recoveryCodes []string) {
+	audit.SetActor(c, user.OrgUUID, user.UId, user.Username)
+
 	// Update user status to Active
 	if user.Status != models.Active {
-		err = uc.Service.UpdateUserStatus(c.Request.Context(), user.UserId, string(models.Active))
+		err := uc.Service.UpdateUserStatus(c.Request.Context(), user.OrgUUID, user.UserId, string(models.Active))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
 			return
@@ -415,8 +578,8 @@ func (uc *UserController) LoginUser(c *gin.Context) {
 		user.Status = models.Active // Update the status in the user object
 	}
 
-	// Generate the token
-	token, err := auth.GenerateAuthToken(user.UserId, user.Username, user.UId, string(user.Role), string(user.Status), user.MobileNumber, user.OrgUUID)
+	// Start a session and generate its tokens
+	tokens, err := uc.SessionService.StartSession(c.Request.Context(), user.OrgUUID, user.UId, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -430,71 +593,511 @@ func (uc *UserController) LoginUser(c *gin.Context) {
 		Role:         string(user.Role),
 		Status:       string(user.Status),
 		MobileNumber: user.MobileNumber,
-		Token:        token,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+
+		MustChangePassword: user.MustChangePassword,
+		RecoveryCodes:      recoveryCodes,
 	})
 }
 
-// SetPassword godoc
-// @Summary Set a user's password
-// @Description Set a new password for a user
+// CompleteTwoFactorLogin godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token from login and a TOTP code, or one of the recovery codes, for access and refresh tokens. If the challenge required enrolment, a TOTP code for the secret from POST /api/v1/users/login/2fa/enrol confirms the enrolment and the response carries the new recovery codes.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param login body models.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 409 {object} ErrorResponse "Enrolment required"
+// @Failure 429 {object} ErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/login/2fa [post]
+func (uc *UserController) CompleteTwoFactorLogin(c *gin.Context) {
+	var request models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	user, recoveryCodes, err := uc.TwoFactorService.CompleteLogin(c.Request.Context(), request.ChallengeToken, request.Code, c.ClientIP())
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to complete login")
+		return
+	}
+	uc.completeLogin(c, user, recoveryCodes)
+}
+
+// StartLoginEnrolment godoc
+// @Summary Enrol a second factor during login
+// @Description For a login challenge with enrolment_required, create a TOTP secret to add to an authenticator app. Complete the login with a code for it at POST /api/v1/users/login/2fa.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param challenge body models.TwoFactorChallengeRequest true "Challenge token"
+// @Success 200 {object} models.TwoFactorEnrolment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 409 {object} ErrorResponse "Already enrolled"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/login/2fa/enrol [post]
+func (uc *UserController) StartLoginEnrolment(c *gin.Context) {
+	var request models.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	enrolment, err := uc.TwoFactorService.StartLoginEnrolment(c.Request.Context(), request.ChallengeToken)
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to start two-factor enrolment")
+		return
+	}
+	c.JSON(http.StatusOK, enrolment)
+}
+
+// StartTwoFactorEnrolment godoc
+// @Summary Enrol a second factor
+// @Description Create a TOTP secret for the authenticated user to add to an authenticator app. Two-factor login starts once it is confirmed with a code at POST /api/v1/users/2fa/confirm.
+// @Tags Users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {object} models.TwoFactorEnrolment
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 409 {object} ErrorResponse "Already enrolled"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/2fa/enrol [post]
+func (uc *UserController) StartTwoFactorEnrolment(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	before := uc.auditedUser(c, authUser.OrgUUID, authUser.UId)
+	enrolment, err := uc.TwoFactorService.StartEnrolment(c.Request.Context(), authUser.OrgUUID, authUser.UId, authUser.Username)
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to start two-factor enrolment")
+		return
+	}
+	uc.recordUserChange(c, before, authUser.OrgUUID, authUser.UId)
+	c.JSON(http.StatusOK, enrolment)
+}
+
+// ConfirmTwoFactorEnrolment godoc
+// @Summary Confirm a second factor
+// @Description Enable two-factor login with a TOTP code for the secret from POST /api/v1/users/2fa/enrol. Returns recovery codes, which are shown only once.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer token"
 // @Param org_id  header string true "Organisation Id"
-// @Param uId path int true "User uId"
-// @Param password body models.SetPasswordRequest true "New password"
-// @Success 200 {object} SuccessResponse
+// @Param code body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 409 {object} ErrorResponse "No enrolment started"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/2fa/confirm [post]
+func (uc *UserController) ConfirmTwoFactorEnrolment(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	var request models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	before := uc.auditedUser(c, authUser.OrgUUID, authUser.UId)
+	codes, err := uc.TwoFactorService.ConfirmEnrolment(c.Request.Context(), authUser.OrgUUID, authUser.UId, request.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to confirm two-factor enrolment")
+		return
+	}
+	uc.recordUserChange(c, before, authUser.OrgUUID, authUser.UId)
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace the recovery codes of the authenticated user, who must give a current TOTP code. The old codes stop working.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param code body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} models.RecoveryCodesResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 409 {object} ErrorResponse "Not enrolled"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/2fa/recovery-codes [post]
+func (uc *UserController) RegenerateRecoveryCodes(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	var request models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	before := uc.auditedUser(c, authUser.OrgUUID, authUser.UId)
+	codes, err := uc.TwoFactorService.RegenerateRecoveryCodes(c.Request.Context(), authUser.OrgUUID, authUser.UId, request.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+	uc.recordUserChange(c, before, authUser.OrgUUID, authUser.UId)
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// ResetTwoFactor godoc
+// @Summary Reset a user's second factor
+// @Description Remove a user's two-factor enrolment, e.g. after they lost their device, and end their sessions. If their role requires two-factor login they enrol again at their next login.
+// @Tags Users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param uId path string true "User uId"
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} InvalidAuthResponse
 // @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} NotFoundResponse
-// @Failure 401 {object} InvalidAuthResponse
 // @Failure 500 {object} InternalErrorResponse
-// @Router /api/v1/users/uid/{uId}/setpassword [put]
-func (uc *UserController) SetPassword(c *gin.Context) {
+// @Router /api/v1/users/uid/{uId}/2fa/reset [post]
+func (uc *UserController) ResetTwoFactor(c *gin.Context) {
 	claims, _ := c.Get("user")
 	authUser := claims.(*auth.AuthTokenClaims)
+	uIdParam := c.Param("uId")
+
+	targetUser, err := uc.Service.GetByUserUID(c.Request.Context(), authUser.OrgUUID, uIdParam)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	policy, _ := c.Get("policy")
+	if !policy.(models.RolePolicy).CanManageRole(models.Role(authUser.Role), targetUser.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role can not reset the second factor of users with role " + string(targetUser.Role)})
+		return
+	}
+
+	before := uc.auditedUser(c, authUser.OrgUUID, uIdParam)
+	if err := uc.TwoFactorService.Reset(c.Request.Context(), authUser.OrgUUID, uIdParam, authUser.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication"})
+		return
+	}
+	uc.recordUserChange(c, before, authUser.OrgUUID, uIdParam)
+	if err := uc.SessionService.RevokeUserSessions(c.Request.Context(), authUser.OrgUUID, uIdParam, "Two-factor reset"); err != nil {
+		log.Printf("Failed to revoke sessions of user %s: %v", uIdParam, err)
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Two-factor authentication reset"})
+}
+
+func respondTwoFactorError(c *gin.Context, err error, fallback string) {
+	var blocked *services.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		respondLoginBlocked(c, blocked)
+	case errors.Is(err, services.ErrInvalidChallenge),
+		errors.Is(err, services.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorEnrolmentRequired),
+		errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrNoPendingEnrolment):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
 
+// UnlockUser godoc
+// @Summary Unlock a user
+// @Description Clear the failed login attempts of a user, lifting any lockout
+// @Tags Users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param uId path string true "User uId"
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/uid/{uId}/unlock [post]
+func (uc *UserController) UnlockUser(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
 	uIdParam := c.Param("uId")
 
-	var request models.SetPasswordRequest
+	targetUser, err := uc.Service.GetByUserUID(c.Request.Context(), authUser.OrgUUID, uIdParam)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	policy, _ := c.Get("policy")
+	if !policy.(models.RolePolicy).CanManageRole(models.Role(authUser.Role), targetUser.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role can not unlock users with role " + string(targetUser.Role)})
+		return
+	}
+
+	if err := uc.Service.UnlockUser(c.Request.Context(), authUser.OrgUUID, uIdParam, authUser.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Message: "User unlocked successfully"})
+}
+
+// RefreshToken godoc
+// @Summary Refresh an access token
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token can be used once; presenting a used token revokes the whole session.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/token/refresh [post]
+func (uc *UserController) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	tokens, err := uc.SessionService.Refresh(c.Request.Context(), req.RefreshToken)
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, tokens)
+	case services.ErrInvalidRefreshToken, services.ErrRefreshTokenReused, services.ErrSessionUserInactive:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+	}
+}
+
+// GetJWKS godoc
+// @Summary Get token verification keys
+// @Description Public keys, in JWKS format, of the asymmetric keys that sign access tokens. Match a token's kid header to a key to verify it.
+// @Tags Users
+// @Produce json
+// @Success 200 {object} auth.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (uc *UserController) GetJWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, auth.PublicJWKS())
+}
+
+// Logout godoc
+// @Summary Logout
+// @Description Revoke the session of a refresh token. Access tokens already issued remain valid until they expire.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/logout [post]
+func (uc *UserController) Logout(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err := uc.SessionService.Logout(c.Request.Context(), req.RefreshToken)
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, SuccessResponse{Message: "Logged out"})
+	case services.ErrInvalidRefreshToken:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+	}
+}
+
+// ChangePassword godoc
+// @Summary Change own password
+// @Description Change the password of the authenticated user, who must give their current password. All of the user's sessions are ended, so they must log in again. Users who must change their password can only use this endpoint.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param password body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 429 {object} ErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/password/change [post]
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	var request models.ChangePasswordRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	// Fetch the target user to validate roles
-	targetUser, err := uc.Service.GetByUserUID(c.Request.Context(), uIdParam)
+	user, err := uc.Service.ChangePassword(c.Request.Context(), authUser.OrgUUID, authUser.Username, request, c.ClientIP())
+	if err != nil {
+		respondPasswordError(c, err, "Failed to change password")
+		return
+	}
+	uc.recordUserChange(c, user, user.OrgUUID, user.UId)
+	uc.endSessions(c, user, "Password changed")
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Password changed, please log in again"})
+}
+
+// IssuePasswordReset godoc
+// @Summary Reset a user's password
+// @Description Issue a one-time code with which the user sets a new password at POST /api/v1/users/password/reset. The current password keeps working until then.
+// @Tags Users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param uId path string true "User uId"
+// @Success 200 {object} models.PasswordResetCodeResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/uid/{uId}/password/reset [post]
+func (uc *UserController) IssuePasswordReset(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+	uIdParam := c.Param("uId")
+
+	targetUser, err := uc.Service.GetByUserUID(c.Request.Context(), authUser.OrgUUID, uIdParam)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+	policy, _ := c.Get("policy")
+	if !policy.(models.RolePolicy).CanManageRole(models.Role(authUser.Role), targetUser.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role can not reset the password of users with role " + string(targetUser.Role)})
+		return
+	}
 
-	// Role-based access control
-	if authUser.Role == string(models.Admin) || authUser.Role == string(models.Owner) {
-		if targetUser.Role != models.Admin && targetUser.Role != models.OperationsLead {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admins and Owners can only set passwords for Admins and Operations Leads"})
-			return
-		}
-	} else if authUser.Role == string(models.OperationsLead) {
-		if targetUser.Role != models.OperationsLead && targetUser.Role != models.FieldLead &&
-			targetUser.Role != models.FieldExecutive && targetUser.Role != models.OperationsExecutive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Operations Leads can only set passwords for specific roles"})
-			return
-		}
-	} else {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	before := uc.auditedUser(c, authUser.OrgUUID, uIdParam)
+	code, err := uc.Service.IssuePasswordReset(c.Request.Context(), authUser.OrgUUID, uIdParam, authUser.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	uc.recordUserChange(c, before, authUser.OrgUUID, uIdParam)
+	c.JSON(http.StatusOK, code)
+}
+
+// ResetPassword godoc
+// @Summary Set a new password with a reset code
+// @Description Set a new password using the one-time code issued by an administrator. All of the user's sessions are ended.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param reset body models.ResetPasswordRequest true "Reset code and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} InvalidAuthResponse
+// @Failure 429 {object} ErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/users/password/reset [post]
+func (uc *UserController) ResetPassword(c *gin.Context) {
+	var request models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	isActive, existingOrg := uc.OrgService.IsOrgActive(c.Request.Context(), request.OrgId)
+	if existingOrg == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate organisation, please contact support"})
+		return
+	}
+	if !isActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid organisation, please contact support"})
+		return
+	}
+
+	audit.SetActor(c, existingOrg.OrgUUID, "", request.Username)
+	user, err := uc.Service.ResetPassword(c.Request.Context(), existingOrg.OrgUUID, request, c.ClientIP())
+	if err != nil {
+		respondPasswordError(c, err, "Failed to reset password")
 		return
 	}
+	audit.SetActor(c, user.OrgUUID, user.UId, user.Username)
+	uc.recordUserChange(c, user, user.OrgUUID, user.UId)
+	uc.endSessions(c, user, "Password reset")
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Password reset, please log in"})
+}
 
-	// Update the password
-	err = uc.Service.SetPassword(c.Request.Context(), uIdParam, request.Password)
+// auditedUser returns the stored record of a user as it is before a change of their credentials. A user
+// who cannot be read is left to the change itself to report.
+func (uc *UserController) auditedUser(c *gin.Context, orgUUID string, uid string) *models.User {
+	user, err := uc.Service.GetUserRecord(c.Request.Context(), orgUUID, uid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return nil
+	}
+	return user
+}
+
+// recordUserChange records the fields a change of credentials set, comparing the user before the change
+// with the stored record after it. Credentials are masked, so only which of them changed is recorded.
+func (uc *UserController) recordUserChange(c *gin.Context, before *models.User, orgUUID string, uid string) {
+	after := uc.auditedUser(c, orgUUID, uid)
+	if before == nil || after == nil {
 		return
 	}
+	audit.SetChanges(c, before, after)
+}
+
+// endSessions logs a user out everywhere after their password changed. The password change already
+// revoked their access tokens, so a failure here is only logged.
+func (uc *UserController) endSessions(c *gin.Context, user *models.User, reason string) {
+	if err := uc.SessionService.RevokeUserSessions(c.Request.Context(), user.OrgUUID, user.UId, reason); err != nil {
+		log.Printf("Failed to revoke sessions of user %s: %v", user.UId, err)
+	}
+}
+
+// respondLoginBlocked tells the client how long to wait before trying again
+func respondLoginBlocked(c *gin.Context, blocked *services.LoginBlockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": blocked.Error()})
+}
+
+// respondWeakPassword answers 400 with the unmet rules when err is a password policy error
+func respondWeakPassword(c *gin.Context, err error) bool {
+	var weak *services.PasswordPolicyError
+	if !errors.As(err, &weak) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": weak.Error(), "violations": weak.Violations})
+	return true
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
+func respondPasswordError(c *gin.Context, err error, fallback string) {
+	if respondWeakPassword(c, err) {
+		return
+	}
+	var blocked *services.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		respondLoginBlocked(c, blocked)
+	case errors.Is(err, services.ErrIncorrectPassword),
+		errors.Is(err, services.ErrInvalidResetCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSamePassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
 }
 
 // CreateAdminUser godoc
@@ -567,6 +1170,7 @@ func (uc *UserController) CreateAdmin(c *gin.Context) {
 	user.Status = reqUser.Status
 	user.Remarks = reqUser.Remarks
 	user.MobileNumber = reqUser.MobileNumber
+	user.BaseLocation = reqUser.BaseLocation
 	user.OrgStatus = existingOrg.Status
 	user.OrgUUID = existingOrg.OrgUUID
 	user.UId = uuid.New().String()
@@ -583,10 +1187,20 @@ func (uc *UserController) CreateAdmin(c *gin.Context) {
 	user.Role = models.Admin
 
 	createdUser, err := uc.Service.CreateUser(c.Request.Context(), &user)
+	if respondWeakPassword(c, err) {
+		return
+	}
+	if err == repositories.ErrUserExists {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create admin user"})
 		return
 	}
+	audit.SetActor(c, existingOrg.OrgUUID, "", "System")
+	audit.SetEntity(c, "users", createdUser.UId)
+	audit.SetChanges(c, nil, createdUser)
 
 	c.JSON(http.StatusCreated, createdUser)
 }
@@ -636,6 +1250,7 @@ func (uc *UserController) CreateOwner(c *gin.Context) {
 	user.Status = reqUser.Status
 	user.Remarks = reqUser.Remarks
 	user.MobileNumber = reqUser.MobileNumber
+	user.BaseLocation = reqUser.BaseLocation
 	user.OrgStatus = existingOrg.Status
 	user.OrgUUID = existingOrg.OrgUUID
 	user.UId = uuid.New().String()
@@ -652,22 +1267,33 @@ func (uc *UserController) CreateOwner(c *gin.Context) {
 	user.Role = models.Owner
 
 	createdUser, err := uc.Service.CreateUser(c.Request.Context(), &user)
+	if respondWeakPassword(c, err) {
+		return
+	}
+	if err == repositories.ErrUserExists {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create admin user"})
 		return
 	}
+	audit.SetActor(c, existingOrg.OrgUUID, "", "System")
+	audit.SetEntity(c, "users", createdUser.UId)
+	audit.SetChanges(c, nil, createdUser)
 
 	c.JSON(http.StatusCreated, createdUser)
 }
 
 // GetUserRoles godoc
 // @Summary Get user roles
-// @Description Retrieve all user roles for a given organisation
+// @Description Retrieve the roles of an organisation with the permissions each holds and the roles its users may manage
 // @Tags Users
 // @Accept json
 // @Produce json
+// @Param Authorization header string true "Bearer token"
 // @Param org_id  header string true "Organisation Id"
-// @Success 200 {array} string
+// @Success 200 {array} models.RoleInfo
 // @Failure 400 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} NotFoundResponse
@@ -692,12 +1318,29 @@ func (uc *UserController) GetUserRoles(c *gin.Context) {
 		return
 	}
 
-	// Return the list of user roles
-	roles := []string{
-		string(models.OperationsLead),
-		string(models.FieldLead),
-		string(models.FieldExecutive),
-		string(models.OperationsExecutive),
+	// Return the organisation's role policy, built-in roles first
+	policy, customRoles, err := uc.RoleService.Policy(c.Request.Context(), existingOrg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return
+	}
+	roles := make([]models.RoleInfo, 0, len(models.Roles)+len(customRoles))
+	for _, role := range models.Roles {
+		_, overridden := existingOrg.RolePermissions[role]
+		roles = append(roles, models.RoleInfo{
+			Role:         role,
+			Permissions:  policy[role],
+			ManagesRoles: policy.ManagedRoles(role, customRoles),
+			Overridden:   overridden,
+		})
+	}
+	for _, customRole := range customRoles {
+		roles = append(roles, models.RoleInfo{
+			Role:         customRole.Name,
+			Permissions:  customRole.Permissions,
+			ManagesRoles: policy.ManagedRoles(customRole.Name, customRoles),
+			Custom:       true,
+		})
 	}
 	c.JSON(http.StatusOK, roles)
 }
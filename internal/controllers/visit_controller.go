@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fverify_be/internal/audit"
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VisitController struct {
+	Service *services.VisitService
+}
+
+func NewVisitController(service *services.VisitService) *VisitController {
+	return &VisitController{Service: service}
+}
+
+// CheckIn godoc
+// @Summary Check in to a field visit
+// @Description Start a visit to the prospect's residence or office. Only the assigned field executive can check in, while the prospect is OnVisit or in progress. The distance from the address is recorded when its coordinates are known.
+// @Tags Visits
+// @Accept json
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param visit body models.VisitCheckInReq true "Check-in details"
+// @Success 201 {object} models.ProspectVisit
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/visits [post]
+func (vc *VisitController) CheckIn(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	var req models.VisitCheckInReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	visit, err := vc.Service.CheckIn(c.Request.Context(), authUser.OrgUUID, c.Param("uid"), req, authUser.UId, authUser.Username)
+	if err != nil {
+		respondVisitError(c, err, "Failed to check in")
+		return
+	}
+	audit.SetChanges(c, nil, visit)
+
+	c.JSON(http.StatusCreated, visit)
+}
+
+// CheckOut godoc
+// @Summary Check out of a field visit
+// @Description Complete a visit started by the caller, recording its duration and the distance from the visited address
+// @Tags Visits
+// @Accept json
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param visitId path string true "Visit UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Param visit body models.VisitCheckOutReq true "Check-out details"
+// @Success 200 {object} models.ProspectVisit
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} NotFoundResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/visits/{visitId}/check-out [post]
+func (vc *VisitController) CheckOut(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	var req models.VisitCheckOutReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, visit, err := vc.Service.CheckOut(c.Request.Context(), authUser.OrgUUID, c.Param("uid"), c.Param("visitId"), req, authUser.UId, authUser.Username)
+	if err != nil {
+		respondVisitError(c, err, "Failed to check out")
+		return
+	}
+	audit.SetChanges(c, before, visit)
+
+	c.JSON(http.StatusOK, visit)
+}
+
+// ListVisits godoc
+// @Summary List field visits of a prospect
+// @Description Retrieve every visit checked in for a prospect
+// @Tags Visits
+// @Produce json
+// @Param uid path string true "Prospect UId"
+// @Param Authorization header string true "Bearer token"
+// @Param org_id  header string true "Organisation Id"
+// @Success 200 {array} models.ProspectVisit
+// @Failure 404 {object} NotFoundResponse
+// @Failure 500 {object} InternalErrorResponse
+// @Router /api/v1/prospects/{uid}/visits [get]
+func (vc *VisitController) ListVisits(c *gin.Context) {
+	claims, _ := c.Get("user")
+	authUser := claims.(*auth.AuthTokenClaims)
+
+	visits, err := vc.Service.ListVisits(c.Request.Context(), authUser.OrgUUID, c.Param("uid"))
+	if err != nil {
+		respondVisitError(c, err, "Failed to retrieve visits")
+		return
+	}
+
+	c.JSON(http.StatusOK, visits)
+}
+
+// respondVisitError maps visit service errors to HTTP responses
+func respondVisitError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrProspectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prospect not found"})
+	case errors.Is(err, services.ErrVisitNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Visit not found"})
+	case errors.Is(err, services.ErrNotAssignee),
+		errors.Is(err, services.ErrNotVisitOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVisitInProgress),
+		errors.Is(err, services.ErrVisitCompleted),
+		errors.Is(err, services.ErrVisitNotAllowed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidVisitTarget),
+		errors.Is(err, services.ErrInvalidDeviceTime):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
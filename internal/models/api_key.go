@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// APIKeyPermissions are the permissions an API key can be granted
+var APIKeyPermissions = []Permission{PermProspectCreate, PermProspectRead}
+
+// APIKey lets a partner system call the API on behalf of an organisation without a user login.
+// Only the hash of the key is stored; the key itself is shown once, when it is created.
+// @Description API key of an organisation. The key itself is only returned on creation.
+type APIKey struct {
+	KeyId        string       `bson:"key_id" json:"key_id" example:"4f6d2c1e-8a3b-4c5d-9e7f-0a1b2c3d4e5f"`     // Unique identifier of the key
+	OrgUUID      string       `bson:"org_uuid" json:"org_uuid" example:"123e4567-e89b-12d3-a456-426614174000"` // UUID of the organisation the key acts for
+	Name         string       `bson:"name" json:"name" example:"Loan origination system"`                      // Name to recognise the key by
+	Prefix       string       `bson:"prefix" json:"prefix" example:"fvk_Q2hM3xVa"`                             // Start of the key, to recognise it in logs
+	KeyHash      string       `bson:"key_hash" json:"-"`                                                       // SHA-256 of the key
+	Permissions  []Permission `bson:"permissions" json:"permissions" example:"prospect.create"`                // Actions the key may perform
+	CreatedBy    string       `bson:"created_by" json:"created_by" example:"admin"`                            // User who created the key
+	CreatedTime  string       `bson:"created_time" json:"created_time" example:"2023-04-12T15:04:05Z"`         // Time the key was created
+	LastUsedTime string       `bson:"last_used_time,omitempty" json:"last_used_time,omitempty"`                // Time the key was last accepted
+	ExpiresAt    *time.Time   `bson:"expires_at,omitempty" json:"expires_at,omitempty"`                        // Time after which the key is no longer accepted; never when empty
+	RevokedTime  string       `bson:"revoked_time,omitempty" json:"revoked_time,omitempty"`                    // Time the key was revoked
+	RevokedBy    string       `bson:"revoked_by,omitempty" json:"revoked_by,omitempty"`                        // User who revoked the key
+}
+
+// Allows reports whether the key was granted a permission
+func (k *APIKey) Allows(permission Permission) bool {
+	for _, granted := range k.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedTime == "" && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyReq represents the request payload for creating an API key.
+// @Description Name, permissions and optional lifetime of a new API key.
+type APIKeyReq struct {
+	Name          string       `json:"name" binding:"required" example:"Loan origination system"`         // Name to recognise the key by
+	Permissions   []Permission `json:"permissions" binding:"required,min=1" example:"prospect.create"`    // Actions the key may perform
+	ExpiresInDays int          `json:"expires_in_days,omitempty" binding:"omitempty,min=1" example:"365"` // Lifetime in days; the key does not expire when omitted
+}
+
+// APIKeyCreatedResponse is returned when an API key is created.
+// @Description The new key and its record. The key is shown only once.
+type APIKeyCreatedResponse struct {
+	Key string `json:"key" example:"fvk_Q2hM3xVa9Lr0pWm7..."` // API key to send as X-API-Key
+	APIKey
+}
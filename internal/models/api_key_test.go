@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPIKeyActive(t *testing.T) {
+	now := time.Date(2023, 4, 12, 10, 0, 0, 0, time.UTC)
+	later := now.Add(time.Second)
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{"never expires", APIKey{}, true},
+		{"not yet expired", APIKey{ExpiresAt: &later}, true},
+		{"expires now", APIKey{ExpiresAt: &now}, false},
+		{"revoked", APIKey{RevokedTime: "2023-04-12T09:00:00Z"}, false},
+		{"revoked before expiry", APIKey{ExpiresAt: &later, RevokedTime: "2023-04-12T09:00:00Z"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Active(now); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAllows(t *testing.T) {
+	key := APIKey{Permissions: []Permission{PermProspectCreate}}
+	if !key.Allows(PermProspectCreate) || key.Allows(PermProspectRead) {
+		t.Errorf("Allows() does not follow the granted permissions %v", key.Permissions)
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+// AuditEvent records a request that changed data or authenticated a user. Events are only ever inserted.
+// @Description Who did what, when and from where, with the fields the request changed.
+type AuditEvent struct {
+	EventId    string        `bson:"event_id" json:"event_id" example:"2b7e9c1d-3f4a-4b5c-8d6e-7f8091a2b3c4"`     // Unique identifier of the event
+	OrgUUID    string        `bson:"org_uuid,omitempty" json:"org_uuid,omitempty"`                                // Organisation the event belongs to
+	Time       string        `bson:"time" json:"time" example:"2023-04-12T15:04:05Z"`                             // Time the request was handled
+	ActorId    string        `bson:"actor_id,omitempty" json:"actor_id,omitempty" example:"123e4567-e89b-12d3"`   // UId of the user, or api-key:<id> for API keys
+	Actor      string        `bson:"actor,omitempty" json:"actor,omitempty" example:"john_doe"`                   // Username of the user, or the name given for logins
+	Action     string        `bson:"action" json:"action" example:"PUT /api/v1/users/uid/:uId"`                   // Method and route of the request
+	EntityType string        `bson:"entity_type" json:"entity_type" example:"users"`                              // Kind of record the request acted on
+	EntityId   string        `bson:"entity_id,omitempty" json:"entity_id,omitempty" example:"123e4567-e89b-12d3"` // Identifier of the record
+	Status     int           `bson:"status" json:"status" example:"200"`                                          // HTTP status of the response
+	IP         string        `bson:"ip" json:"ip" example:"203.0.113.7"`                                          // Client IP address
+	UserAgent  string        `bson:"user_agent" json:"user_agent" example:"Mozilla/5.0"`                          // Client user agent
+	Changes    []FieldChange `bson:"changes,omitempty" json:"changes,omitempty"`                                  // Fields the request changed
+}
+
+// AuditFilter selects audit events of an organisation. Empty fields match every event.
+type AuditFilter struct {
+	Actor      string
+	ActorId    string
+	Action     string
+	EntityType string
+	EntityId   string
+	From       string // RFC 3339 time of the oldest event, inclusive
+	To         string // RFC 3339 time of the newest event, exclusive
+}
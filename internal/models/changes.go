@@ -0,0 +1,192 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldChange is the old and new value of one field of a changed record. Values of sensitive fields are
+// masked.
+// @Description Old and new value of a changed field.
+type FieldChange struct {
+	Field string      `bson:"field" json:"field" example:"status"` // bson name of the field
+	Old   interface{} `bson:"old" json:"old" example:"Active"`     // Value before the change; null for created records
+	New   interface{} `bson:"new" json:"new" example:"InActive"`   // Value after the change; null for deleted records
+}
+
+// MaskedValue replaces the values of sensitive fields in recorded changes
+const MaskedValue = "***"
+
+// maskedFields are the bson names of fields whose values never leave the record they belong to. That
+// they changed is still recorded.
+var maskedFields = map[string]bool{
+	"password":           true,
+	"password_history":   true,
+	"password_reset":     true,
+	"two_factor":         true,
+	"login_otp":          true,
+	"token_version":      true,
+	"key_hash":           true,
+	"current_token_hash": true,
+	"used_token_hashes":  true,
+	"storage_key":        true,
+	"assignment_cursor":  true,
+}
+
+// skippedFields are the bson names of fields that are bookkeeping rather than data, and are not compared
+var skippedFields = map[string]bool{
+	"update_history": true,
+	"history_head":   true,
+	"updated_time":   true,
+	"updated_by":     true,
+}
+
+// Diff compares two records field by field, using the bson names of their struct fields, and returns the
+// fields whose values differ, in field order. Either record may be nil, and they may be of different struct
+// types; fields only one of them has are compared against nil. Values of sensitive fields are masked.
+func Diff(before interface{}, after interface{}) []FieldChange {
+	beforeFields, beforeOrder := bsonFields(before)
+	afterFields, afterOrder := bsonFields(after)
+
+	order := afterOrder
+	for _, name := range beforeOrder {
+		if _, ok := afterFields[name]; !ok {
+			order = append(order, name)
+		}
+	}
+	return diffFields(beforeFields, afterFields, order)
+}
+
+// DiffUpdate compares a stored record with a document written over it with $set. Fields the document
+// leaves out, zero values of omitempty fields, keep their stored value and are not compared.
+func DiffUpdate(stored interface{}, update interface{}) []FieldChange {
+	storedFields, _ := bsonFields(stored)
+	updateFields, updateOrder := bsonFields(update)
+	return diffFields(storedFields, updateFields, updateOrder)
+}
+
+func diffFields(beforeFields map[string]interface{}, afterFields map[string]interface{}, order []string) []FieldChange {
+	var changes []FieldChange
+	for _, name := range order {
+		oldValue, newValue := beforeFields[name], afterFields[name]
+		// A field of a created or deleted record that was never set did not change
+		if reflect.DeepEqual(oldValue, newValue) || (isZero(oldValue) && isZero(newValue)) {
+			continue
+		}
+		if maskedFields[name] {
+			oldValue, newValue = maskPresent(oldValue), maskPresent(newValue)
+		}
+		changes = append(changes, FieldChange{Field: name, Old: oldValue, New: newValue})
+	}
+	return changes
+}
+
+// bsonFields returns the exported, non-skipped fields of a struct or struct pointer by bson name. Nil
+// pointers and empty lists are returned as nil, and zero values of omitempty fields as absent.
+func bsonFields(record interface{}) (map[string]interface{}, []string) {
+	fields := map[string]interface{}{}
+	value := reflect.ValueOf(record)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return fields, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return fields, nil
+	}
+
+	var order []string
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("bson"), ",")
+		if name == "-" || skippedFields[name] {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fieldValue := value.Field(i)
+		if fieldValue.IsZero() && strings.Contains(options, "omitempty") {
+			continue
+		}
+		order = append(order, name)
+		switch fieldValue.Kind() {
+		case reflect.Pointer:
+			if fieldValue.IsNil() {
+				fields[name] = nil
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		case reflect.Slice, reflect.Map:
+			// An empty list is no different from a missing one
+			if fieldValue.Len() == 0 {
+				fields[name] = nil
+				continue
+			}
+		}
+		fields[name] = fieldValue.Interface()
+	}
+	return fields, order
+}
+
+// maskPresent hides a value but keeps whether there was one
+func maskPresent(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return MaskedValue
+}
+
+func isZero(value interface{}) bool {
+	return value == nil || reflect.ValueOf(value).IsZero()
+}
+
+// ChangeComments describes changes in words, e.g. "role changed from 'Admin' to 'Owner', password
+// updated". Masked and structured values are only named.
+func ChangeComments(changes []FieldChange) string {
+	comments := make([]string, 0, len(changes))
+	for _, change := range changes {
+		label := strings.ReplaceAll(change.Field, "_", " ")
+		oldText, oldOk := commentValue(change.Old)
+		newText, newOk := commentValue(change.New)
+		if !oldOk || !newOk {
+			comments = append(comments, label+" updated")
+			continue
+		}
+		comments = append(comments, label+" changed from '"+oldText+"' to '"+newText+"'")
+	}
+	return strings.Join(comments, ", ")
+}
+
+// commentValue formats a plain value for ChangeComments; it reports false for masked and structured values
+func commentValue(value interface{}) (string, bool) {
+	if value == nil {
+		return "", true
+	}
+	if value == MaskedValue {
+		return "", false
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(value), true
+	}
+	return "", false
+}
+
+// NewUpdateHistory records changes made by a user, with the comment derived from the changes
+func NewUpdateHistory(updatedTime string, updateBy string, changes []FieldChange) UpdateHistory {
+	return UpdateHistory{
+		UpdatedComments: ChangeComments(changes),
+		UpdatedTime:     updatedTime,
+		UpdateBy:        updateBy,
+		Changes:         changes,
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// changeRecord has one field of every kind Diff treats differently
+type changeRecord struct {
+	Name        string    `bson:"name"`
+	Age         int       `bson:"age"`
+	Password    string    `bson:"password"`
+	Location    *GeoPoint `bson:"location,omitempty"`
+	Remarks     string    `bson:"remarks,omitempty"`
+	Tags        []string  `bson:"tags"`
+	UpdatedTime string    `bson:"updated_time"`
+	internal    string
+}
+
+func TestDiff(t *testing.T) {
+	base := changeRecord{Name: "John", Age: 30, Password: "hash-1", Location: &GeoPoint{Latitude: 12.97, Longitude: 77.59}, Remarks: "ok"}
+	tests := []struct {
+		name   string
+		before interface{}
+		after  func(changeRecord) interface{}
+		want   []FieldChange
+	}{
+		{"unchanged", base, func(r changeRecord) interface{} { return r }, nil},
+		{
+			"plain fields", base, func(r changeRecord) interface{} { r.Name, r.Age = "Jane", 31; return r },
+			[]FieldChange{{Field: "name", Old: "John", New: "Jane"}, {Field: "age", Old: 30, New: 31}},
+		},
+		{
+			"masked field", base, func(r changeRecord) interface{} { r.Password = "hash-2"; return r },
+			[]FieldChange{{Field: "password", Old: MaskedValue, New: MaskedValue}},
+		},
+		{
+			"pointer dereferenced", base, func(r changeRecord) interface{} { r.Location = &GeoPoint{Latitude: 1, Longitude: 2}; return r },
+			[]FieldChange{{Field: "location", Old: GeoPoint{Latitude: 12.97, Longitude: 77.59}, New: GeoPoint{Latitude: 1, Longitude: 2}}},
+		},
+		{
+			"omitempty field cleared", base, func(r changeRecord) interface{} { r.Location, r.Remarks = nil, ""; return r },
+			[]FieldChange{{Field: "location", Old: GeoPoint{Latitude: 12.97, Longitude: 77.59}, New: nil}, {Field: "remarks", Old: "ok", New: nil}},
+		},
+		{"empty list is no list", base, func(r changeRecord) interface{} { r.Tags = []string{}; return r }, nil},
+		{"bookkeeping skipped", base, func(r changeRecord) interface{} { r.UpdatedTime = "2023-04-12T15:04:05Z"; return r }, nil},
+		{"unexported ignored", base, func(r changeRecord) interface{} { r.internal = "x"; return r }, nil},
+		{"pointer and value compare alike", &base, func(r changeRecord) interface{} { return r }, nil},
+		{
+			"created", nil, func(r changeRecord) interface{} { return &r },
+			[]FieldChange{
+				{Field: "name", Old: nil, New: "John"},
+				{Field: "age", Old: nil, New: 30},
+				{Field: "password", Old: nil, New: MaskedValue},
+				{Field: "location", Old: nil, New: GeoPoint{Latitude: 12.97, Longitude: 77.59}},
+				{Field: "remarks", Old: nil, New: "ok"},
+			},
+		},
+		{
+			"deleted", base, func(changeRecord) interface{} { return nil },
+			[]FieldChange{
+				{Field: "name", Old: "John", New: nil},
+				{Field: "age", Old: 30, New: nil},
+				{Field: "password", Old: MaskedValue, New: nil},
+				{Field: "location", Old: GeoPoint{Latitude: 12.97, Longitude: 77.59}, New: nil},
+				{Field: "remarks", Old: "ok", New: nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after(base)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffUpdate(t *testing.T) {
+	stored := changeRecord{Name: "John", Age: 30, Password: "hash-1", Location: &GeoPoint{Latitude: 12.97, Longitude: 77.59}, Remarks: "ok"}
+	tests := []struct {
+		name   string
+		update func(changeRecord) changeRecord
+		want   []FieldChange
+	}{
+		{"unchanged", func(r changeRecord) changeRecord { return r }, nil},
+		{
+			"omitted omitempty fields keep their value", func(r changeRecord) changeRecord { r.Location, r.Remarks, r.Age = nil, "", 31; return r },
+			[]FieldChange{{Field: "age", Old: 30, New: 31}},
+		},
+		{
+			"non-omitempty fields are written", func(r changeRecord) changeRecord { r.Name = ""; return r },
+			[]FieldChange{{Field: "name", Old: "John", New: ""}},
+		},
+		{
+			"masked field", func(r changeRecord) changeRecord { r.Password = "hash-2"; return r },
+			[]FieldChange{{Field: "password", Old: MaskedValue, New: MaskedValue}},
+		},
+		{
+			"omitempty field set", func(r changeRecord) changeRecord { r.Remarks = "visited"; return r },
+			[]FieldChange{{Field: "remarks", Old: "ok", New: "visited"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := tt.update(stored)
+			if got := DiffUpdate(&stored, &update); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffUpdate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeComments(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []FieldChange
+		want    string
+	}{
+		{"none", nil, ""},
+		{"plain", []FieldChange{{Field: "role", Old: "Admin", New: "Owner"}}, "role changed from 'Admin' to 'Owner'"},
+		{"number", []FieldChange{{Field: "years_of_stay", Old: 4, New: 5}}, "years of stay changed from '4' to '5'"},
+		{"set", []FieldChange{{Field: "remarks", Old: nil, New: "ok"}}, "remarks changed from '' to 'ok'"},
+		{"masked", []FieldChange{{Field: "password", Old: MaskedValue, New: MaskedValue}}, "password updated"},
+		{"structured", []FieldChange{{Field: "base_location", Old: nil, New: GeoPoint{Latitude: 1, Longitude: 2}}}, "base location updated"},
+		{
+			"several", []FieldChange{{Field: "role", Old: "Admin", New: "Owner"}, {Field: "password", Old: MaskedValue, New: MaskedValue}},
+			"role changed from 'Admin' to 'Owner', password updated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChangeComments(tt.changes); got != tt.want {
+				t.Errorf("ChangeComments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
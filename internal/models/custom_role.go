@@ -0,0 +1,27 @@
+package models
+
+// CustomRole is a role an organisation defines in addition to the built-in roles.
+// @Description Role defined by an organisation, with the permissions its users hold.
+type CustomRole struct {
+	RoleId      string       `bson:"role_id" json:"role_id" example:"7c1e2d3f-4a5b-6c7d-8e9f-0a1b2c3d4e5f"`   // Unique identifier of the role
+	OrgUUID     string       `bson:"org_uuid" json:"org_uuid" example:"123e4567-e89b-12d3-a456-426614174000"` // UUID of the organisation the role belongs to
+	Name        Role         `bson:"name" json:"name" example:"Quality Auditor"`                              // Role name, as set on users
+	Permissions []Permission `bson:"permissions" json:"permissions" example:"prospect.read"`                  // Permissions the role holds
+	CreatedBy   string       `bson:"created_by" json:"created_by" example:"owner"`                            // User who created the role
+	CreatedTime string       `bson:"created_time" json:"created_time" example:"2023-04-12T15:04:05Z"`         // Time the role was created
+	UpdatedBy   string       `bson:"updated_by,omitempty" json:"updated_by,omitempty" example:"owner"`        // User who last changed the permissions
+	UpdatedTime string       `bson:"updated_time,omitempty" json:"updated_time,omitempty"`                    // Time the permissions were last changed
+}
+
+// CustomRoleReq represents the request payload for creating a custom role.
+// @Description Name and permissions of a new custom role.
+type CustomRoleReq struct {
+	Name        Role         `json:"name" binding:"required,max=50" example:"Quality Auditor"`     // Role name; may not be a built-in role
+	Permissions []Permission `json:"permissions" binding:"required,min=1" example:"prospect.read"` // Permissions the role holds
+}
+
+// CustomRolePermissionsReq represents the request payload for changing the permissions of a custom role.
+// @Description New permissions of a custom role.
+type CustomRolePermissionsReq struct {
+	Permissions []Permission `json:"permissions" binding:"required,min=1" example:"prospect.read"` // Permissions the role holds
+}
@@ -0,0 +1,9 @@
+package models
+
+// GeoPoint represents a WGS84 coordinate. Bound request bodies must be in range and may not be (0, 0),
+// which is what an empty object decodes to.
+// @Description Latitude and longitude in decimal degrees.
+type GeoPoint struct {
+	Latitude  float64 `bson:"latitude" json:"latitude" binding:"min=-90,max=90,required_if=Longitude 0" example:"12.9716"` // Latitude in decimal degrees
+	Longitude float64 `bson:"longitude" json:"longitude" binding:"min=-180,max=180" example:"77.5946"`                     // Longitude in decimal degrees
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// historyLink is the canonical content hashed into a prospect's history chain. Field order is fixed by the
+// struct, so the same entry always hashes the same.
+type historyLink struct {
+	Prospect string `json:"prospect"`
+	Prev     string `json:"prev"`
+	Time     string `json:"time"`
+	By       string `json:"by"`
+	Comments string `json:"comments"`
+	Changes  string `json:"changes,omitempty"`
+}
+
+// HistoryHash returns the hash that seals an update history entry of a prospect. It covers the prospect,
+// the entry's PrevHash and the entry's content, so an entry can neither be changed nor moved to another
+// place or prospect without breaking the chain.
+func HistoryHash(prospectUId string, entry UpdateHistory) string {
+	link := historyLink{
+		Prospect: prospectUId,
+		Prev:     entry.PrevHash,
+		Time:     entry.UpdatedTime,
+		By:       entry.UpdateBy,
+		Comments: entry.UpdatedComments,
+	}
+	// Changed values are hashed in their BSON encoding, which stays the same when the entry is read back
+	// from the database, unlike the Go types they were recorded with
+	if len(entry.Changes) > 0 {
+		changes, _ := bson.Marshal(bson.D{{Key: "changes", Value: entry.Changes}})
+		sum := sha256.Sum256(changes)
+		link.Changes = hex.EncodeToString(sum[:])
+	}
+	content, _ := json.Marshal(link)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// SealHistoryEntry chains an entry to the given previous hash
+func SealHistoryEntry(prospectUId string, prevHash string, entry UpdateHistory) UpdateHistory {
+	entry.PrevHash = prevHash
+	entry.Hash = HistoryHash(prospectUId, entry)
+	return entry
+}
+
+// SealHistory chains the entries appended after the last sealed entry and moves HistoryHead to the end
+// of the chain. Entries that are already sealed are left untouched.
+func (p *Prospect) SealHistory() {
+	start := 0
+	prev := ""
+	for i := len(p.UpdateHistory) - 1; i >= 0; i-- {
+		if p.UpdateHistory[i].Hash != "" {
+			start = i + 1
+			prev = p.UpdateHistory[i].Hash
+			break
+		}
+	}
+	for i := start; i < len(p.UpdateHistory); i++ {
+		p.UpdateHistory[i] = SealHistoryEntry(p.UId, prev, p.UpdateHistory[i])
+		prev = p.UpdateHistory[i].Hash
+	}
+	if prev != "" {
+		p.HistoryHead = prev
+	}
+}
+
+// HistoryHead is the end of one prospect's history chain at the time of an anchor
+// @Description Chain head of a prospect recorded in an anchor.
+type HistoryHead struct {
+	ProspectUId string `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174000"` // UId of the prospect
+	Length      int    `bson:"length" json:"length" example:"7"`                              // Number of history entries
+	Hash        string `bson:"hash" json:"hash"`                                              // Hash of the last entry
+}
+
+// HistoryAnchor records the chain heads of an organisation's prospects that changed since the previous
+// anchor. Anchors are chained by their digests, so the latest digest vouches for every earlier anchor.
+// @Description Periodic digest of an organisation's prospect history chains.
+type HistoryAnchor struct {
+	AnchorId   string        `bson:"anchor_id" json:"anchor_id" example:"5d6e7f80-91a2-4b3c-8d4e-5f6a7b8c9d0e"` // Unique identifier of the anchor
+	OrgUUID    string        `bson:"org_uuid" json:"org_uuid" example:"123e4567-e89b-12d3-a456-426614174000"`   // Organisation the anchor belongs to
+	Time       string        `bson:"time" json:"time" example:"2023-04-12T15:04:05Z"`                           // Time the heads were read
+	PrevDigest string        `bson:"prev_digest" json:"prev_digest"`                                            // Digest of the organisation's previous anchor
+	Digest     string        `bson:"digest" json:"digest"`                                                      // SHA-256 over the previous digest, the time and the heads
+	Heads      []HistoryHead `bson:"heads" json:"heads"`                                                        // Chain heads of the prospects that changed
+}
+
+// HistoryBrokenLink is the first entry of a prospect's history that fails verification
+// @Description Position and cause of the first broken link of a history chain.
+type HistoryBrokenLink struct {
+	Index  int            `json:"index" example:"3"`                              // Zero-based index into update_history; the length when entries are missing at the end
+	Entry  *UpdateHistory `json:"entry,omitempty"`                                // The entry at that index, if there is one
+	Reason string         `json:"reason" example:"entry does not match its hash"` // Why the link is broken
+}
+
+// HistoryBrokenAnchor is the first anchor of an organisation's anchor chain that fails verification
+// @Description Anchor and cause of the first broken link of an anchor chain.
+type HistoryBrokenAnchor struct {
+	AnchorId string `json:"anchor_id,omitempty" example:"5d6e7f80-91a2-4b3c-8d4e-5f6a7b8c9d0e"` // The anchor, if there is one
+	Time     string `json:"time,omitempty" example:"2023-04-12T15:04:05Z"`                      // Time of the anchor
+	Reason   string `json:"reason" example:"anchor does not match its digest"`                  // Why the link is broken
+}
+
+// HistoryVerification is the result of verifying a prospect's history chain
+// @Description Outcome of checking a prospect's update history against its hash chain and anchors.
+type HistoryVerification struct {
+	ProspectUId    string               `json:"uid" example:"123e4567-e89b-12d3-a456-426614174000"` // UId of the prospect
+	Valid          bool                 `json:"valid" example:"true"`                               // Whether the whole chain verified
+	Entries        int                  `json:"entries" example:"7"`                                // Number of history entries
+	Unsealed       int                  `json:"unsealed" example:"0"`                               // Entries written before the chain was introduced
+	Head           string               `json:"head,omitempty"`                                     // Recorded hash of the last entry
+	AnchorsChecked int                  `json:"anchors_checked" example:"2"`                        // Number of anchors the chain was compared with
+	LastAnchorTime string               `json:"last_anchor_time,omitempty"`                         // Time of the latest of those anchors
+	LatestDigest   string               `json:"latest_digest,omitempty"`                            // Digest of the organisation's newest anchor, to compare with the published digests
+	BrokenLink     *HistoryBrokenLink   `json:"broken_link,omitempty"`                              // First entry that failed, when the chain is not valid
+	BrokenAnchor   *HistoryBrokenAnchor `json:"broken_anchor,omitempty"`                            // First anchor that failed, when the anchor chain is not valid
+}
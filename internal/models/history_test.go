@@ -0,0 +1,151 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func historyEntry() UpdateHistory {
+	return UpdateHistory{
+		UpdatedComments: "age changed from '30' to '31'",
+		UpdatedTime:     "2023-04-12T15:04:05Z",
+		UpdateBy:        "ops_lead",
+		PrevHash:        "prev",
+		Changes:         []FieldChange{{Field: "age", Old: 30, New: 31}},
+	}
+}
+
+func TestHistoryHashCoversEntry(t *testing.T) {
+	base := HistoryHash("prospect-1", historyEntry())
+	tests := []struct {
+		name     string
+		prospect string
+		change   func(*UpdateHistory)
+	}{
+		{"other prospect", "prospect-2", func(*UpdateHistory) {}},
+		{"previous hash", "prospect-1", func(e *UpdateHistory) { e.PrevHash = "other" }},
+		{"time", "prospect-1", func(e *UpdateHistory) { e.UpdatedTime = "2023-04-12T15:04:06Z" }},
+		{"author", "prospect-1", func(e *UpdateHistory) { e.UpdateBy = "admin" }},
+		{"comment", "prospect-1", func(e *UpdateHistory) { e.UpdatedComments = "edited" }},
+		{"changed value", "prospect-1", func(e *UpdateHistory) { e.Changes[0].New = 32 }},
+		{"changed field", "prospect-1", func(e *UpdateHistory) { e.Changes[0].Field = "years_of_stay" }},
+		{"changes removed", "prospect-1", func(e *UpdateHistory) { e.Changes = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := historyEntry()
+			tt.change(&entry)
+			if HistoryHash(tt.prospect, entry) == base {
+				t.Errorf("hash did not change")
+			}
+		})
+	}
+}
+
+func TestHistoryHashIgnoresOwnHash(t *testing.T) {
+	entry := historyEntry()
+	want := HistoryHash("prospect-1", entry)
+	entry.Hash = "anything"
+	if got := HistoryHash("prospect-1", entry); got != want {
+		t.Errorf("HistoryHash depends on the stored hash: got %s, want %s", got, want)
+	}
+}
+
+func TestHistoryHashSurvivesDatabaseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []FieldChange
+	}{
+		{"ints", []FieldChange{{Field: "age", Old: 30, New: 31}}},
+		{"strings", []FieldChange{{Field: "status", Old: "Pending", New: "OnVisit"}}},
+		{"created", []FieldChange{{Field: "remarks", Old: nil, New: "visited"}}},
+		{"bools and floats", []FieldChange{{Field: "name_verified", Old: false, New: true}, {Field: "net_salary", Old: 1.5, New: 2.25}}},
+		{"masked", []FieldChange{{Field: "password", Old: MaskedValue, New: MaskedValue}}},
+		{"structured", []FieldChange{{Field: "residential_location", Old: nil, New: GeoPoint{Latitude: 12.97, Longitude: 77.59}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := historyEntry()
+			entry.Changes = tt.changes
+			entry = SealHistoryEntry("prospect-1", "prev", entry)
+
+			raw, err := bson.Marshal(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stored UpdateHistory
+			if err := bson.Unmarshal(raw, &stored); err != nil {
+				t.Fatal(err)
+			}
+			if got := HistoryHash("prospect-1", stored); got != entry.Hash {
+				t.Errorf("hash after round trip = %s, want %s", got, entry.Hash)
+			}
+		})
+	}
+}
+
+func TestSealHistory(t *testing.T) {
+	unsealed := UpdateHistory{UpdatedComments: "before chaining", UpdatedTime: "2023-01-01T00:00:00Z", UpdateBy: "admin"}
+	sealed := SealHistoryEntry("prospect-1", "", historyEntry())
+	fresh := UpdateHistory{UpdatedComments: "new", UpdatedTime: "2023-05-01T00:00:00Z", UpdateBy: "field_exec"}
+
+	tests := []struct {
+		name        string
+		history     []UpdateHistory
+		wantHead    bool
+		wantSealed  int // index of the first entry expected to be chained
+		wantUnmoved []int
+	}{
+		{"empty history", nil, false, 0, nil},
+		{"only entries from before chaining", []UpdateHistory{unsealed}, true, 0, nil},
+		{"new entries after unsealed ones", []UpdateHistory{unsealed, fresh, fresh}, true, 0, nil},
+		{"new entries after sealed ones", []UpdateHistory{unsealed, sealed, fresh}, true, 1, []int{1}},
+		{"nothing new", []UpdateHistory{sealed}, true, 0, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Prospect{UId: "prospect-1", UpdateHistory: append([]UpdateHistory(nil), tt.history...)}
+			p.SealHistory()
+
+			if (p.HistoryHead != "") != tt.wantHead {
+				t.Fatalf("HistoryHead = %q, want set: %v", p.HistoryHead, tt.wantHead)
+			}
+			for _, i := range tt.wantUnmoved {
+				if p.UpdateHistory[i].Hash != tt.history[i].Hash || p.UpdateHistory[i].PrevHash != tt.history[i].PrevHash {
+					t.Errorf("sealed entry %d was rewritten", i)
+				}
+			}
+			prev := ""
+			if tt.wantSealed > 0 {
+				prev = p.UpdateHistory[tt.wantSealed-1].Hash
+			}
+			for i := tt.wantSealed; i < len(p.UpdateHistory); i++ {
+				entry := p.UpdateHistory[i]
+				if entry.PrevHash != prev {
+					t.Errorf("entry %d prev_hash = %q, want %q", i, entry.PrevHash, prev)
+				}
+				if entry.Hash != HistoryHash(p.UId, entry) {
+					t.Errorf("entry %d does not match its hash", i)
+				}
+				prev = entry.Hash
+			}
+			if len(p.UpdateHistory) > 0 && p.HistoryHead != p.UpdateHistory[len(p.UpdateHistory)-1].Hash {
+				t.Errorf("HistoryHead = %q, want the last entry's hash", p.HistoryHead)
+			}
+
+			// Sealing again changes nothing
+			again := append([]UpdateHistory(nil), p.UpdateHistory...)
+			head := p.HistoryHead
+			p.SealHistory()
+			for i := range again {
+				if p.UpdateHistory[i].Hash != again[i].Hash {
+					t.Errorf("second SealHistory rewrote entry %d", i)
+				}
+			}
+			if p.HistoryHead != head {
+				t.Errorf("second SealHistory moved the head")
+			}
+		})
+	}
+}
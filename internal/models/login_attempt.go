@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// LoginAttemptScope says what a login attempt counter is keyed on
+type LoginAttemptScope string
+
+const (
+	LoginAttemptAccount LoginAttemptScope = "account" // Username within an organisation
+	LoginAttemptIP      LoginAttemptScope = "ip"      // Client IP address
+)
+
+// LoginAttempt counts recent failed logins for an account or a client IP. The record expires once no
+// failure has been seen for the attempt window and any lockout has ended.
+type LoginAttempt struct {
+	Key         string            `bson:"key" json:"key"`                                       // Scope-qualified key, e.g. account:<org_uuid>:<username>
+	Scope       LoginAttemptScope `bson:"scope" json:"scope"`                                   // What the counter is keyed on
+	Failures    int               `bson:"failures" json:"failures"`                             // Failed attempts within the window
+	LastFailure time.Time         `bson:"last_failure" json:"last_failure"`                     // Time of the latest failed attempt
+	LockedUntil *time.Time        `bson:"locked_until,omitempty" json:"locked_until,omitempty"` // End of the lockout, if locked
+	ExpiresAt   time.Time         `bson:"expires_at" json:"expires_at"`                         // Time after which the record is discarded
+}
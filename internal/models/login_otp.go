@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// LoginOTP is the state of a user's SMS login codes. Only the hash of the outstanding code is stored;
+// the send counters stay after it is used so that resends remain rate limited.
+type LoginOTP struct {
+	CodeHash    string    `bson:"code_hash,omitempty"`  // Keyed hash of the outstanding code
+	ExpiresAt   time.Time `bson:"expires_at,omitempty"` // Time after which the code is no longer accepted
+	Attempts    int       `bson:"attempts"`             // Wrong guesses against the outstanding code
+	LastSent    time.Time `bson:"last_sent"`            // Time the latest code was sent
+	WindowStart time.Time `bson:"window_start"`         // Start of the current send-limit window
+	Sends       int       `bson:"sends"`                // Codes sent within the window
+}
+
+// OTPLoginRequest asks for a login code by SMS.
+// @Description Organisation and mobile number of the user logging in.
+type OTPLoginRequest struct {
+	OrgId        string `json:"org_id" binding:"required" example:"123456"`            // Organization ID
+	MobileNumber string `json:"mobile_number" binding:"required" example:"9876543210"` // Mobile number of the user
+}
+
+// OTPVerifyRequest completes a login with a code received by SMS.
+// @Description Organisation, mobile number and the code received by SMS.
+type OTPVerifyRequest struct {
+	OrgId        string `json:"org_id" binding:"required" example:"123456"`            // Organization ID
+	MobileNumber string `json:"mobile_number" binding:"required" example:"9876543210"` // Mobile number of the user
+	Code         string `json:"code" binding:"required" example:"482913"`              // Code received by SMS
+}
+
+// OTPSentResponse is returned when a login code was requested. It looks the same whether or not the
+// number belongs to a user, so it cannot be used to find registered numbers.
+// @Description Confirmation that a code was sent if the number belongs to a user who may log in by SMS.
+type OTPSentResponse struct {
+	Message   string `json:"message" example:"If the number is registered, a login code has been sent"` // Confirmation message
+	ExpiresIn int    `json:"expires_in" example:"300"`                                                  // Code lifetime in seconds
+}
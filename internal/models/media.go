@@ -0,0 +1,61 @@
+package models
+
+// MediaCategory describes what an uploaded prospect file shows.
+// Enum: "ResidenceFront", "ResidenceInterior", "OfficeFront", "OfficeInterior", "IDCard", "Selfie", "Document", "Other"
+type MediaCategory string
+
+const (
+	ResidenceFront    MediaCategory = "ResidenceFront"
+	ResidenceInterior MediaCategory = "ResidenceInterior"
+	OfficeFront       MediaCategory = "OfficeFront"
+	OfficeInterior    MediaCategory = "OfficeInterior"
+	IDCard            MediaCategory = "IDCard"
+	Selfie            MediaCategory = "Selfie"
+	Document          MediaCategory = "Document"
+	OtherMedia        MediaCategory = "Other"
+)
+
+// MediaFlag marks a field photo whose capture evidence needs a reviewer's attention.
+// Enum: "NoCaptureTime", "NoGeotag", "NoTargetLocation", "LocationMismatch", "OutsideVisitWindow"
+type MediaFlag string
+
+const (
+	FlagNoCaptureTime      MediaFlag = "NoCaptureTime"
+	FlagNoGeotag           MediaFlag = "NoGeotag"
+	FlagNoTargetLocation   MediaFlag = "NoTargetLocation"
+	FlagLocationMismatch   MediaFlag = "LocationMismatch"
+	FlagOutsideVisitWindow MediaFlag = "OutsideVisitWindow"
+)
+
+// ProspectMedia represents a file uploaded against a prospect.
+// @Description Metadata of an image or document uploaded for a prospect.
+//
+//	@Example {
+//	  "uid": "123e4567-e89b-12d3-a456-426614174333",
+//	  "file_name": "front.jpg",
+//	  "category": "ResidenceFront",
+//	  "content_type": "image/jpeg",
+//	  "size": 245123,
+//	  "checksum": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+//	  "uploaded_by": "field_exec",
+//	  "uploaded_time": "2023-04-12T15:04:05Z",
+//	  "capture_time": "2023-04-12T14:55:00Z",
+//	  "capture_location": {"latitude": 12.9716, "longitude": 77.5946},
+//	  "distance_km": 0.12,
+//	  "flags": []
+//	}
+type ProspectMedia struct {
+	UId             string        `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174333"`                                       // Unique identifier of the file
+	FileName        string        `bson:"file_name" json:"file_name" example:"front.jpg"`                                                      // Original file name
+	Category        MediaCategory `bson:"category" json:"category" example:"ResidenceFront"`                                                   // What the file shows
+	ContentType     string        `bson:"content_type" json:"content_type" example:"image/jpeg"`                                               // MIME type detected from the content
+	Size            int64         `bson:"size" json:"size" example:"245123"`                                                                   // Size in bytes
+	Checksum        string        `bson:"checksum" json:"checksum" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"` // SHA-256 of the content
+	StorageKey      string        `bson:"storage_key" json:"-"`                                                                                // Key of the object in the storage backend
+	UploadedBy      string        `bson:"uploaded_by" json:"uploaded_by" example:"field_exec"`                                                 // User who uploaded the file
+	UploadedTime    string        `bson:"uploaded_time" json:"uploaded_time" example:"2023-04-12T15:04:05Z"`                                   // Time of the upload
+	CaptureTime     string        `bson:"capture_time,omitempty" json:"capture_time,omitempty" example:"2023-04-12T14:55:00Z"`                 // Capture time from EXIF
+	CaptureLocation *GeoPoint     `bson:"capture_location,omitempty" json:"capture_location,omitempty"`                                        // GPS position from EXIF
+	DistanceKm      *float64      `bson:"distance_km,omitempty" json:"distance_km,omitempty" example:"0.12"`                                   // Distance between the capture position and the verified address
+	Flags           []MediaFlag   `bson:"flags,omitempty" json:"flags,omitempty" example:"LocationMismatch"`                                   // Issues found with the capture evidence
+}
@@ -10,18 +10,54 @@ const (
 	OrgInActive OrganisationStatus = "InActive"
 )
 
+// AssignmentStrategy selects how new prospects are distributed among field executives.
+// Enum: "Manual", "RoundRobin", "LeastOpenCases", "Nearest"
+type AssignmentStrategy string
+
+const (
+	AssignManual         AssignmentStrategy = "Manual"
+	AssignRoundRobin     AssignmentStrategy = "RoundRobin"
+	AssignLeastOpenCases AssignmentStrategy = "LeastOpenCases"
+	AssignNearest        AssignmentStrategy = "Nearest"
+)
+
+// PasswordPolicy sets the rules new passwords of an organisation's users must meet. Common passwords
+// from the denylist are always rejected.
+// @Description Password rules of an organisation.
+type PasswordPolicy struct {
+	MinLength     int  `json:"min_length" bson:"min_length" example:"10"`            // Minimum number of characters
+	RequireUpper  bool `json:"require_upper" bson:"require_upper" example:"true"`    // Require an upper-case letter
+	RequireLower  bool `json:"require_lower" bson:"require_lower" example:"true"`    // Require a lower-case letter
+	RequireDigit  bool `json:"require_digit" bson:"require_digit" example:"true"`    // Require a digit
+	RequireSymbol bool `json:"require_symbol" bson:"require_symbol" example:"false"` // Require a character that is not a letter or digit
+	HistorySize   int  `json:"history_size" bson:"history_size" example:"5"`         // Number of previous passwords that may not be reused
+}
+
+// PasswordViolation is one password policy rule a password does not meet.
+type PasswordViolation struct {
+	Rule    string `json:"rule" example:"min_length"`                             // Rule identifier
+	Message string `json:"message" example:"must be at least 10 characters long"` // Human-readable description
+}
+
 // OrganisationReq represents an Organisation Request in the system.
 // @Description OrganisationReq model containing all organisation request related information.
 //
 //	@Example {
 //	  "org_id": "12345",
 //	  "org_name": "Acme Corp",
-//	  "status": "Active"
+//	  "status": "Active",
+//	  "assignment_strategy": "LeastOpenCases",
+//	  "two_factor_roles": ["Owner", "Admin"],
+//	  "role_permissions": {"Field Lead": ["prospect.read", "prospect.assign"]}
 //	}
 type OrganisationReq struct {
-	OrgId   string             `json:"org_id" bson:"org_id" example:"12345"`         // Organisation ID
-	OrgName string             `json:"org_name" bson:"org_name" example:"Acme Corp"` // Organisation Name
-	Status  OrganisationStatus `json:"status" bson:"status" example:"Active"`        // Organisation Status
+	OrgId              string             `json:"org_id" bson:"org_id" example:"12345"`                                    // Organisation ID
+	OrgName            string             `json:"org_name" bson:"org_name" example:"Acme Corp"`                            // Organisation Name
+	Status             OrganisationStatus `json:"status" bson:"status" example:"Active"`                                   // Organisation Status
+	AssignmentStrategy AssignmentStrategy `json:"assignment_strategy" bson:"assignment_strategy" example:"LeastOpenCases"` // Strategy for assigning new prospects
+	PasswordPolicy     *PasswordPolicy    `json:"password_policy,omitempty" bson:"password_policy,omitempty"`              // Password rules; the server default applies when omitted
+	TwoFactorRoles     []Role             `json:"two_factor_roles,omitempty" bson:"two_factor_roles,omitempty"`            // Roles that must log in with a second factor
+	RolePermissions    RolePolicy         `json:"role_permissions,omitempty" bson:"role_permissions,omitempty"`            // Permissions of the roles whose defaults the organisation replaces
 }
 
 // Organisation represents an organisation in the system.
@@ -30,11 +66,18 @@ type OrganisationReq struct {
 //	@Example {
 //	  "org_id": "12345",
 //	  "org_name": "Acme Corp",
-//	  "status": "Active"
+//	  "status": "Active",
+//	  "assignment_strategy": "LeastOpenCases"
 //	}
 type Organisation struct {
-	OrgId   string             `json:"org_id" bson:"org_id" example:"12345"`         // Organisation ID
-	OrgName string             `json:"org_name" bson:"org_name" example:"Acme Corp"` // Organisation Name
-	OrgUUID string             `json:"org_uuid" bson:"org_uuid" example:"uuid-v4"`   // Auto-generated UUID
-	Status  OrganisationStatus `json:"status" bson:"status" example:"Active"`        // Organisation Status
+	OrgId              string             `json:"org_id" bson:"org_id" example:"12345"`                                                // Organisation ID
+	OrgName            string             `json:"org_name" bson:"org_name" example:"Acme Corp"`                                        // Organisation Name
+	OrgUUID            string             `json:"org_uuid" bson:"org_uuid" example:"uuid-v4"`                                          // Auto-generated UUID
+	Status             OrganisationStatus `json:"status" bson:"status" example:"Active"`                                               // Organisation Status
+	AssignmentStrategy AssignmentStrategy `json:"assignment_strategy" bson:"assignment_strategy" example:"LeastOpenCases"`             // Strategy for assigning new prospects
+	PasswordPolicy     *PasswordPolicy    `json:"password_policy,omitempty" bson:"password_policy,omitempty"`                          // Password rules; the server default applies when unset
+	TwoFactorRoles     []Role             `json:"two_factor_roles,omitempty" bson:"two_factor_roles"`                                  // Roles that must log in with a second factor
+	RolePermissions    RolePolicy         `json:"role_permissions,omitempty" bson:"role_permissions"`                                  // Permissions of the roles whose defaults the organisation replaces
+	AssignmentCursor   string             `json:"-" bson:"assignment_cursor"`                                                          // UId of the last round-robin assignee
+	CreatedTime        string             `json:"created_time,omitempty" bson:"created_time,omitempty" example:"2023-04-12T15:04:05Z"` // Time when the organisation was created
 }
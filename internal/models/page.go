@@ -0,0 +1,17 @@
+package models
+
+// Page is the envelope returned by the list endpoints.
+// @Description A page of results with the cursor of the next page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`                                        // Results of this page
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJmIjoiY3Jl"` // Pass as cursor to fetch the next page; empty on the last page
+	Total      *int   `json:"total,omitempty" example:"42"`                 // Number of matching records, when requested with include_total
+}
+
+// PageRequest selects a page either by offset (Skip) or by the opaque Cursor of a previous page
+type PageRequest struct {
+	Skip         int
+	Limit        int
+	Cursor       string
+	IncludeTotal bool
+}
@@ -0,0 +1,187 @@
+package models
+
+import "slices"
+
+// Permission names an action that can be granted to a role or an API key.
+type Permission string
+
+const (
+	// Users
+	PermUserCreate         Permission = "user.create"         // Create users of a role the caller can manage
+	PermUserRead           Permission = "user.read"           // List and read users
+	PermUserUpdate         Permission = "user.update"         // Update users of a role the caller can manage
+	PermUserUnlock         Permission = "user.unlock"         // Lift a login lockout
+	PermUserPasswordReset  Permission = "user.password.reset" // Issue password reset codes
+	PermUserTwoFactorReset Permission = "user.2fa.reset"      // Remove a user's second factor
+	PermAccountTwoFactor   Permission = "account.2fa"         // Enrol one's own second factor
+	PermAPIKeyManage       Permission = "apikey.manage"       // Create, list and revoke API keys
+	PermRoleManage         Permission = "role.manage"         // Define the organisation's custom roles
+	PermAuditRead          Permission = "audit.read"          // Query the audit log
+
+	// Prospects
+	PermProspectCreate        Permission = "prospect.create"         // Create prospects
+	PermProspectRead          Permission = "prospect.read"           // List, count and read prospects, their media and visits
+	PermProspectUpdate        Permission = "prospect.update"         // Edit prospect details
+	PermProspectAssign        Permission = "prospect.assign"         // Assign, reassign and unassign prospects
+	PermProspectVisit         Permission = "prospect.visit"          // Check in and out of visits and start verification
+	PermProspectVisitPostpone Permission = "prospect.visit.postpone" // Postpone a prospect during a visit
+	PermProspectSubmit        Permission = "prospect.submit"         // Submit a verified prospect for review
+	PermProspectPostpone      Permission = "prospect.postpone"       // Postpone a pending prospect
+	PermProspectResume        Permission = "prospect.resume"         // Return a postponed prospect to pending
+	PermProspectReview        Permission = "prospect.review"         // Take a submitted prospect under review or send it back
+	PermProspectApprove       Permission = "prospect.approve"        // Approve a prospect under review
+	PermProspectReject        Permission = "prospect.reject"         // Reject a prospect under review
+	PermProspectComplete      Permission = "prospect.complete"       // Complete an approved or rejected prospect
+	PermProspectCancel        Permission = "prospect.cancel"         // Cancel a prospect before a decision
+	PermMediaUpload           Permission = "prospect.media.upload"   // Upload prospect media
+	PermMediaDelete           Permission = "prospect.media.delete"   // Delete prospect media
+	PermReportGenerate        Permission = "prospect.report"         // Generate verification reports
+)
+
+// Permissions lists every permission
+var Permissions = []Permission{
+	PermUserCreate, PermUserRead, PermUserUpdate, PermUserUnlock, PermUserPasswordReset, PermUserTwoFactorReset,
+	PermAccountTwoFactor, PermAPIKeyManage, PermRoleManage, PermAuditRead,
+	PermProspectCreate, PermProspectRead, PermProspectUpdate, PermProspectAssign, PermProspectVisit,
+	PermProspectVisitPostpone, PermProspectSubmit, PermProspectPostpone, PermProspectResume, PermProspectReview,
+	PermProspectApprove, PermProspectReject, PermProspectComplete, PermProspectCancel,
+	PermMediaUpload, PermMediaDelete, PermReportGenerate,
+}
+
+// Roles lists the built-in roles, most senior first
+var Roles = []Role{Owner, Admin, OperationsLead, OperationsExecutive, FieldLead, FieldExecutive}
+
+// RolePolicy maps each role to the permissions it holds.
+type RolePolicy map[Role][]Permission
+
+// defaultRolePolicy is the permission set of each role unless its organisation overrides it
+var defaultRolePolicy = RolePolicy{
+	Owner: {
+		PermUserCreate, PermUserRead, PermUserUpdate, PermUserUnlock, PermUserPasswordReset, PermUserTwoFactorReset,
+		PermAccountTwoFactor, PermAPIKeyManage, PermRoleManage, PermAuditRead,
+		PermProspectCreate, PermProspectRead, PermProspectUpdate, PermProspectComplete, PermProspectCancel,
+		PermMediaUpload, PermMediaDelete, PermReportGenerate,
+	},
+	Admin: {
+		PermUserCreate, PermUserRead, PermUserUpdate, PermUserUnlock, PermUserPasswordReset, PermUserTwoFactorReset,
+		PermAccountTwoFactor, PermAPIKeyManage, PermAuditRead,
+		PermProspectCreate, PermProspectRead, PermProspectUpdate, PermProspectComplete, PermProspectCancel,
+		PermMediaUpload, PermMediaDelete, PermReportGenerate,
+	},
+	OperationsLead: {
+		PermUserCreate, PermUserRead, PermUserUpdate, PermAccountTwoFactor,
+		PermProspectCreate, PermProspectRead, PermProspectUpdate, PermProspectAssign, PermProspectPostpone,
+		PermProspectResume, PermProspectReview, PermProspectApprove, PermProspectReject, PermProspectComplete,
+		PermProspectCancel, PermMediaUpload, PermMediaDelete, PermReportGenerate,
+	},
+	OperationsExecutive: {
+		PermUserRead, PermUserUpdate, PermAccountTwoFactor,
+		PermProspectCreate, PermProspectRead, PermProspectUpdate, PermProspectResume, PermProspectReview,
+		PermMediaUpload, PermReportGenerate,
+	},
+	FieldLead: {
+		PermAccountTwoFactor,
+		PermProspectCreate, PermProspectRead, PermProspectUpdate, PermProspectAssign, PermProspectPostpone,
+		PermProspectVisitPostpone, PermProspectResume, PermMediaUpload, PermMediaDelete,
+	},
+	FieldExecutive: {
+		PermAccountTwoFactor,
+		PermProspectRead, PermProspectUpdate, PermProspectVisit, PermProspectVisitPostpone, PermProspectSubmit,
+		PermProspectPostpone, PermMediaUpload,
+	},
+}
+
+// managedRoles is the role hierarchy: the roles whose users each role may create and manage
+var managedRoles = map[Role][]Role{
+	Owner:               {Owner, Admin, OperationsLead, OperationsExecutive, FieldLead, FieldExecutive},
+	Admin:               {Admin, OperationsLead, OperationsExecutive, FieldLead, FieldExecutive},
+	OperationsLead:      {OperationsLead, OperationsExecutive, FieldLead, FieldExecutive},
+	OperationsExecutive: {OperationsExecutive, FieldLead, FieldExecutive},
+}
+
+// customRoleManagers are the roles whose users may create and manage users of custom roles
+var customRoleManagers = []Role{Owner, Admin}
+
+// RolePolicyFor returns the default policy with the permissions of the overridden roles replaced and the
+// custom roles added
+func RolePolicyFor(overrides RolePolicy, customRoles []CustomRole) RolePolicy {
+	policy := make(RolePolicy, len(defaultRolePolicy)+len(customRoles))
+	for role, permissions := range defaultRolePolicy {
+		policy[role] = permissions
+	}
+	for role, permissions := range overrides {
+		policy[role] = permissions
+	}
+	for _, customRole := range customRoles {
+		policy[customRole.Name] = customRole.Permissions
+	}
+	return policy
+}
+
+// RolePolicy returns the permissions of the organisation's built-in and custom roles
+func (o *Organisation) RolePolicy(customRoles []CustomRole) RolePolicy {
+	return RolePolicyFor(o.RolePermissions, customRoles)
+}
+
+// Allows reports whether a role holds a permission
+func (p RolePolicy) Allows(role Role, permission Permission) bool {
+	return slices.Contains(p[role], permission)
+}
+
+// HasRole reports whether the policy defines a role
+func (p RolePolicy) HasRole(role Role) bool {
+	_, ok := p[role]
+	return ok
+}
+
+// IsBuiltInRole reports whether role is one of the built-in roles
+func IsBuiltInRole(role Role) bool {
+	return slices.Contains(Roles, role)
+}
+
+// IsValidPermission reports whether permission is a known permission
+func IsValidPermission(permission Permission) bool {
+	return slices.Contains(Permissions, permission)
+}
+
+// HoldsAll reports whether a role holds every one of the given permissions
+func (p RolePolicy) HoldsAll(role Role, permissions []Permission) bool {
+	for _, permission := range permissions {
+		if !p.Allows(role, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanManageRole reports whether users of one role may create and manage users of another. Any role that
+// is not built in is taken to be a custom role, which may only be managed by a custom role manager that
+// holds every permission it grants.
+func (p RolePolicy) CanManageRole(actor Role, target Role) bool {
+	if !IsBuiltInRole(target) {
+		return slices.Contains(customRoleManagers, actor) && p.HoldsAll(actor, p[target])
+	}
+	return slices.Contains(managedRoles[actor], target)
+}
+
+// ManagedRoles returns the roles whose users a role may create and manage, out of the built-in roles and
+// the given custom roles
+func (p RolePolicy) ManagedRoles(actor Role, customRoles []CustomRole) []Role {
+	roles := append([]Role{}, managedRoles[actor]...)
+	for _, customRole := range customRoles {
+		if p.CanManageRole(actor, customRole.Name) {
+			roles = append(roles, customRole.Name)
+		}
+	}
+	return roles
+}
+
+// RoleInfo describes a role of an organisation.
+// @Description A role with its permissions and the roles its users may manage.
+type RoleInfo struct {
+	Role         Role         `json:"role" example:"Operations Lead"`          // Role name
+	Permissions  []Permission `json:"permissions" example:"prospect.approve"`  // Permissions the role holds
+	ManagesRoles []Role       `json:"manages_roles" example:"Field Executive"` // Roles whose users it may create and manage
+	Overridden   bool         `json:"overridden" example:"false"`              // The organisation replaced the default permissions
+	Custom       bool         `json:"custom" example:"false"`                  // The organisation defined the role
+}
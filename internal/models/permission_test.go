@@ -0,0 +1,135 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRolePolicyAllows(t *testing.T) {
+	overridden := RolePolicyFor(RolePolicy{FieldLead: {PermProspectRead, PermProspectApprove}}, nil)
+	tests := []struct {
+		name       string
+		policy     RolePolicy
+		role       Role
+		permission Permission
+		want       bool
+	}{
+		{"default grant", RolePolicyFor(nil, nil), OperationsLead, PermProspectApprove, true},
+		{"default denial", RolePolicyFor(nil, nil), FieldExecutive, PermProspectApprove, false},
+		{"override grants", overridden, FieldLead, PermProspectApprove, true},
+		{"override replaces the defaults", overridden, FieldLead, PermMediaUpload, false},
+		{"other roles keep their defaults", overridden, FieldExecutive, PermProspectSubmit, true},
+		{"unknown role", RolePolicyFor(nil, nil), Role("Intern"), PermProspectRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Allows(tt.role, tt.permission); got != tt.want {
+				t.Errorf("Allows(%s, %s) = %v, want %v", tt.role, tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolePolicyFor(t *testing.T) {
+	// Overriding one organisation's policy must not leak into the defaults
+	RolePolicyFor(RolePolicy{Admin: {}}, nil)
+	if !RolePolicyFor(nil, nil).Allows(Admin, PermUserCreate) {
+		t.Errorf("an override changed the default policy")
+	}
+}
+
+func TestRolePolicyHoldsAll(t *testing.T) {
+	policy := RolePolicyFor(nil, nil)
+	tests := []struct {
+		name        string
+		role        Role
+		permissions []Permission
+		want        bool
+	}{
+		{"nothing asked", FieldExecutive, nil, true},
+		{"all held", FieldExecutive, []Permission{PermProspectRead, PermProspectSubmit}, true},
+		{"one missing", FieldExecutive, []Permission{PermProspectRead, PermProspectApprove}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.HoldsAll(tt.role, tt.permissions); got != tt.want {
+				t.Errorf("HoldsAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolePolicyCanManageRole(t *testing.T) {
+	policy := RolePolicyFor(nil, nil)
+	tests := []struct {
+		name   string
+		actor  Role
+		target Role
+		want   bool
+	}{
+		{"owner manages owners", Owner, Owner, true},
+		{"admin manages field executives", Admin, FieldExecutive, true},
+		{"admin cannot manage owners", Admin, Owner, false},
+		{"operations lead cannot manage admins", OperationsLead, Admin, false},
+		{"field lead manages nobody", FieldLead, FieldExecutive, false},
+		{"field executive manages nobody", FieldExecutive, FieldExecutive, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.CanManageRole(tt.actor, tt.target); got != tt.want {
+				t.Errorf("CanManageRole(%s, %s) = %v, want %v", tt.actor, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomRoleManagement(t *testing.T) {
+	auditor := CustomRole{Name: "Quality Auditor", Permissions: []Permission{PermProspectRead, PermAuditRead}}
+	keyKeeper := CustomRole{Name: "Key Keeper", Permissions: []Permission{PermAPIKeyManage}}
+	customRoles := []CustomRole{auditor, keyKeeper}
+	// This organisation took API key management away from its admins
+	policy := RolePolicyFor(RolePolicy{Admin: {PermUserCreate, PermUserRead, PermProspectRead, PermAuditRead}}, customRoles)
+
+	t.Run("custom role permissions", func(t *testing.T) {
+		if !policy.Allows(auditor.Name, PermAuditRead) || policy.Allows(auditor.Name, PermProspectUpdate) {
+			t.Errorf("custom role permissions = %v, want %v", policy[auditor.Name], auditor.Permissions)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		actor  Role
+		target Role
+		want   bool
+	}{
+		{"owner holding every permission", Owner, keyKeeper.Name, true},
+		{"admin holding every permission", Admin, auditor.Name, true},
+		{"admin missing a permission", Admin, keyKeeper.Name, false},
+		{"operations lead is no custom role manager", OperationsLead, auditor.Name, false},
+		{"custom role cannot manage itself", auditor.Name, auditor.Name, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.CanManageRole(tt.actor, tt.target); got != tt.want {
+				t.Errorf("CanManageRole(%s, %s) = %v, want %v", tt.actor, tt.target, got, tt.want)
+			}
+		})
+	}
+
+	managed := []struct {
+		actor Role
+		want  []Role
+	}{
+		{Owner, []Role{Owner, Admin, OperationsLead, OperationsExecutive, FieldLead, FieldExecutive, auditor.Name, keyKeeper.Name}},
+		{Admin, []Role{Admin, OperationsLead, OperationsExecutive, FieldLead, FieldExecutive, auditor.Name}},
+		{OperationsLead, []Role{OperationsLead, OperationsExecutive, FieldLead, FieldExecutive}},
+		{FieldExecutive, []Role{}},
+	}
+	for _, tt := range managed {
+		t.Run("managed by "+string(tt.actor), func(t *testing.T) {
+			if got := policy.ManagedRoles(tt.actor, customRoles); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ManagedRoles(%s) = %v, want %v", tt.actor, got, tt.want)
+			}
+		})
+	}
+}
@@ -65,43 +65,55 @@ const (
 //	  "remarks": "Prospect is under review"
 //	}
 type Prospect struct {
-	UId                   string          `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174111"`                     // unique identifier for the prospect
-	ProspectId            string          `bson:"prospect_id" json:"prospect_id" example:"P12345"`                                   // Unique prospect ID
-	ApplicantName         string          `bson:"applicant_name" json:"applicant_name" example:"John Doe"`                           // Name of the applicant
-	NameVerified          bool            `bson:"name_verified" json:"name_verified" example:"true"`                                 // Name verification status
-	MobileNumber          string          `bson:"mobile_number" json:"mobile_number" example:"9876543210"`                           // Mobile number of the applicant
-	MobileVerified        bool            `bson:"mobile_verified" json:"mobile_verified" example:"true"`                             // Mobile verification status
-	Gender                string          `bson:"gender" json:"gender" example:"Male"`                                               // Gender of the applicant
-	Age                   int             `bson:"age" json:"age" example:"30"`                                                       // Age of the applicant
-	ResidentialAddress    string          `bson:"residential_address" json:"residential_address" example:"123 Main Street"`          // Residential address
-	ResAddressVerified    bool            `bson:"res_address_verified" json:"res_address_verified" example:"true"`                   // Residential address verification status
-	YearsOfStay           int             `bson:"years_of_stay" json:"years_of_stay" example:"5"`                                    // Years of stay at the current address
-	NumberOfFamilyMembers int             `bson:"number_of_family_members" json:"number_of_family_members" example:"4"`              // Number of family members
-	ReferenceName         string          `bson:"reference_name" json:"reference_name" example:"Jane Doe"`                           // Reference name
-	ReferenceRelation     string          `bson:"reference_relation" json:"reference_relation" example:"Sister"`                     // Relation with the reference
-	ReferenceMobile       string          `bson:"reference_mobile" json:"reference_mobile" example:"9876543211"`                     // Mobile number of the reference
-	EmploymentType        EmploymentType  `bson:"employment_type" json:"employment_type" example:"Employee"`                         // Employment type ("Employee" or "Business")
-	OfficeAddress         string          `bson:"office_address" json:"office_address" example:"456 Office Street"`                  // Office address
-	OffAddressVerified    bool            `bson:"off_address_verified" json:"off_address_verified" example:"true"`                   // Office address verification status
-	YearsInCurrentOffice  int             `bson:"years_in_current_office" json:"years_in_current_office" example:"3"`                // Years in the current office
-	Role                  string          `bson:"role" json:"role" example:"Manager"`                                                // Role in the organization
-	RoleVerified          bool            `bson:"role_verified" json:"role_verified" example:"true"`                                 // Role verification status
-	EmpId                 string          `bson:"emp_id" json:"emp_id" example:"EMP123"`                                             // Employee ID
-	EmpIdVerified         bool            `bson:"emp_id_verified" json:"emp_id_verified" example:"true"`                             // Employee ID verification status
-	Status                ProspectStatus  `bson:"status" json:"status" example:"Pending"`                                            // Current status of the prospect
-	PreviousExperience    int             `bson:"previous_experience" json:"previous_experience" example:"5"`                        // Previous experience
-	GrossSalary           float64         `bson:"gross_salary" json:"gross_salary" example:"50000.00"`                               // Gross salary
-	NetSalary             float64         `bson:"net_salary" json:"net_salary" example:"40000.00"`                                   // Net salary
-	ColleagueName         string          `bson:"colleague_name" json:"colleague_name" example:"Mark Smith"`                         // Name of a colleague
-	ColleagueDesignation  string          `bson:"colleague_designation" json:"colleague_designation" example:"Team Lead"`            // Designation of the colleague
-	ColleagueMobile       string          `bson:"colleague_mobile" json:"colleague_mobile" example:"9876543212"`                     // Mobile number of the colleague
-	UploadedImages        []string        `bson:"uploaded_images" json:"uploaded_images" example:"[\"image1.jpg\", \"image2.jpg\"]"` // Uploaded images
-	Remarks               string          `bson:"remarks" json:"remarks" example:"Prospect is under review"`                         // Additional remarks
-	CreatedBy             string          `bson:"created_by" json:"created_by" example:"admin"`                                      // User who created the prospect
-	CreatedTime           string          `bson:"created_time" json:"created_time" example:"2023-04-12T15:04:05Z"`                   // Time when the prospect was created
-	UpdatedTime           string          `bson:"updated_time" json:"updated_time" example:"2023-04-12T15:04:05Z"`                   // Time when the prospect was last updated
-	UpdatedBy             string          `bson:"updated_by" json:"updated_by" example:"admin"`                                      // User who last updated the prospect
-	UpdateHistory         []UpdateHistory `bson:"update_history" json:"update_history"`                                              // Comments about the last update
+	UId                   string          `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174111"`                         // unique identifier for the prospect
+	ProspectId            string          `bson:"prospect_id" json:"prospect_id" example:"P12345"`                                       // Unique prospect ID
+	ApplicantName         string          `bson:"applicant_name" json:"applicant_name" example:"John Doe"`                               // Name of the applicant
+	NameVerified          bool            `bson:"name_verified" json:"name_verified" example:"true"`                                     // Name verification status
+	MobileNumber          string          `bson:"mobile_number" json:"mobile_number" example:"9876543210"`                               // Mobile number of the applicant
+	MobileVerified        bool            `bson:"mobile_verified" json:"mobile_verified" example:"true"`                                 // Mobile verification status
+	Gender                string          `bson:"gender" json:"gender" example:"Male"`                                                   // Gender of the applicant
+	Age                   int             `bson:"age" json:"age" example:"30"`                                                           // Age of the applicant
+	ResidentialAddress    string          `bson:"residential_address" json:"residential_address" example:"123 Main Street"`              // Residential address
+	ResAddressVerified    bool            `bson:"res_address_verified" json:"res_address_verified" example:"true"`                       // Residential address verification status
+	YearsOfStay           int             `bson:"years_of_stay" json:"years_of_stay" example:"5"`                                        // Years of stay at the current address
+	NumberOfFamilyMembers int             `bson:"number_of_family_members" json:"number_of_family_members" example:"4"`                  // Number of family members
+	ReferenceName         string          `bson:"reference_name" json:"reference_name" example:"Jane Doe"`                               // Reference name
+	ReferenceRelation     string          `bson:"reference_relation" json:"reference_relation" example:"Sister"`                         // Relation with the reference
+	ReferenceMobile       string          `bson:"reference_mobile" json:"reference_mobile" example:"9876543211"`                         // Mobile number of the reference
+	EmploymentType        EmploymentType  `bson:"employment_type" json:"employment_type" example:"Employee"`                             // Employment type ("Employee" or "Business")
+	OfficeAddress         string          `bson:"office_address" json:"office_address" example:"456 Office Street"`                      // Office address
+	ResidentialLocation   *GeoPoint       `bson:"residential_location,omitempty" json:"residential_location,omitempty"`                  // Coordinates of the residential address
+	OfficeLocation        *GeoPoint       `bson:"office_location,omitempty" json:"office_location,omitempty"`                            // Coordinates of the office address
+	OffAddressVerified    bool            `bson:"off_address_verified" json:"off_address_verified" example:"true"`                       // Office address verification status
+	YearsInCurrentOffice  int             `bson:"years_in_current_office" json:"years_in_current_office" example:"3"`                    // Years in the current office
+	Role                  string          `bson:"role" json:"role" example:"Manager"`                                                    // Role in the organization
+	RoleVerified          bool            `bson:"role_verified" json:"role_verified" example:"true"`                                     // Role verification status
+	EmpId                 string          `bson:"emp_id" json:"emp_id" example:"EMP123"`                                                 // Employee ID
+	EmpIdVerified         bool            `bson:"emp_id_verified" json:"emp_id_verified" example:"true"`                                 // Employee ID verification status
+	Status                ProspectStatus  `bson:"status" json:"status" example:"Pending"`                                                // Current status of the prospect
+	PreviousExperience    int             `bson:"previous_experience" json:"previous_experience" example:"5"`                            // Previous experience
+	GrossSalary           float64         `bson:"gross_salary" json:"gross_salary" example:"50000.00"`                                   // Gross salary
+	NetSalary             float64         `bson:"net_salary" json:"net_salary" example:"40000.00"`                                       // Net salary
+	ColleagueName         string          `bson:"colleague_name" json:"colleague_name" example:"Mark Smith"`                             // Name of a colleague
+	ColleagueDesignation  string          `bson:"colleague_designation" json:"colleague_designation" example:"Team Lead"`                // Designation of the colleague
+	ColleagueMobile       string          `bson:"colleague_mobile" json:"colleague_mobile" example:"9876543212"`                         // Mobile number of the colleague
+	UploadedImages        []string        `bson:"uploaded_images" json:"uploaded_images" example:"[\"image1.jpg\", \"image2.jpg\"]"`     // Uploaded images
+	Media                 []ProspectMedia `bson:"media,omitempty" json:"media"`                                                          // Files uploaded through the media endpoints
+	Visits                []ProspectVisit `bson:"visits,omitempty" json:"visits"`                                                        // Field visits checked in through the visit endpoints
+	Remarks               string          `bson:"remarks" json:"remarks" example:"Prospect is under review"`                             // Additional remarks
+	CreatedBy             string          `bson:"created_by" json:"created_by" example:"admin"`                                          // User who created the prospect
+	CreatedTime           string          `bson:"created_time" json:"created_time" example:"2023-04-12T15:04:05Z"`                       // Time when the prospect was created
+	UpdatedTime           string          `bson:"updated_time" json:"updated_time" example:"2023-04-12T15:04:05Z"`                       // Time when the prospect was last updated
+	UpdatedBy             string          `bson:"updated_by" json:"updated_by" example:"admin"`                                          // User who last updated the prospect
+	UpdateHistory         []UpdateHistory `bson:"update_history" json:"update_history"`                                                  // Comments about the last update
+	HistoryHead           string          `bson:"history_head,omitempty" json:"history_head,omitempty"`                                  // Hash of the last sealed update history entry
+	OrgUUID               string          `bson:"org_uuid" json:"org_uuid" example:"123e4567-e89b-12d3-a456-426614174000"`               // UUID of the organisation that owns the prospect
+	AssignedTo            string          `bson:"assigned_to" json:"assigned_to" example:"123e4567-e89b-12d3-a456-426614174222"`         // UId of the field executive the prospect is assigned to
+	AssignedToName        string          `bson:"assigned_to_name" json:"assigned_to_name" example:"field_exec"`                         // Username of the assigned field executive
+	AssignedBy            string          `bson:"assigned_by" json:"assigned_by" example:"field_lead"`                                   // User who made the current assignment
+	AssignedTime          string          `bson:"assigned_time" json:"assigned_time" example:"2023-04-12T15:04:05Z"`                     // Time of the current assignment
+	ReviewedBy            string          `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty" example:"ops_lead"`                 // User who approved or rejected the prospect
+	ReviewedTime          string          `bson:"reviewed_time,omitempty" json:"reviewed_time,omitempty" example:"2023-04-12T15:04:05Z"` // Time of the approval or rejection
 }
 
 // Prospect represents a prospect in the system.
@@ -157,6 +169,8 @@ type ProspecReq struct {
 	ReferenceMobile       string         `bson:"reference_mobile" json:"reference_mobile" example:"9876543211"`                     // Mobile number of the reference
 	EmploymentType        EmploymentType `bson:"employment_type" json:"employment_type" example:"Employee"`                         // Employment type ("Employee" or "Business")
 	OfficeAddress         string         `bson:"office_address" json:"office_address" example:"456 Office Street"`                  // Office address
+	ResidentialLocation   *GeoPoint      `bson:"residential_location,omitempty" json:"residential_location,omitempty"`              // Coordinates of the residential address
+	OfficeLocation        *GeoPoint      `bson:"office_location,omitempty" json:"office_location,omitempty"`                        // Coordinates of the office address
 	OffAddressVerified    bool           `bson:"off_address_verified" json:"off_address_verified" example:"true"`                   // Office address verification status
 	YearsInCurrentOffice  int            `bson:"years_in_current_office" json:"years_in_current_office" example:"3"`                // Years in the current office
 	Role                  string         `bson:"role" json:"role" example:"Manager"`                                                // Role in the organization
@@ -173,3 +187,60 @@ type ProspecReq struct {
 	UploadedImages        []string       `bson:"uploaded_images" json:"uploaded_images" example:"[\"image1.jpg\", \"image2.jpg\"]"` // Uploaded images
 	Remarks               string         `bson:"remarks" json:"remarks" example:"Prospect is under review"`                         // Additional remarks
 }
+
+// ProspectTransitionReq represents a request to move a prospect to another workflow status.
+// @Description Prospect status transition request with the reason for the change.
+//
+//	@Example {
+//	  "status": "OnVisit",
+//	  "reason": "Reached applicant's residence"
+//	}
+type ProspectTransitionReq struct {
+	Status ProspectStatus `json:"status" binding:"required" example:"OnVisit"`                       // Target status
+	Reason string         `json:"reason" binding:"required" example:"Reached applicant's residence"` // Reason recorded in the update history
+}
+
+// ProspectAssignReq represents a request to assign a prospect to a field executive.
+// @Description Prospect assignment request referencing the field executive's UId.
+//
+//	@Example {
+//	  "assignee_uid": "123e4567-e89b-12d3-a456-426614174222"
+//	}
+type ProspectAssignReq struct {
+	AssigneeUId string `json:"assignee_uid" binding:"required" example:"123e4567-e89b-12d3-a456-426614174222"` // UId of the field executive
+}
+
+// ProspectAutoAssignReq represents a request to auto-assign a batch of prospects.
+// @Description Prospect UIds to auto-assign; when empty every unassigned Pending or RePending prospect is assigned.
+//
+//	@Example {
+//	  "uids": ["123e4567-e89b-12d3-a456-426614174111"]
+//	}
+type ProspectAutoAssignReq struct {
+	UIds []string `json:"uids" example:"123e4567-e89b-12d3-a456-426614174111"` // Prospect UIds to assign
+}
+
+// ProspectFilter selects and orders prospects for the list and count endpoints.
+// Date bounds are RFC3339 timestamps in UTC, matching how created_time and updated_time are stored.
+type ProspectFilter struct {
+	Statuses       []ProspectStatus // Match any of these statuses
+	EmploymentType EmploymentType   // Match this employment type
+	CreatedFrom    string           // created_time lower bound, inclusive
+	CreatedTo      string           // created_time upper bound, inclusive
+	UpdatedFrom    string           // updated_time lower bound, inclusive
+	UpdatedTo      string           // updated_time upper bound, inclusive
+	AssignedTo     string           // UId of the assignee
+	CreatedBy      string           // Username of the creator
+	Search         string           // Case-insensitive substring of applicant name, mobile number or prospect ID
+	SortBy         string           // One of ProspectSortFields; created_time when empty
+	SortAscending  bool             // Sort ascending instead of the default descending order
+}
+
+// ProspectSortFields are the fields prospects can be sorted by
+var ProspectSortFields = map[string]bool{
+	"created_time":   true,
+	"updated_time":   true,
+	"applicant_name": true,
+	"prospect_id":    true,
+	"status":         true,
+}
@@ -0,0 +1,35 @@
+package models
+
+// VerificationReport records a PDF report issued for a prospect so recipients can check its authenticity.
+// @Description Issued verification report with the hashes printed on and computed from the PDF.
+//
+//	@Example {
+//	  "report_id": "123e4567-e89b-12d3-a456-426614174444",
+//	  "prospect_uid": "123e4567-e89b-12d3-a456-426614174111",
+//	  "prospect_id": "P12345",
+//	  "prospect_status": "Approved",
+//	  "content_hash": "5d41402abc4b2a76b9719d911017c592...",
+//	  "signature": "2c26b46b68ffc68ff99b453c1d304134...",
+//	  "issued_by": "ops_lead",
+//	  "issued_time": "2023-04-12T15:04:05Z"
+//	}
+type VerificationReport struct {
+	ReportId       string         `bson:"report_id" json:"report_id" example:"123e4567-e89b-12d3-a456-426614174444"`       // Unique identifier of the report
+	ProspectUId    string         `bson:"prospect_uid" json:"prospect_uid" example:"123e4567-e89b-12d3-a456-426614174111"` // UId of the reported prospect
+	ProspectId     string         `bson:"prospect_id" json:"prospect_id" example:"P12345"`                                 // Prospect ID printed on the report
+	ProspectStatus ProspectStatus `bson:"prospect_status" json:"prospect_status" example:"Approved"`                       // Prospect status at issue time
+	OrgUUID        string         `bson:"org_uuid" json:"-"`                                                               // Organisation that issued the report
+	ContentHash    string         `bson:"content_hash" json:"content_hash" example:"5d41402abc4b2a76b9719d911017c592"`     // SHA-256 of the report content, printed on the report
+	Signature      string         `bson:"signature" json:"signature" example:"2c26b46b68ffc68ff99b453c1d304134"`           // HMAC-SHA256 of the content hash with the issuer key
+	PdfHash        string         `bson:"pdf_hash" json:"-"`                                                               // SHA-256 of the generated PDF file
+	IssuedBy       string         `bson:"issued_by" json:"issued_by" example:"ops_lead"`                                   // User who downloaded the report
+	IssuedTime     string         `bson:"issued_time" json:"issued_time" example:"2023-04-12T15:04:05Z"`                   // Time the report was generated
+}
+
+// ReportVerificationResp is returned by the public report verification endpoints.
+// @Description Result of checking a report against the issuer's records.
+type ReportVerificationResp struct {
+	Valid  bool                `json:"valid" example:"true"`                                          // Whether the report was issued by us and is unaltered
+	Reason string              `json:"reason,omitempty" example:"Content hash does not match report"` // Why verification failed
+	Report *VerificationReport `json:"report,omitempty"`                                              // Issued report details when found
+}
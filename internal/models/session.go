@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Session is a login session holding the current refresh token of a user. Each refresh rotates the
+// token; presenting a token that has already been rotated revokes the session.
+type Session struct {
+	SessionId        string    `bson:"session_id" json:"session_id"`                                   // Unique identifier of the session, carried as sid in access tokens
+	UserUId          string    `bson:"user_uid" json:"user_uid"`                                       // UId of the user
+	OrgUUID          string    `bson:"org_uuid" json:"org_uuid"`                                       // UUID of the user's organisation
+	CurrentTokenHash string    `bson:"current_token_hash" json:"-"`                                    // SHA-256 of the refresh token that may be used next
+	UsedTokenHashes  []string  `bson:"used_token_hashes" json:"-"`                                     // SHA-256 of refresh tokens already rotated
+	UserAgent        string    `bson:"user_agent" json:"user_agent"`                                   // User agent of the login request
+	IPAddress        string    `bson:"ip_address" json:"ip_address"`                                   // Client IP of the login request
+	CreatedTime      string    `bson:"created_time" json:"created_time"`                               // Time of the login
+	LastRefreshTime  string    `bson:"last_refresh_time,omitempty" json:"last_refresh_time,omitempty"` // Time of the last token refresh
+	ExpiresAt        time.Time `bson:"expires_at" json:"expires_at"`                                   // Time after which the refresh token is no longer accepted
+	RevokedTime      string    `bson:"revoked_time,omitempty" json:"revoked_time,omitempty"`           // Time the session was revoked
+	RevokedReason    string    `bson:"revoked_reason,omitempty" json:"revoked_reason,omitempty"`       // Why the session was revoked
+}
+
+// RefreshTokenRequest carries a refresh token for the refresh and logout endpoints.
+// @Description Refresh token issued at login or by the previous refresh.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"mF_9.B5f-4.1JqM"` // Refresh token
+}
+
+// TokenResponse is returned by the token refresh endpoint.
+// @Description New access token and the rotated refresh token.
+type TokenResponse struct {
+	Token        string `json:"token" example:"<jwt_token>"`             // Short-lived access token
+	RefreshToken string `json:"refresh_token" example:"mF_9.B5f-4.1JqM"` // Refresh token to use next; the presented one is no longer valid
+	ExpiresIn    int    `json:"expires_in" example:"900"`                // Access token lifetime in seconds
+}
@@ -0,0 +1,60 @@
+package models
+
+// TwoFactor holds a user's TOTP enrolment. A secret is pending until the user proves their
+// authenticator app with a code, which enables two-factor login.
+type TwoFactor struct {
+	Enabled            bool     `bson:"enabled"`                        // Two-factor login is on
+	Secret             string   `bson:"secret,omitempty"`               // Base32 TOTP secret of the confirmed enrolment
+	PendingSecret      string   `bson:"pending_secret,omitempty"`       // Base32 TOTP secret awaiting confirmation
+	EnrolledTime       string   `bson:"enrolled_time,omitempty"`        // Time the enrolment was confirmed
+	LastUsedStep       int64    `bson:"last_used_step"`                 // Time step of the last accepted code, so codes cannot be replayed
+	RecoveryCodeHashes []string `bson:"recovery_code_hashes,omitempty"` // Keyed hashes of the unused recovery codes
+}
+
+// TwoFactorStatus is the part of a user's two-factor enrolment shown to administrators.
+type TwoFactorStatus struct {
+	Enabled      bool   `bson:"enabled" json:"enabled" example:"true"`                                                 // Two-factor login is on
+	EnrolledTime string `bson:"enrolled_time,omitempty" json:"enrolled_time,omitempty" example:"2023-04-12T15:04:05Z"` // Time the enrolment was confirmed
+}
+
+// TwoFactorChallenge is returned by login when a second factor is needed.
+// @Description Challenge to complete at POST /api/v1/users/login/2fa, after enrolling first if enrolment_required is set.
+type TwoFactorChallenge struct {
+	TwoFactorRequired  bool   `json:"two_factor_required" example:"true"`        // A second factor is needed to log in
+	EnrolmentRequired  bool   `json:"enrolment_required" example:"false"`        // The user must enrol an authenticator app first
+	ChallengeToken     string `json:"challenge_token" example:"<challenge_jwt>"` // Token for the second login step
+	ChallengeExpiresIn int    `json:"expires_in" example:"300"`                  // Challenge lifetime in seconds
+}
+
+// TwoFactorEnrolment is the secret of a new TOTP enrolment.
+// @Description Secret to add to an authenticator app, as text, otpauth URL or QR code.
+type TwoFactorEnrolment struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`                                                            // Base32 TOTP secret
+	OTPAuthURL string `json:"otpauth_url" example:"otpauth://totp/FVerify:john_doe?secret=JBSWY3DPEHPK3PXP&issuer=FVerify"` // Provisioning URL
+	QRCodePNG  []byte `json:"qr_code_png" swaggertype:"string" format:"base64"`                                             // QR code of the provisioning URL
+}
+
+// TwoFactorChallengeRequest starts enrolment during a challenged login.
+// @Description Challenge token from login.
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required" example:"<challenge_jwt>"` // Challenge token from login
+}
+
+// TwoFactorLoginRequest completes a two-factor login.
+// @Description Challenge token from login and a TOTP or recovery code.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required" example:"<challenge_jwt>"` // Challenge token from login
+	Code           string `json:"code" binding:"required" example:"123456"`                     // TOTP code, or a recovery code
+}
+
+// TwoFactorCodeRequest carries a TOTP code.
+// @Description TOTP code from the authenticator app.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"` // TOTP code
+}
+
+// RecoveryCodesResponse lists newly issued recovery codes; they are shown only once.
+// @Description One-time recovery codes, each usable once in place of a TOTP code.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"7KQM-X2PD-9WRT"` // Recovery codes
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Role represents the role of a user.
 // Enum: "Admin", "Operations Lead", "Field Lead", "Field Executive", "Owner", "Operations Executive"
 type Role string
@@ -30,9 +32,12 @@ const (
 // UpdateHistory represents the history of updates made to a user.
 // @Description History of updates made to a user.
 type UpdateHistory struct {
-	UpdatedComments string `bson:"updated_comments" json:"updated_comments" example:"Updated user role"` // Comments about the update
-	UpdatedTime     string `bson:"updated_time" json:"updated_time" example:"2023-04-12T15:04:05Z"`      // Time of the update
-	UpdateBy        string `bson:"update_by" json:"update_by" example:"admin"`                           // User who made the update
+	UpdatedComments string        `bson:"updated_comments" json:"updated_comments" example:"Updated user role"` // Comments about the update
+	UpdatedTime     string        `bson:"updated_time" json:"updated_time" example:"2023-04-12T15:04:05Z"`      // Time of the update
+	UpdateBy        string        `bson:"update_by" json:"update_by" example:"admin"`                           // User who made the update
+	PrevHash        string        `bson:"prev_hash,omitempty" json:"prev_hash,omitempty"`                       // Hash of the preceding entry of a prospect's history chain
+	Hash            string        `bson:"hash,omitempty" json:"hash,omitempty"`                                 // Hash sealing this entry into a prospect's history chain
+	Changes         []FieldChange `bson:"changes,omitempty" json:"changes,omitempty"`                           // Fields the update changed, with their old and new values; the comment is derived from them
 }
 
 // User represents a user in the system.
@@ -57,18 +62,22 @@ type UpdateHistory struct {
 //		  "org_uuid": "123e4567-e89b-12d3-a456-426614174000"
 //		}
 type UserResp struct {
-	UId           string             `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174111"`           // Auto-incremented unique identifier
-	UserId        string             `bson:"userid" json:"userid" example:"112345"`                                   // Unique identifier for the user
-	Username      string             `bson:"username" json:"username" example:"john_doe"`                             // Username of the user
-	Role          Role               `bson:"role" json:"role" example:"Admin"`                                        // Role of the user
-	Status        UserStatus         `bson:"status" json:"status" example:"Active"`                                   // Status of the user
-	CreatedTime   string             `bson:"created_time" json:"created_time" example:"2023-04-12T15:04:05Z"`         // Time when the user was created
-	UpdatedTime   string             `bson:"updated_time" json:"updated_time" example:"2023-04-12T15:04:05Z"`         // Time when the user was last updated
-	UpdateHistory []UpdateHistory    `bson:"update_history" json:"update_history"`                                    // History of updates
-	Remarks       string             `bson:"remarks" json:"remarks" example:"User is active and verified"`            // Additional remarks about the user
-	MobileNumber  string             `bson:"mobile_number" json:"mobile_number" example:"9876543210"`                 // Mobile number of the user
-	OrgStatus     OrganisationStatus `bson:"org_status" json:"org_status" example:"123456"`                           // Organization ID
-	OrgUUID       string             `bson:"org_uuid" json:"org_uuid" example:"123e4567-e89b-12d3-a456-426614174000"` // UUID of the organization
+	UId                string             `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174111"`           // Auto-incremented unique identifier
+	UserId             string             `bson:"userid" json:"userid" example:"112345"`                                   // Unique identifier for the user
+	Username           string             `bson:"username" json:"username" example:"john_doe"`                             // Username of the user
+	Role               Role               `bson:"role" json:"role" example:"Admin"`                                        // Role of the user
+	Status             UserStatus         `bson:"status" json:"status" example:"Active"`                                   // Status of the user
+	CreatedTime        string             `bson:"created_time" json:"created_time" example:"2023-04-12T15:04:05Z"`         // Time when the user was created
+	UpdatedTime        string             `bson:"updated_time" json:"updated_time" example:"2023-04-12T15:04:05Z"`         // Time when the user was last updated
+	UpdateHistory      []UpdateHistory    `bson:"update_history" json:"update_history"`                                    // History of updates
+	Remarks            string             `bson:"remarks" json:"remarks" example:"User is active and verified"`            // Additional remarks about the user
+	MobileNumber       string             `bson:"mobile_number" json:"mobile_number" example:"9876543210"`                 // Mobile number of the user
+	BaseLocation       *GeoPoint          `bson:"base_location,omitempty" json:"base_location,omitempty"`                  // Home base of a field executive, used for nearest assignment
+	OrgStatus          OrganisationStatus `bson:"org_status" json:"org_status" example:"123456"`                           // Organization ID
+	OrgUUID            string             `bson:"org_uuid" json:"org_uuid" example:"123e4567-e89b-12d3-a456-426614174000"` // UUID of the organization
+	TokenVersion       int                `bson:"token_version,omitempty" json:"-"`                                        // Bumped to revoke issued access tokens
+	MustChangePassword bool               `bson:"must_change_password" json:"must_change_password" example:"false"`        // User must set a new password before using the API
+	TwoFactor          *TwoFactorStatus   `bson:"two_factor,omitempty" json:"two_factor,omitempty"`                        // Two-factor enrolment
 }
 
 // User represents a user in the system.
@@ -94,19 +103,26 @@ type UserResp struct {
 //		  "org_uuid": "123e4567-e89b-12d3-a456-426614174000"
 //		}
 type User struct {
-	UId           string             `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174111"`           // Auto-incremented unique identifier
-	UserId        string             `bson:"userid" json:"userid" example:"112345"`                                   // Unique identifier for the user
-	Username      string             `bson:"username" json:"username" example:"john_doe"`                             // Username of the user
-	Password      string             `bson:"password" json:"password" example:"plane_password"`                       // Hashed password
-	Role          Role               `bson:"role" json:"role" example:"Admin"`                                        // Role of the user
-	Status        UserStatus         `bson:"status" json:"status" example:"Active"`                                   // Status of the user
-	CreatedTime   string             `bson:"created_time" json:"created_time" example:"2023-04-12T15:04:05Z"`         // Time when the user was created
-	UpdatedTime   string             `bson:"updated_time" json:"updated_time" example:"2023-04-12T15:04:05Z"`         // Time when the user was last updated
-	UpdateHistory []UpdateHistory    `bson:"update_history" json:"update_history"`                                    // History of updates
-	Remarks       string             `bson:"remarks" json:"remarks" example:"User is active and verified"`            // Additional remarks about the user
-	MobileNumber  string             `bson:"mobile_number" json:"mobile_number" example:"9876543210"`                 // Mobile number of the user
-	OrgStatus     OrganisationStatus `bson:"org_status" json:"org_status" example:"123456"`                           // Organization ID
-	OrgUUID       string             `bson:"org_uuid" json:"org_uuid" example:"123e4567-e89b-12d3-a456-426614174000"` // UUID of the organization
+	UId                string             `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174111"`           // Auto-incremented unique identifier
+	UserId             string             `bson:"userid" json:"userid" example:"112345"`                                   // Unique identifier for the user
+	Username           string             `bson:"username" json:"username" example:"john_doe"`                             // Username of the user
+	Password           string             `bson:"password" json:"password" example:"plane_password"`                       // Hashed password
+	Role               Role               `bson:"role" json:"role" example:"Admin"`                                        // Role of the user
+	Status             UserStatus         `bson:"status" json:"status" example:"Active"`                                   // Status of the user
+	CreatedTime        string             `bson:"created_time" json:"created_time" example:"2023-04-12T15:04:05Z"`         // Time when the user was created
+	UpdatedTime        string             `bson:"updated_time" json:"updated_time" example:"2023-04-12T15:04:05Z"`         // Time when the user was last updated
+	UpdateHistory      []UpdateHistory    `bson:"update_history" json:"update_history"`                                    // History of updates
+	Remarks            string             `bson:"remarks" json:"remarks" example:"User is active and verified"`            // Additional remarks about the user
+	MobileNumber       string             `bson:"mobile_number" json:"mobile_number" example:"9876543210"`                 // Mobile number of the user
+	BaseLocation       *GeoPoint          `bson:"base_location,omitempty" json:"base_location,omitempty"`                  // Home base of a field executive, used for nearest assignment
+	OrgStatus          OrganisationStatus `bson:"org_status" json:"org_status" example:"123456"`                           // Organization ID
+	OrgUUID            string             `bson:"org_uuid" json:"org_uuid" example:"123e4567-e89b-12d3-a456-426614174000"` // UUID of the organization
+	TokenVersion       int                `bson:"token_version,omitempty" json:"-"`                                        // Bumped to revoke issued access tokens
+	MustChangePassword bool               `bson:"must_change_password" json:"must_change_password" example:"false"`        // User must set a new password before using the API
+	PasswordReset      *PasswordReset     `bson:"password_reset,omitempty" json:"-"`                                       // Outstanding one-time password reset code
+	PasswordHistory    []string           `bson:"password_history,omitempty" json:"-"`                                     // Hashes of the most recent passwords, newest last
+	TwoFactor          *TwoFactor         `bson:"two_factor,omitempty" json:"-"`                                           // TOTP enrolment
+	LoginOTP           *LoginOTP          `bson:"login_otp,omitempty" json:"-"`                                            // Outstanding SMS login code and send counters
 }
 
 // User represents a user in the system.
@@ -131,6 +147,7 @@ type UserReq struct {
 	Status       UserStatus `bson:"status" json:"status"  binding:"required" example:"Active"`                        // Status of the user
 	Remarks      string     `bson:"remarks" json:"remarks"  binding:"required" example:"User is active and verified"` // Additional remarks about the user
 	MobileNumber string     `bson:"mobile_number" json:"mobile_number"  binding:"required" example:"9876543210"`      // Mobile number of the user
+	BaseLocation *GeoPoint  `bson:"base_location,omitempty" json:"base_location,omitempty"`                           // Home base of a field executive
 	Org_Id       string     `bson:"org_id" json:"org_id"  binding:"required" example:"123456"`                        // UUID of the organization
 }
 
@@ -157,18 +174,52 @@ type LoginRequest struct {
 //	  "role": "Admin",
 //	  "status": "Active",
 //	  "mobileNumber": "9876543210",
-//	  "token": "<jwt_token>"
+//	  "token": "<jwt_token>",
+//	  "refresh_token": "mF_9.B5f-4.1JqM",
+//	  "expires_in": 900
 //	}
 type LoginResponse struct {
-	UId          string `json:"uid" example:"1"`                   // User's unique ID
-	UserId       string `json:"userId" example:"12345"`            // User's unique identifier
-	Username     string `json:"username" example:"john_doe"`       // Username
-	Role         string `json:"role" example:"Admin"`              // Role of the user
-	Status       string `json:"status" example:"Active"`           // Status of the user
-	MobileNumber string `json:"mobileNumber" example:"9876543210"` // Mobile number
-	Token        string `json:"token" example:"<jwt_token>"`       // Auth token
+	UId          string `json:"uid" example:"1"`                         // User's unique ID
+	UserId       string `json:"userId" example:"12345"`                  // User's unique identifier
+	Username     string `json:"username" example:"john_doe"`             // Username
+	Role         string `json:"role" example:"Admin"`                    // Role of the user
+	Status       string `json:"status" example:"Active"`                 // Status of the user
+	MobileNumber string `json:"mobileNumber" example:"9876543210"`       // Mobile number
+	Token        string `json:"token" example:"<jwt_token>"`             // Short-lived access token
+	RefreshToken string `json:"refresh_token" example:"mF_9.B5f-4.1JqM"` // Refresh token for POST /users/token/refresh
+	ExpiresIn    int    `json:"expires_in" example:"900"`                // Access token lifetime in seconds
+	// The token can only be used to change the password until this is cleared
+	MustChangePassword bool `json:"must_change_password" example:"false"`
+	// Issued only when the login completed a two-factor enrolment; shown once
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// PasswordReset is a one-time code an administrator issued for a user to set a new password.
+type PasswordReset struct {
+	CodeHash  string    `bson:"code_hash"`  // Keyed hash of the code
+	ExpiresAt time.Time `bson:"expires_at"` // Time after which the code is no longer accepted
+	IssuedBy  string    `bson:"issued_by"`  // User who issued the code
+}
+
+// ChangePasswordRequest represents the request payload for changing one's own password.
+// @Description Current and new password of the authenticated user.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"old_password"` // Current password
+	NewPassword     string `json:"new_password" binding:"required" example:"new_password"`     // New password
+}
+
+// PasswordResetCodeResponse is returned when an administrator resets a user's password.
+// @Description One-time code to hand to the user, who sets a new password with it.
+type PasswordResetCodeResponse struct {
+	Code      string `json:"code" example:"K7PX-M2QD"`                  // One-time reset code
+	ExpiresAt string `json:"expires_at" example:"2023-04-12T16:04:05Z"` // Time after which the code is no longer accepted
 }
 
-type SetPasswordRequest struct {
-	Password string `json:"password" binding:"required" example:"new_password"` // New password
+// ResetPasswordRequest represents the request payload for setting a new password with a reset code.
+// @Description Reset code issued by an administrator and the new password.
+type ResetPasswordRequest struct {
+	OrgId       string `json:"org_id" binding:"required" example:"123456"`             // Organization ID
+	Username    string `json:"username" binding:"required" example:"john_doe"`         // Username
+	Code        string `json:"code" binding:"required" example:"K7PX-M2QD"`            // One-time reset code
+	NewPassword string `json:"new_password" binding:"required" example:"new_password"` // New password
 }
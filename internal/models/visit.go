@@ -0,0 +1,65 @@
+package models
+
+// VisitTarget is the address a field visit is made to.
+// Enum: "Residence", "Office"
+type VisitTarget string
+
+const (
+	VisitResidence VisitTarget = "Residence"
+	VisitOffice    VisitTarget = "Office"
+)
+
+// ProspectVisit records a field executive's visit to a prospect's address.
+// @Description Check-in and check-out of a field visit with the reported positions and device times.
+//
+//	@Example {
+//	  "uid": "123e4567-e89b-12d3-a456-426614174555",
+//	  "target": "Residence",
+//	  "visited_by": "123e4567-e89b-12d3-a456-426614174222",
+//	  "visited_by_name": "field_exec",
+//	  "check_in_time": "2023-04-12T10:00:05Z",
+//	  "check_in_device_time": "2023-04-12T15:30:00+05:30",
+//	  "check_in_location": { "latitude": 12.9716, "longitude": 77.5946 },
+//	  "check_in_distance_km": 0.04,
+//	  "check_out_time": "2023-04-12T10:25:40Z",
+//	  "duration_seconds": 1535
+//	}
+type ProspectVisit struct {
+	UId                string      `bson:"uid" json:"uid" example:"123e4567-e89b-12d3-a456-426614174555"`                                              // Unique identifier of the visit
+	Target             VisitTarget `bson:"target" json:"target" example:"Residence"`                                                                   // Address visited
+	VisitedBy          string      `bson:"visited_by" json:"visited_by" example:"123e4567-e89b-12d3-a456-426614174222"`                                // UId of the field executive
+	VisitedByName      string      `bson:"visited_by_name" json:"visited_by_name" example:"field_exec"`                                                // Username of the field executive
+	CheckInTime        string      `bson:"check_in_time" json:"check_in_time" example:"2023-04-12T10:00:05Z"`                                          // Server time of the check-in
+	CheckInDeviceTime  string      `bson:"check_in_device_time,omitempty" json:"check_in_device_time,omitempty" example:"2023-04-12T15:30:00+05:30"`   // Device time reported at check-in
+	CheckInLocation    GeoPoint    `bson:"check_in_location" json:"check_in_location"`                                                                 // Position reported at check-in
+	CheckInDistanceKm  *float64    `bson:"check_in_distance_km,omitempty" json:"check_in_distance_km,omitempty" example:"0.04"`                        // Distance from the target address at check-in
+	CheckInNotes       string      `bson:"check_in_notes,omitempty" json:"check_in_notes,omitempty" example:"Applicant available"`                     // Notes entered at check-in
+	CheckOutTime       string      `bson:"check_out_time,omitempty" json:"check_out_time,omitempty" example:"2023-04-12T10:25:40Z"`                    // Server time of the check-out; empty while the visit is in progress
+	CheckOutDeviceTime string      `bson:"check_out_device_time,omitempty" json:"check_out_device_time,omitempty" example:"2023-04-12T15:55:30+05:30"` // Device time reported at check-out
+	CheckOutLocation   *GeoPoint   `bson:"check_out_location,omitempty" json:"check_out_location,omitempty"`                                           // Position reported at check-out
+	CheckOutDistanceKm *float64    `bson:"check_out_distance_km,omitempty" json:"check_out_distance_km,omitempty" example:"0.05"`                      // Distance from the target address at check-out
+	CheckOutNotes      string      `bson:"check_out_notes,omitempty" json:"check_out_notes,omitempty" example:"Verified residence and family"`         // Notes entered at check-out
+	DurationSeconds    int64       `bson:"duration_seconds,omitempty" json:"duration_seconds,omitempty" example:"1535"`                                // Server-measured duration of the visit
+}
+
+// Completed reports whether the visit has been checked out
+func (v ProspectVisit) Completed() bool {
+	return v.CheckOutTime != ""
+}
+
+// VisitCheckInReq is the body of a visit check-in.
+// @Description Target address, current position and device time at check-in.
+type VisitCheckInReq struct {
+	Target     VisitTarget `json:"target" binding:"required" example:"Residence"`   // Address being visited ("Residence" or "Office")
+	Location   *GeoPoint   `json:"location" binding:"required"`                     // Current position of the device
+	DeviceTime string      `json:"device_time" example:"2023-04-12T15:30:00+05:30"` // Device clock in RFC3339
+	Notes      string      `json:"notes" example:"Applicant available"`             // Optional notes
+}
+
+// VisitCheckOutReq is the body of a visit check-out.
+// @Description Current position and device time at check-out.
+type VisitCheckOutReq struct {
+	Location   *GeoPoint `json:"location" binding:"required"`                     // Current position of the device
+	DeviceTime string    `json:"device_time" example:"2023-04-12T15:55:30+05:30"` // Device clock in RFC3339
+	Notes      string    `json:"notes" example:"Verified residence and family"`   // Optional notes
+}
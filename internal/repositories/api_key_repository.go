@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"fverify_be/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type APIKeyRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepository(client *mongo.Client, dbName, collectionName string) *APIKeyRepositoryImpl {
+	collection := client.Database(dbName).Collection(collectionName)
+	return &APIKeyRepositoryImpl{collection: collection}
+}
+
+// EnsureIndexes creates the unique key id and key hash indexes and the per-organisation listing index
+func (r *APIKeyRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("key_id_unique")},
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true).SetName("key_hash_unique")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "created_time", Value: 1}}, Options: options.Index().SetName("org_uuid_created_time")},
+	})
+	return err
+}
+
+func (r *APIKeyRepositoryImpl) Create(ctx context.Context, key *models.APIKey) error {
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+// GetActiveByHash returns the unrevoked, unexpired key with the given hash
+func (r *APIKeyRepositoryImpl) GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.collection.FindOne(ctx, bson.M{
+		"key_hash":     keyHash,
+		"revoked_time": bson.M{"$in": bson.A{nil, ""}},
+		"$or":          bson.A{bson.M{"expires_at": nil}, bson.M{"expires_at": bson.M{"$gt": time.Now().UTC()}}},
+	}).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAllByOrg returns every key of an organisation, oldest first, including revoked and expired keys
+func (r *APIKeyRepositoryImpl) GetAllByOrg(ctx context.Context, orgUUID string) ([]models.APIKey, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"org_uuid": orgUUID}, options.Find().SetSort(bson.D{{Key: "created_time", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Touch records that a key was accepted
+func (r *APIKeyRepositoryImpl) Touch(ctx context.Context, keyId string, usedTime string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"key_id": keyId}, bson.M{"$set": bson.M{"last_used_time": usedTime}})
+	return err
+}
+
+// Revoke revokes a key of an organisation and returns the revoked key. It returns mongo.ErrNoDocuments
+// when the organisation has no such key that is still unrevoked.
+func (r *APIKeyRepositoryImpl) Revoke(ctx context.Context, orgUUID string, keyId string, revokedTime string, revokedBy string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"key_id": keyId, "org_uuid": orgUUID, "revoked_time": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"revoked_time": revokedTime, "revoked_by": revokedBy}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
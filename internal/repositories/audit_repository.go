@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"fverify_be/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// auditKeyset lists audit events newest first
+var auditKeyset = keyset{field: "time", tie: "event_id", ascending: false}
+
+// AuditRepositoryImpl stores audit events. It deliberately has no way to change or remove them.
+type AuditRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository(client *mongo.Client, dbName, collectionName string) *AuditRepositoryImpl {
+	collection := client.Database(dbName).Collection(collectionName)
+	return &AuditRepositoryImpl{collection: collection}
+}
+
+// EnsureIndexes creates the unique event id index and the indexes behind the actor, entity and time filters
+func (r *AuditRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "event_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("event_id_unique")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "time", Value: -1}}, Options: options.Index().SetName("org_uuid_time")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "actor", Value: 1}, {Key: "time", Value: -1}}, Options: options.Index().SetName("org_uuid_actor_time")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "entity_type", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "time", Value: -1}}, Options: options.Index().SetName("org_uuid_entity_time")},
+	})
+	return err
+}
+
+func (r *AuditRepositoryImpl) Insert(ctx context.Context, event *models.AuditEvent) error {
+	_, err := r.collection.InsertOne(ctx, event)
+	return err
+}
+
+// Find returns a page of an organisation's audit events matching the filter, newest first
+func (r *AuditRepositoryImpl) Find(ctx context.Context, orgUUID string, filter models.AuditFilter, page models.PageRequest) ([]models.AuditEvent, string, error) {
+	return findPage(ctx, r.collection, auditQuery(orgUUID, filter), auditKeyset, page, func(event *models.AuditEvent) (string, string) {
+		return event.Time, event.EventId
+	})
+}
+
+func (r *AuditRepositoryImpl) Count(ctx context.Context, orgUUID string, filter models.AuditFilter) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, auditQuery(orgUUID, filter))
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func auditQuery(orgUUID string, filter models.AuditFilter) bson.M {
+	query := bson.M{"org_uuid": orgUUID}
+	for field, value := range map[string]string{
+		"actor":       filter.Actor,
+		"actor_id":    filter.ActorId,
+		"action":      filter.Action,
+		"entity_type": filter.EntityType,
+		"entity_id":   filter.EntityId,
+	} {
+		if value != "" {
+			query[field] = value
+		}
+	}
+	// Times are stored as UTC RFC 3339 strings, which sort chronologically
+	if filter.From != "" || filter.To != "" {
+		timeRange := bson.M{}
+		if filter.From != "" {
+			timeRange["$gte"] = filter.From
+		}
+		if filter.To != "" {
+			timeRange["$lt"] = filter.To
+		}
+		query["time"] = timeRange
+	}
+	return query
+}
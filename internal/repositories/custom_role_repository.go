@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fverify_be/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ErrRoleExists is returned when a custom role name is already taken within the organisation
+var ErrRoleExists = errors.New("a role with this name already exists")
+
+type CustomRoleRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewCustomRoleRepository(client *mongo.Client, dbName, collectionName string) *CustomRoleRepositoryImpl {
+	collection := client.Database(dbName).Collection(collectionName)
+	return &CustomRoleRepositoryImpl{collection: collection}
+}
+
+// EnsureIndexes creates the unique role id index and the unique role name index per organisation
+func (r *CustomRoleRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "role_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("role_id_unique")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetUnique(true).SetName("org_uuid_name_unique")},
+	})
+	return err
+}
+
+func (r *CustomRoleRepositoryImpl) Create(ctx context.Context, role *models.CustomRole) error {
+	_, err := r.collection.InsertOne(ctx, role)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrRoleExists
+	}
+	return err
+}
+
+// GetAllByOrg returns the custom roles of an organisation in name order
+func (r *CustomRoleRepositoryImpl) GetAllByOrg(ctx context.Context, orgUUID string) ([]models.CustomRole, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"org_uuid": orgUUID}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	roles := []models.CustomRole{}
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *CustomRoleRepositoryImpl) GetByID(ctx context.Context, orgUUID string, roleId string) (*models.CustomRole, error) {
+	var role models.CustomRole
+	err := r.collection.FindOne(ctx, bson.M{"org_uuid": orgUUID, "role_id": roleId}).Decode(&role)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// UpdatePermissions replaces the permissions of a custom role. It returns mongo.ErrNoDocuments when the
+// organisation has no such role.
+func (r *CustomRoleRepositoryImpl) UpdatePermissions(ctx context.Context, orgUUID string, roleId string, permissions []models.Permission, updatedTime string, updatedBy string) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"org_uuid": orgUUID, "role_id": roleId},
+		bson.M{"$set": bson.M{"permissions": permissions, "updated_time": updatedTime, "updated_by": updatedBy}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Delete removes a custom role. It returns mongo.ErrNoDocuments when the organisation has no such role.
+func (r *CustomRoleRepositoryImpl) Delete(ctx context.Context, orgUUID string, roleId string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"org_uuid": orgUUID, "role_id": roleId})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
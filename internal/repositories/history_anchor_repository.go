@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"fverify_be/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// HistoryAnchorRepositoryImpl stores the periodic digests of prospect history chains. Like the audit log
+// it has no way to change or remove them.
+type HistoryAnchorRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewHistoryAnchorRepository(client *mongo.Client, dbName, collectionName string) *HistoryAnchorRepositoryImpl {
+	collection := client.Database(dbName).Collection(collectionName)
+	return &HistoryAnchorRepositoryImpl{collection: collection}
+}
+
+// EnsureIndexes creates the unique anchor id index, the indexes behind the anchor lookups, and a unique
+// index on the previous digest so concurrent anchoring can not fork an organisation's chain
+func (r *HistoryAnchorRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "anchor_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("anchor_id_unique")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "time", Value: -1}}, Options: options.Index().SetName("org_uuid_time")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "heads.uid", Value: 1}, {Key: "time", Value: 1}}, Options: options.Index().SetName("org_uuid_heads_uid_time")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "prev_digest", Value: 1}}, Options: options.Index().SetUnique(true).SetName("org_uuid_prev_digest_unique")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "digest", Value: 1}}, Options: options.Index().SetName("org_uuid_digest")},
+	})
+	return err
+}
+
+func (r *HistoryAnchorRepositoryImpl) Insert(ctx context.Context, anchor *models.HistoryAnchor) error {
+	_, err := r.collection.InsertOne(ctx, anchor)
+	return err
+}
+
+// GetLatest returns the newest anchor of an organisation, or mongo.ErrNoDocuments when there is none
+func (r *HistoryAnchorRepositoryImpl) GetLatest(ctx context.Context, orgUUID string) (*models.HistoryAnchor, error) {
+	var anchor models.HistoryAnchor
+	opts := options.FindOne().SetSort(bson.D{{Key: "time", Value: -1}}).SetProjection(bson.M{"heads": 0})
+	if err := r.collection.FindOne(ctx, bson.M{"org_uuid": orgUUID}, opts).Decode(&anchor); err != nil {
+		return nil, err
+	}
+	return &anchor, nil
+}
+
+// GetFirstForProspect returns the oldest anchor that recorded a prospect's chain head, without its heads,
+// or mongo.ErrNoDocuments when there is none
+func (r *HistoryAnchorRepositoryImpl) GetFirstForProspect(ctx context.Context, orgUUID string, uid string) (*models.HistoryAnchor, error) {
+	var anchor models.HistoryAnchor
+	opts := options.FindOne().SetSort(bson.D{{Key: "time", Value: 1}}).SetProjection(bson.M{"heads": 0})
+	if err := r.collection.FindOne(ctx, bson.M{"org_uuid": orgUUID, "heads.uid": uid}, opts).Decode(&anchor); err != nil {
+		return nil, err
+	}
+	return &anchor, nil
+}
+
+// GetByDigest returns the anchor of an organisation with the given digest, without its heads, or
+// mongo.ErrNoDocuments when there is none
+func (r *HistoryAnchorRepositoryImpl) GetByDigest(ctx context.Context, orgUUID string, digest string) (*models.HistoryAnchor, error) {
+	var anchor models.HistoryAnchor
+	opts := options.FindOne().SetProjection(bson.M{"heads": 0})
+	if err := r.collection.FindOne(ctx, bson.M{"org_uuid": orgUUID, "digest": digest}, opts).Decode(&anchor); err != nil {
+		return nil, err
+	}
+	return &anchor, nil
+}
+
+// GetSince returns the complete anchors of an organisation taken at or after since, oldest first
+func (r *HistoryAnchorRepositoryImpl) GetSince(ctx context.Context, orgUUID string, since string) ([]models.HistoryAnchor, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"org_uuid": orgUUID, "time": bson.M{"$gte": since}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var anchors []models.HistoryAnchor
+	if err := cursor.All(ctx, &anchors); err != nil {
+		return nil, err
+	}
+	return anchors, nil
+}
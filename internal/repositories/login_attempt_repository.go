@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fverify_be/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type LoginAttemptRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewLoginAttemptRepository(client *mongo.Client, dbName, collectionName string) *LoginAttemptRepositoryImpl {
+	collection := client.Database(dbName).Collection(collectionName)
+	return &LoginAttemptRepositoryImpl{collection: collection}
+}
+
+// EnsureIndexes creates the unique key index and expires counters once their window has passed
+func (r *LoginAttemptRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true).SetName("key_unique")},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl")},
+	})
+	return err
+}
+
+// Get returns the live counter for a key, or nil when there is none
+func (r *LoginAttemptRepositoryImpl) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.collection.FindOne(ctx, bson.M{"key": key, "expires_at": bson.M{"$gt": time.Now().UTC()}}).Decode(&attempt)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts a failed attempt against a key and returns the updated counter. A counter whose
+// window has lapsed but which the TTL monitor has not removed yet starts again from one.
+func (r *LoginAttemptRepositoryImpl) RecordFailure(ctx context.Context, key string, scope models.LoginAttemptScope, now time.Time, expiresAt time.Time) (*models.LoginAttempt, error) {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"key": key, "expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, err
+	}
+	var attempt models.LoginAttempt
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"key": key},
+		bson.M{
+			"$inc": bson.M{"failures": 1},
+			"$set": bson.M{"scope": scope, "last_failure": now},
+			"$max": bson.M{"expires_at": expiresAt},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Lock locks a key until the given time, keeping the counter at least that long
+func (r *LoginAttemptRepositoryImpl) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"key": key},
+		bson.M{"$set": bson.M{"locked_until": until}, "$max": bson.M{"expires_at": until}},
+	)
+	return err
+}
+
+// Reset clears the counter and any lockout of a key
+func (r *LoginAttemptRepositoryImpl) Reset(ctx context.Context, key string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"fverify_be/internal/models"
+	"time"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -21,6 +22,7 @@ func NewOrganisationRepository(client *mongo.Client, dbName, collectionName stri
 func (r *OrganisationRepositoryImpl) Create(ctx context.Context, org *models.Organisation) (*models.Organisation, error) {
 	// Generate a UUID for the organisation
 	org.OrgUUID = uuid.New().String()
+	org.CreatedTime = time.Now().UTC().Format(time.RFC3339)
 
 	_, err := r.collection.InsertOne(ctx, org)
 	if err != nil {
@@ -30,10 +32,21 @@ func (r *OrganisationRepositoryImpl) Create(ctx context.Context, org *models.Org
 }
 
 func (r *OrganisationRepositoryImpl) Update(ctx context.Context, org_id string, org *models.Organisation) error {
-	_, err := r.collection.UpdateOne(
+	raw, err := bson.Marshal(org)
+	if err != nil {
+		return err
+	}
+	var set bson.M
+	if err := bson.Unmarshal(raw, &set); err != nil {
+		return err
+	}
+	// The round-robin cursor is only moved by SetAssignmentCursor
+	delete(set, "assignment_cursor")
+
+	_, err = r.collection.UpdateOne(
 		ctx,
 		bson.M{"org_id": org_id},
-		bson.M{"$set": org},
+		bson.M{"$set": set},
 	)
 	return err
 }
@@ -42,22 +55,23 @@ func (r *OrganisationRepositoryImpl) Delete(ctx context.Context, org_id string)
 	_, err := r.collection.DeleteOne(ctx, bson.M{"org_id": org_id})
 	return err
 }
-func (r *OrganisationRepositoryImpl) GetAllOrganisations(ctx context.Context) ([]*models.Organisation, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
 
-	var organisations []*models.Organisation
-	for cursor.Next(ctx) {
-		var org models.Organisation
-		if err := cursor.Decode(&org); err != nil {
-			return nil, err
-		}
-		organisations = append(organisations, &org)
+// organisationKeyset pages organisations oldest first; organisations created before created_time was recorded come first
+var organisationKeyset = keyset{field: "created_time", tie: "org_uuid", ascending: true}
+
+// GetAllOrganisations returns one page of organisations and the cursor of the next page
+func (r *OrganisationRepositoryImpl) GetAllOrganisations(ctx context.Context, page models.PageRequest) ([]models.Organisation, string, error) {
+	return findPage(ctx, r.collection, bson.M{}, organisationKeyset, page, func(org *models.Organisation) (string, string) {
+		return org.CreatedTime, org.OrgUUID
+	})
+}
+
+func (r *OrganisationRepositoryImpl) CountOrganisations(ctx context.Context) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return 0, err
 	}
-	return organisations, nil
+	return int(count), nil
 }
 func (r *OrganisationRepositoryImpl) IsOrgActive(ctx context.Context, org_id string) (bool, *models.Organisation) {
 	var org models.Organisation
@@ -78,3 +92,26 @@ func (r *OrganisationRepositoryImpl) GetOrganisationByID(ctx context.Context, or
 	}
 	return &org, nil
 }
+
+func (r *OrganisationRepositoryImpl) GetOrganisationByUUID(ctx context.Context, orgUUID string) (*models.Organisation, error) {
+	var org models.Organisation
+	err := r.collection.FindOne(ctx, bson.M{"org_uuid": orgUUID}).Decode(&org)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// SetAssignmentCursor moves the round-robin cursor of the organisation from prev to next. It reports
+// false without writing when the cursor no longer holds prev.
+func (r *OrganisationRepositoryImpl) SetAssignmentCursor(ctx context.Context, orgUUID string, prev string, next string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"org_uuid": orgUUID, "assignment_cursor": storedValue(prev)},
+		bson.M{"$set": bson.M{"assignment_cursor": next}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fverify_be/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded or was issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the position after the last item of a page, encoded as opaque base64 JSON
+type pageCursor struct {
+	Field     string `json:"f"`
+	Value     string `json:"v"`
+	Tie       string `json:"t"`
+	Ascending bool   `json:"a"`
+}
+
+// keyset is a stable sort on a string field with a unique tie-breaker field, used for cursor pagination
+type keyset struct {
+	field     string
+	tie       string
+	ascending bool
+}
+
+func (k keyset) direction() int {
+	if k.ascending {
+		return 1
+	}
+	return -1
+}
+
+func (k keyset) sort() bson.D {
+	return bson.D{{Key: k.field, Value: k.direction()}, {Key: k.tie, Value: k.direction()}}
+}
+
+func (k keyset) encode(value string, tie string) string {
+	data, _ := json.Marshal(pageCursor{Field: k.field, Value: value, Tie: tie, Ascending: k.ascending})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// after decodes a cursor into the filter selecting the records that follow it
+func (k keyset) after(cursor string) (bson.M, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var position pageCursor
+	if err := json.Unmarshal(data, &position); err != nil || position.Field != k.field || position.Ascending != k.ascending {
+		return nil, ErrInvalidCursor
+	}
+	op := "$lt"
+	if k.ascending {
+		op = "$gt"
+	}
+	// Records created before the field existed have no value; treat them as the empty string
+	var equal interface{} = position.Value
+	if position.Value == "" {
+		equal = bson.M{"$in": bson.A{nil, ""}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{k.field: bson.M{op: position.Value}},
+		bson.M{k.field: equal, k.tie: bson.M{op: position.Tie}},
+	}}, nil
+}
+
+// findPage runs a keyset-ordered query for one page and returns the cursor of the next page.
+// key returns the sort field and tie-breaker values of an item.
+func findPage[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, k keyset, page models.PageRequest, key func(*T) (string, string)) ([]T, string, error) {
+	opts := options.Find().SetSort(k.sort()).SetLimit(int64(page.Limit) + 1)
+	if page.Cursor != "" {
+		after, err := k.after(page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": bson.A{filter, after}}
+	} else if page.Skip > 0 {
+		opts.SetSkip(int64(page.Skip))
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	items := []T{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, "", err
+	}
+	// One extra record was fetched to tell whether another page follows
+	if len(items) <= page.Limit {
+		return items, "", nil
+	}
+	items = items[:page.Limit]
+	return items, k.encode(key(&items[page.Limit-1])), nil
+}
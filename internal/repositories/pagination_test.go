@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestKeysetCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     keyset
+		value string
+		tie   string
+		want  bson.M
+	}{
+		{
+			"descending", keyset{field: "created_time", tie: "uid"}, "2023-04-12T15:04:05Z", "u-2",
+			bson.M{"$or": bson.A{
+				bson.M{"created_time": bson.M{"$lt": "2023-04-12T15:04:05Z"}},
+				bson.M{"created_time": "2023-04-12T15:04:05Z", "uid": bson.M{"$lt": "u-2"}},
+			}},
+		},
+		{
+			"ascending", keyset{field: "created_time", tie: "org_uuid", ascending: true}, "2023-04-12T15:04:05Z", "o-1",
+			bson.M{"$or": bson.A{
+				bson.M{"created_time": bson.M{"$gt": "2023-04-12T15:04:05Z"}},
+				bson.M{"created_time": "2023-04-12T15:04:05Z", "org_uuid": bson.M{"$gt": "o-1"}},
+			}},
+		},
+		{
+			"record without the field", keyset{field: "created_time", tie: "uid", ascending: true}, "", "u-1",
+			bson.M{"$or": bson.A{
+				bson.M{"created_time": bson.M{"$gt": ""}},
+				bson.M{"created_time": bson.M{"$in": bson.A{nil, ""}}, "uid": bson.M{"$gt": "u-1"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.k.after(tt.k.encode(tt.value, tt.tie))
+			if err != nil {
+				t.Fatalf("after() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetCursorRejected(t *testing.T) {
+	k := keyset{field: "created_time", tie: "uid"}
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "%%%"},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("created_time"))},
+		{"other field", keyset{field: "time", tie: "uid"}.encode("2023-04-12T15:04:05Z", "u-1")},
+		{"other direction", keyset{field: "created_time", tie: "uid", ascending: true}.encode("2023-04-12T15:04:05Z", "u-1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := k.after(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("after(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+			}
+		})
+	}
+}
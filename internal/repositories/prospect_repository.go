@@ -2,9 +2,11 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fverify_be/internal/models"
+	"regexp"
 
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
@@ -18,7 +20,27 @@ func NewProspectRepository(client *mongo.Client, dbName, collectionName string)
 	return &ProspectRepositoryImpl{collection: collection}
 }
 
+// orgFilter scopes a prospect query to the given organisation
+func orgFilter(orgUUID string, filter bson.M) bson.M {
+	scoped := bson.M{"org_uuid": orgUUID}
+	for key, value := range filter {
+		scoped[key] = value
+	}
+	return scoped
+}
+
+// ErrProspectChanged is returned when a prospect was changed by another request after it was read
+var ErrProspectChanged = errors.New("prospect was changed by another request, reload it and try again")
+
+// ErrHistoryConflict is returned when a history entry could not be chained because other entries kept
+// being appended to the same prospect
+var ErrHistoryConflict = errors.New("prospect history changed concurrently")
+
+// historyAppendAttempts bounds how often appendHistory rereads the chain head after losing a race
+const historyAppendAttempts = 5
+
 func (r *ProspectRepositoryImpl) Create(ctx context.Context, prospect *models.Prospect) error {
+	prospect.SealHistory()
 	_, err := r.collection.InsertOne(ctx, prospect)
 	if err != nil {
 		return err
@@ -26,24 +48,86 @@ func (r *ProspectRepositoryImpl) Create(ctx context.Context, prospect *models.Pr
 	return nil
 }
 
-func (r *ProspectRepositoryImpl) GetByID(ctx context.Context, id string) (*models.Prospect, error) {
+func (r *ProspectRepositoryImpl) GetByID(ctx context.Context, orgUUID string, id string) (*models.Prospect, error) {
 	var prospect models.Prospect
-	err := r.collection.FindOne(ctx, bson.M{"uid": id}).Decode(&prospect)
+	err := r.collection.FindOne(ctx, orgFilter(orgUUID, bson.M{"uid": id})).Decode(&prospect)
 	return &prospect, err
 }
 
-func (r *ProspectRepositoryImpl) Update(ctx context.Context, prospect *models.Prospect) error {
-	_, err := r.collection.UpdateOne(ctx, bson.M{"uid": prospect.UId}, bson.M{"$set": prospect})
-	return err
+// Update writes a prospect read as before back to the database. History entries appended since before are
+// chained and pushed, so the stored history is never rewritten; media and visits are left to their own
+// updates. The write only applies while the stored history head, status and assignee are still the ones
+// read with before, and returns ErrProspectChanged otherwise, so a concurrent change is never silently
+// overwritten and workflow and assignment checks made on before still hold.
+func (r *ProspectRepositoryImpl) Update(ctx context.Context, orgUUID string, before *models.Prospect, prospect *models.Prospect) error {
+	head := before.HistoryHead
+	appended := append([]models.UpdateHistory(nil), prospect.UpdateHistory[len(before.UpdateHistory):]...)
+	for i := range appended {
+		appended[i] = models.SealHistoryEntry(prospect.UId, head, appended[i])
+		prospect.UpdateHistory[len(before.UpdateHistory)+i] = appended[i]
+		head = appended[i].Hash
+	}
+	prospect.HistoryHead = head
+
+	raw, err := bson.Marshal(prospect)
+	if err != nil {
+		return err
+	}
+	var set bson.M
+	if err := bson.Unmarshal(raw, &set); err != nil {
+		return err
+	}
+	for _, field := range []string{"update_history", "media", "visits"} {
+		delete(set, field)
+	}
+	update := bson.M{"$set": set}
+	// Cleared locations are left out of $set by omitempty and have to be removed explicitly
+	unset := bson.M{}
+	if before.ResidentialLocation != nil && prospect.ResidentialLocation == nil {
+		unset["residential_location"] = ""
+	}
+	if before.OfficeLocation != nil && prospect.OfficeLocation == nil {
+		unset["office_location"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(appended) > 0 {
+		update["$push"] = bson.M{"update_history": bson.M{"$each": appended}}
+	}
+
+	// The update only applies if the prospect is still in the state it was read in
+	filter := orgFilter(orgUUID, bson.M{
+		"uid":          prospect.UId,
+		"history_head": storedValue(before.HistoryHead),
+		"status":       storedValue(string(before.Status)),
+		"assigned_to":  storedValue(before.AssignedTo),
+	})
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrProspectChanged
+	}
+	return nil
 }
 
-func (r *ProspectRepositoryImpl) Delete(ctx context.Context, id string) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"uid": id})
+// storedValue matches a string field as it was read, where an empty string also matches a missing field
+func storedValue(value string) any {
+	if value == "" {
+		return bson.M{"$in": bson.A{nil, ""}}
+	}
+	return value
+}
+
+func (r *ProspectRepositoryImpl) Delete(ctx context.Context, orgUUID string, id string) error {
+	_, err := r.collection.DeleteOne(ctx, orgFilter(orgUUID, bson.M{"uid": id}))
 	return err
 }
 
-func (r *ProspectRepositoryImpl) FindAll(ctx context.Context) ([]*models.Prospect, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+func (r *ProspectRepositoryImpl) FindAll(ctx context.Context, orgUUID string) ([]*models.Prospect, error) {
+	cursor, err := r.collection.Find(ctx, orgFilter(orgUUID, bson.M{}))
 	if err != nil {
 		return nil, err
 	}
@@ -59,29 +143,326 @@ func (r *ProspectRepositoryImpl) FindAll(ctx context.Context) ([]*models.Prospec
 	}
 	return prospects, nil
 }
-func (r *ProspectRepositoryImpl) GetProspects(ctx context.Context, skip int, limit int) ([]models.Prospect, error) {
-	var prospects []models.Prospect
 
-	// MongoDB query with skip and limit
-	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSkip(int64(skip)).SetLimit(int64(limit)))
+// EnsureIndexes creates the indexes backing the prospect list filters, sorts and assignment queries
+func (r *ProspectRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "uid", Value: 1}}, Options: options.Index().SetName("org_uuid_uid")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "created_time", Value: -1}, {Key: "uid", Value: -1}}, Options: options.Index().SetName("org_uuid_created_time")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "updated_time", Value: -1}}, Options: options.Index().SetName("org_uuid_updated_time")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "status", Value: 1}, {Key: "created_time", Value: -1}}, Options: options.Index().SetName("org_uuid_status_created_time")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "assigned_to", Value: 1}, {Key: "status", Value: 1}}, Options: options.Index().SetName("org_uuid_assigned_to_status")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "created_by", Value: 1}}, Options: options.Index().SetName("org_uuid_created_by")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "prospect_id", Value: 1}}, Options: options.Index().SetName("org_uuid_prospect_id")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "mobile_number", Value: 1}}, Options: options.Index().SetName("org_uuid_mobile_number")},
+	})
+	return err
+}
+
+// prospectQuery builds the MongoDB filter for a ProspectFilter, scoped to the organisation
+func prospectQuery(orgUUID string, filter models.ProspectFilter) bson.M {
+	query := bson.M{}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	if filter.EmploymentType != "" {
+		query["employment_type"] = filter.EmploymentType
+	}
+	if r := timeRange(filter.CreatedFrom, filter.CreatedTo); r != nil {
+		query["created_time"] = r
+	}
+	if r := timeRange(filter.UpdatedFrom, filter.UpdatedTo); r != nil {
+		query["updated_time"] = r
+	}
+	if filter.AssignedTo != "" {
+		query["assigned_to"] = filter.AssignedTo
+	}
+	if filter.CreatedBy != "" {
+		query["created_by"] = filter.CreatedBy
+	}
+	if filter.Search != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(filter.Search), "$options": "i"}
+		query["$or"] = []bson.M{
+			{"applicant_name": pattern},
+			{"mobile_number": pattern},
+			{"prospect_id": pattern},
+		}
+	}
+	return orgFilter(orgUUID, query)
+}
+
+func timeRange(from string, to string) bson.M {
+	if from == "" && to == "" {
+		return nil
+	}
+	r := bson.M{}
+	if from != "" {
+		r["$gte"] = from
+	}
+	if to != "" {
+		r["$lte"] = to
+	}
+	return r
+}
+
+// prospectKeyset orders by the requested field with uid as a tie-breaker so pages are stable
+func prospectKeyset(filter models.ProspectFilter) keyset {
+	field := filter.SortBy
+	if field == "" {
+		field = "created_time"
+	}
+	return keyset{field: field, tie: "uid", ascending: filter.SortAscending}
+}
+
+// prospectSortValue returns the value of a sortable field, see models.ProspectSortFields
+func prospectSortValue(prospect *models.Prospect, field string) string {
+	switch field {
+	case "updated_time":
+		return prospect.UpdatedTime
+	case "applicant_name":
+		return prospect.ApplicantName
+	case "prospect_id":
+		return prospect.ProspectId
+	case "status":
+		return string(prospect.Status)
+	}
+	return prospect.CreatedTime
+}
+
+// GetProspects returns one page of matching prospects and the cursor of the next page
+func (r *ProspectRepositoryImpl) GetProspects(ctx context.Context, orgUUID string, filter models.ProspectFilter, page models.PageRequest) ([]models.Prospect, string, error) {
+	k := prospectKeyset(filter)
+	return findPage(ctx, r.collection, prospectQuery(orgUUID, filter), k, page, func(p *models.Prospect) (string, string) {
+		return prospectSortValue(p, k.field), p.UId
+	})
+}
+
+func (r *ProspectRepositoryImpl) GetProspectsCount(ctx context.Context, orgUUID string, filter models.ProspectFilter) (int, error) {
+	// MongoDB query to count documents
+	count, err := r.collection.CountDocuments(ctx, prospectQuery(orgUUID, filter))
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// AddMedia appends uploaded file metadata and its history entry to a prospect
+func (r *ProspectRepositoryImpl) AddMedia(ctx context.Context, orgUUID string, uid string, media models.ProspectMedia, history models.UpdateHistory) error {
+	return r.updateWithHistory(ctx, orgUUID, uid, bson.M{"$push": bson.M{"media": media}}, history)
+}
+
+// RemoveMedia removes a file's metadata from a prospect and records the history entry
+func (r *ProspectRepositoryImpl) RemoveMedia(ctx context.Context, orgUUID string, uid string, mediaUId string, history models.UpdateHistory) error {
+	return r.updateWithHistory(ctx, orgUUID, uid, bson.M{"$pull": bson.M{"media": bson.M{"uid": mediaUId}}}, history)
+}
+
+// updateWithHistory applies an update to one prospect together with its update stamp
+func (r *ProspectRepositoryImpl) updateWithHistory(ctx context.Context, orgUUID string, uid string, update bson.M, history models.UpdateHistory) error {
+	update["$set"] = bson.M{"updated_by": history.UpdateBy, "updated_time": history.UpdatedTime}
+	return r.appendHistory(ctx, orgUUID, uid, bson.M{}, update, history)
+}
+
+// appendHistory applies an update to one prospect and pushes a history entry chained to the prospect's
+// current head. The update only applies while the head is unchanged, so two concurrent appends cannot
+// chain to the same entry; the loser rereads the head and tries again. filter narrows the prospect
+// further, and update must not push to update_history itself.
+func (r *ProspectRepositoryImpl) appendHistory(ctx context.Context, orgUUID string, uid string, filter bson.M, update bson.M, history models.UpdateHistory) error {
+	head, err := r.historyHead(ctx, orgUUID, uid)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; attempt < historyAppendAttempts; attempt++ {
+		entry := models.SealHistoryEntry(uid, head, history)
+		guarded := orgFilter(orgUUID, filter)
+		guarded["uid"] = uid
+		guarded["history_head"] = head
+		if head == "" {
+			guarded["history_head"] = bson.M{"$in": []interface{}{nil, ""}}
+		}
+		chained := bson.M{}
+		for operator, fields := range update {
+			chained[operator] = fields
+		}
+		push := bson.M{"update_history": entry}
+		for field, value := range asDocument(update["$push"]) {
+			push[field] = value
+		}
+		set := bson.M{"history_head": entry.Hash}
+		for field, value := range asDocument(update["$set"]) {
+			set[field] = value
+		}
+		chained["$push"], chained["$set"] = push, set
+
+		result, err := r.collection.UpdateOne(ctx, guarded, chained)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount > 0 {
+			return nil
+		}
+		// Either another entry was appended or the rest of the filter no longer matches
+		moved, err := r.historyHead(ctx, orgUUID, uid)
+		if err != nil {
+			return err
+		}
+		if moved == head {
+			return mongo.ErrNoDocuments
+		}
+		head = moved
+	}
+	return ErrHistoryConflict
+}
+
+// historyHead returns the hash of the last sealed history entry of a prospect
+func (r *ProspectRepositoryImpl) historyHead(ctx context.Context, orgUUID string, uid string) (string, error) {
+	var current struct {
+		HistoryHead string `bson:"history_head"`
+	}
+	err := r.collection.FindOne(ctx, orgFilter(orgUUID, bson.M{"uid": uid}), options.FindOne().SetProjection(bson.M{"history_head": 1})).Decode(&current)
+	return current.HistoryHead, err
+}
+
+func asDocument(value interface{}) bson.M {
+	document, _ := value.(bson.M)
+	return document
+}
+
+// AddVisit appends a checked-in visit and its history entry to a prospect
+func (r *ProspectRepositoryImpl) AddVisit(ctx context.Context, orgUUID string, uid string, visit models.ProspectVisit, history models.UpdateHistory) error {
+	return r.updateWithHistory(ctx, orgUUID, uid, bson.M{"$push": bson.M{"visits": visit}}, history)
+}
+
+// CompleteVisit stores the check-out of a visit that is still in progress. It returns
+// mongo.ErrNoDocuments when the visit does not exist or has already been checked out.
+func (r *ProspectRepositoryImpl) CompleteVisit(ctx context.Context, orgUUID string, uid string, visit models.ProspectVisit, history models.UpdateHistory) error {
+	filter := bson.M{
+		"visits": bson.M{"$elemMatch": bson.M{"uid": visit.UId, "check_out_time": bson.M{"$in": []interface{}{nil, ""}}}},
+	}
+	return r.appendHistory(ctx, orgUUID, uid, filter, bson.M{
+		"$set": bson.M{
+			"visits.$":     visit,
+			"updated_by":   history.UpdateBy,
+			"updated_time": history.UpdatedTime,
+		},
+	}, history)
+}
+
+// SetLocation stores geocoded coordinates in residential_location or office_location
+func (r *ProspectRepositoryImpl) SetLocation(ctx context.Context, orgUUID string, uid string, field string, location models.GeoPoint) error {
+	_, err := r.collection.UpdateOne(ctx, orgFilter(orgUUID, bson.M{"uid": uid}), bson.M{"$set": bson.M{field: location}})
+	return err
+}
+
+// CountByAssignee counts the prospects held by each of the given assignees, ignoring the excluded statuses
+func (r *ProspectRepositoryImpl) CountByAssignee(ctx context.Context, orgUUID string, assignees []string, excludeStatuses []models.ProspectStatus) (map[string]int, error) {
+	pipeline := []bson.M{
+		{"$match": orgFilter(orgUUID, bson.M{
+			"assigned_to": bson.M{"$in": assignees},
+			"status":      bson.M{"$nin": excludeStatuses},
+		})},
+		{"$group": bson.M{"_id": "$assigned_to", "count": bson.M{"$sum": 1}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	// Decode the results
-	if err := cursor.All(ctx, &prospects); err != nil {
+	var results []struct {
+		Assignee string `bson:"_id"`
+		Count    int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
 		return nil, err
 	}
+	counts := make(map[string]int, len(results))
+	for _, result := range results {
+		counts[result.Assignee] = result.Count
+	}
+	return counts, nil
+}
 
+// GetUnassigned returns unassigned prospects in the given statuses, optionally limited to specific UIds
+func (r *ProspectRepositoryImpl) GetUnassigned(ctx context.Context, orgUUID string, uids []string, statuses []models.ProspectStatus) ([]*models.Prospect, error) {
+	filter := bson.M{
+		"assigned_to": bson.M{"$in": []interface{}{nil, ""}},
+		"status":      bson.M{"$in": statuses},
+	}
+	if len(uids) > 0 {
+		filter["uid"] = bson.M{"$in": uids}
+	}
+	cursor, err := r.collection.Find(ctx, orgFilter(orgUUID, filter), options.Find().SetSort(bson.D{{Key: "created_time", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var prospects []*models.Prospect
+	if err := cursor.All(ctx, &prospects); err != nil {
+		return nil, err
+	}
 	return prospects, nil
 }
 
-func (r *ProspectRepositoryImpl) GetProspectsCount(ctx context.Context) (int, error) {
-	// MongoDB query to count documents
-	count, err := r.collection.CountDocuments(ctx, bson.M{})
+// GetCreatorsWithoutOrg returns the distinct created_by usernames of prospects that predate org scoping
+func (r *ProspectRepositoryImpl) GetCreatorsWithoutOrg(ctx context.Context) ([]string, error) {
+	var creators []string
+	err := r.collection.Distinct(ctx, "created_by", bson.M{"org_uuid": bson.M{"$in": []interface{}{nil, ""}}}).Decode(&creators)
+	if err != nil {
+		return nil, err
+	}
+	return creators, nil
+}
+
+// SetOrgUUIDForCreator stamps the org on unscoped prospects created by the given username
+func (r *ProspectRepositoryImpl) SetOrgUUIDForCreator(ctx context.Context, createdBy string, orgUUID string) (int, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"created_by": createdBy, "org_uuid": bson.M{"$in": []interface{}{nil, ""}}},
+		bson.M{"$set": bson.M{"org_uuid": orgUUID}},
+	)
 	if err != nil {
 		return 0, err
 	}
-	return int(count), nil
+	return int(result.ModifiedCount), nil
+}
+
+// GetOrgUUIDs returns the distinct organisations that own prospects
+func (r *ProspectRepositoryImpl) GetOrgUUIDs(ctx context.Context) ([]string, error) {
+	var orgUUIDs []string
+	err := r.collection.Distinct(ctx, "org_uuid", bson.M{"org_uuid": bson.M{"$nin": []interface{}{nil, ""}}}).Decode(&orgUUIDs)
+	if err != nil {
+		return nil, err
+	}
+	return orgUUIDs, nil
+}
+
+// GetHistoryHeads returns the chain heads and history lengths of an organisation's prospects updated at
+// or after since, ordered by uid. Prospects without a sealed history are left out.
+func (r *ProspectRepositoryImpl) GetHistoryHeads(ctx context.Context, orgUUID string, since string) ([]models.HistoryHead, error) {
+	match := bson.M{"history_head": bson.M{"$nin": []interface{}{nil, ""}}}
+	if since != "" {
+		match["updated_time"] = bson.M{"$gte": since}
+	}
+	pipeline := []bson.M{
+		{"$match": orgFilter(orgUUID, match)},
+		{"$project": bson.M{
+			"_id":    0,
+			"uid":    1,
+			"hash":   "$history_head",
+			"length": bson.M{"$size": bson.M{"$ifNull": bson.A{"$update_history", bson.A{}}}},
+		}},
+		{"$sort": bson.M{"uid": 1}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var heads []models.HistoryHead
+	if err := cursor.All(ctx, &heads); err != nil {
+		return nil, err
+	}
+	return heads, nil
 }
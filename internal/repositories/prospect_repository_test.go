@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+
+	"fverify_be/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestProspectQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter models.ProspectFilter
+		want   bson.M
+	}{
+		{"no filter", models.ProspectFilter{}, bson.M{}},
+		{
+			"statuses and assignee", models.ProspectFilter{Statuses: []models.ProspectStatus{models.Pending, models.OnVisit}, AssignedTo: "user-1"},
+			bson.M{"status": bson.M{"$in": []models.ProspectStatus{models.Pending, models.OnVisit}}, "assigned_to": "user-1"},
+		},
+		{
+			"open time range", models.ProspectFilter{CreatedFrom: "2023-04-01T00:00:00Z", UpdatedTo: "2023-04-30T23:59:59Z"},
+			bson.M{"created_time": bson.M{"$gte": "2023-04-01T00:00:00Z"}, "updated_time": bson.M{"$lte": "2023-04-30T23:59:59Z"}},
+		},
+		{
+			"search is literal", models.ProspectFilter{Search: "j.doe+1"},
+			bson.M{"$or": []bson.M{
+				{"applicant_name": bson.M{"$regex": `j\.doe\+1`, "$options": "i"}},
+				{"mobile_number": bson.M{"$regex": `j\.doe\+1`, "$options": "i"}},
+				{"prospect_id": bson.M{"$regex": `j\.doe\+1`, "$options": "i"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := prospectQuery("org-1", tt.filter), orgFilter("org-1", tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("prospectQuery() = %v, want %v", got, want)
+			}
+		})
+	}
+}
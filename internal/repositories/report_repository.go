@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"fverify_be/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type ReportRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewReportRepository(client *mongo.Client, dbName, collectionName string) *ReportRepositoryImpl {
+	collection := client.Database(dbName).Collection(collectionName)
+	return &ReportRepositoryImpl{collection: collection}
+}
+
+func (r *ReportRepositoryImpl) Create(ctx context.Context, report *models.VerificationReport) error {
+	_, err := r.collection.InsertOne(ctx, report)
+	return err
+}
+
+func (r *ReportRepositoryImpl) GetByReportID(ctx context.Context, reportId string) (*models.VerificationReport, error) {
+	var report models.VerificationReport
+	err := r.collection.FindOne(ctx, bson.M{"report_id": reportId}).Decode(&report)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *ReportRepositoryImpl) GetByPdfHash(ctx context.Context, pdfHash string) (*models.VerificationReport, error) {
+	var report models.VerificationReport
+	err := r.collection.FindOne(ctx, bson.M{"pdf_hash": pdfHash}).Decode(&report)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"fverify_be/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type SessionRepositoryImpl struct {
+	collection *mongo.Collection
+}
+
+func NewSessionRepository(client *mongo.Client, dbName, collectionName string) *SessionRepositoryImpl {
+	collection := client.Database(dbName).Collection(collectionName)
+	return &SessionRepositoryImpl{collection: collection}
+}
+
+// EnsureIndexes creates the refresh token lookup indexes and expires sessions once their refresh token lapses
+func (r *SessionRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "session_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("session_id_unique")},
+		{Keys: bson.D{{Key: "current_token_hash", Value: 1}}, Options: options.Index().SetName("current_token_hash")},
+		{Keys: bson.D{{Key: "used_token_hashes", Value: 1}}, Options: options.Index().SetName("used_token_hashes")},
+		{Keys: bson.D{{Key: "org_uuid", Value: 1}, {Key: "user_uid", Value: 1}}, Options: options.Index().SetName("org_uuid_user_uid")},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl")},
+	})
+	return err
+}
+
+func (r *SessionRepositoryImpl) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+// Rotate replaces the current refresh token of a live session. It matches on the presented token hash,
+// so of two concurrent refreshes with the same token only one succeeds.
+func (r *SessionRepositoryImpl) Rotate(ctx context.Context, tokenHash string, newTokenHash string, refreshedTime string) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"current_token_hash": tokenHash,
+			"revoked_time":       bson.M{"$in": bson.A{nil, ""}},
+			"expires_at":         bson.M{"$gt": time.Now().UTC()},
+		},
+		bson.M{
+			"$set":  bson.M{"current_token_hash": newTokenHash, "last_refresh_time": refreshedTime},
+			"$push": bson.M{"used_token_hashes": tokenHash},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByUsedTokenHash finds the session a rotated refresh token belonged to
+func (r *SessionRepositoryImpl) GetByUsedTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOne(ctx, bson.M{"used_token_hashes": tokenHash}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByTokenHash finds the session whose current refresh token has the given hash
+func (r *SessionRepositoryImpl) GetByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOne(ctx, bson.M{"current_token_hash": tokenHash}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Revoke ends a session so none of its refresh tokens are accepted any more
+func (r *SessionRepositoryImpl) Revoke(ctx context.Context, sessionId string, reason string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"session_id": sessionId, "revoked_time": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"revoked_time": time.Now().UTC().Format(time.RFC3339), "revoked_reason": reason}},
+	)
+	return err
+}
+
+// RevokeUserSessions ends every live session of a user
+func (r *SessionRepositoryImpl) RevokeUserSessions(ctx context.Context, orgUUID string, userUId string, reason string) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"org_uuid": orgUUID, "user_uid": userUId, "revoked_time": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"revoked_time": time.Now().UTC().Format(time.RFC3339), "revoked_reason": reason}},
+	)
+	return err
+}
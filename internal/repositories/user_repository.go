@@ -2,15 +2,25 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fverify_be/internal/models"
-	"strings"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrUserExists is returned when a userid or username is already taken within the organisation
+var ErrUserExists = errors.New("user with the same userid or username already exists in the organisation")
+
+// ErrResetCodeUsed is returned when a password reset code was consumed or replaced before it could be used
+var ErrResetCodeUsed = errors.New("password reset code is no longer valid")
+
+// MaxPasswordHistory is the number of password hashes kept per user to prevent reuse
+const MaxPasswordHistory = 24
+
 type UserRepositoryImpl struct {
 	collection *mongo.Collection
 }
@@ -34,6 +44,30 @@ func NewUserRepository(client *mongo.Client, dbName, collectionName string) *Use
 	return &UserRepositoryImpl{collection: collection}
 }
 
+// EnsureIndexes creates the per-organisation unique indexes on userid and username, and the mobile
+// number index used by SMS logins
+func (r *UserRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "org_uuid", Value: 1}, {Key: "userid", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("org_uuid_userid_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "org_uuid", Value: 1}, {Key: "username", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("org_uuid_username_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "org_uuid", Value: 1}, {Key: "created_time", Value: 1}, {Key: "uid", Value: 1}},
+			Options: options.Index().SetName("org_uuid_created_time"),
+		},
+		{
+			Keys:    bson.D{{Key: "org_uuid", Value: 1}, {Key: "mobile_number", Value: 1}},
+			Options: options.Index().SetName("org_uuid_mobile_number"),
+		},
+	})
+	return err
+}
+
 func (r *UserRepositoryImpl) ValidateUser(ctx context.Context, username, password string, orgUUID string) (*models.User, error) {
 	var user models.User
 	err := r.collection.FindOne(ctx, bson.M{"username": username, "org_uuid": orgUUID}).Decode(&user)
@@ -48,18 +82,67 @@ func (r *UserRepositoryImpl) ValidateUser(ctx context.Context, username, passwor
 	return &user, nil
 }
 
-func (r *UserRepositoryImpl) SetPassword(ctx context.Context, uId string, newPassword string) error {
+// SetPassword replaces a user's password, clearing any forced change or outstanding reset code, and
+// records the change in the update history
+func (r *UserRepositoryImpl) SetPassword(ctx context.Context, orgUUID string, uId string, newPassword string, history models.UpdateHistory) error {
+	_, err := r.setPassword(ctx, bson.M{"uid": uId, "org_uuid": orgUUID}, newPassword, history)
+	return err
+}
+
+// ResetPassword is SetPassword for a reset code: the password is only replaced while the user's
+// outstanding code still has the given hash and has not expired, so a code can be used only once.
+// It returns ErrResetCodeUsed when the code was consumed or replaced in the meantime.
+func (r *UserRepositoryImpl) ResetPassword(ctx context.Context, orgUUID string, uId string, codeHash string, newPassword string, history models.UpdateHistory) error {
+	matched, err := r.setPassword(ctx, bson.M{
+		"uid":                       uId,
+		"org_uuid":                  orgUUID,
+		"password_reset.code_hash":  codeHash,
+		"password_reset.expires_at": bson.M{"$gt": time.Now().UTC()},
+	}, newPassword, history)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return ErrResetCodeUsed
+	}
+	return nil
+}
+
+func (r *UserRepositoryImpl) setPassword(ctx context.Context, filter bson.M, newPassword string, history models.UpdateHistory) (bool, error) {
 	// Hash the new password
 	hashedPassword, err := HashPassword(newPassword)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// Update the password for the user with the given uId
-	_, err = r.collection.UpdateOne(
+	// Update the password for the matching user
+	result, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"uid": uId}, // Filter by uId
-		bson.M{"$set": bson.M{"password": hashedPassword}}, // Update the password field
+		filter,
+		bson.M{
+			"$set":   bson.M{"password": hashedPassword, "must_change_password": false, "updated_time": history.UpdatedTime},
+			"$unset": bson.M{"password_reset": ""},
+			"$inc":   bson.M{"token_version": 1}, // Revoke issued tokens
+			"$push":  bson.M{"update_history": history, "password_history": passwordHistoryPush(hashedPassword)},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// passwordHistoryPush appends a hash to the password history, keeping the newest MaxPasswordHistory
+func passwordHistoryPush(hashedPassword string) bson.M {
+	return bson.M{"$each": bson.A{hashedPassword}, "$slice": -MaxPasswordHistory}
+}
+
+// SetPasswordReset stores a one-time password reset code for a user, replacing any earlier one
+func (r *UserRepositoryImpl) SetPasswordReset(ctx context.Context, orgUUID string, uId string, reset *models.PasswordReset, history models.UpdateHistory) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID},
+		bson.M{"$set": bson.M{"password_reset": reset}, "$push": bson.M{"update_history": history}},
 	)
 	return err
 }
@@ -72,9 +155,13 @@ func (r *UserRepositoryImpl) Create(ctx context.Context, user *models.User) (*mo
 		return nil, err
 	}
 	user.Password = hashedPassword // Set the hashed password
+	user.PasswordHistory = []string{hashedPassword}
 	// Insert the user into the collection
 	result, err := r.collection.InsertOne(ctx, user)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrUserExists
+		}
 		return nil, err
 	}
 
@@ -87,44 +174,130 @@ func (r *UserRepositoryImpl) Create(ctx context.Context, user *models.User) (*mo
 
 	// Convert to UserResp
 	createdUserResp := models.UserResp{
-		UId:           createdUser.UId,
-		UserId:        createdUser.UserId,
-		Username:      createdUser.Username,
-		Role:          createdUser.Role,
-		Status:        createdUser.Status,
-		MobileNumber:  createdUser.MobileNumber,
-		Remarks:       createdUser.Remarks,
-		OrgUUID:       createdUser.OrgUUID,
-		CreatedTime:   createdUser.CreatedTime,
-		OrgStatus:     createdUser.OrgStatus,
-		UpdatedTime:   createdUser.UpdatedTime,
-		UpdateHistory: createdUser.UpdateHistory}
+		UId:                createdUser.UId,
+		UserId:             createdUser.UserId,
+		Username:           createdUser.Username,
+		Role:               createdUser.Role,
+		Status:             createdUser.Status,
+		MobileNumber:       createdUser.MobileNumber,
+		BaseLocation:       createdUser.BaseLocation,
+		Remarks:            createdUser.Remarks,
+		OrgUUID:            createdUser.OrgUUID,
+		CreatedTime:        createdUser.CreatedTime,
+		OrgStatus:          createdUser.OrgStatus,
+		UpdatedTime:        createdUser.UpdatedTime,
+		UpdateHistory:      createdUser.UpdateHistory,
+		MustChangePassword: createdUser.MustChangePassword}
 	return &createdUserResp, nil
 }
 
-func (r *UserRepositoryImpl) GetByUserID(ctx context.Context, userId string) (*models.UserResp, error) {
+func (r *UserRepositoryImpl) GetByUserID(ctx context.Context, orgUUID string, userId string) (*models.UserResp, error) {
 	var user models.UserResp
-	err := r.collection.FindOne(ctx, bson.M{"userid": userId}).Decode(&user)
+	err := r.collection.FindOne(ctx, bson.M{"userid": userId, "org_uuid": orgUUID}).Decode(&user)
 	return &user, err
 }
 
-func (r *UserRepositoryImpl) GetByUserUID(ctx context.Context, uid string) (*models.UserResp, error) {
+// GetCredentials returns a user with their password hashes
+func (r *UserRepositoryImpl) GetCredentials(ctx context.Context, orgUUID string, uid string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"uid": uid, "org_uuid": orgUUID}).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername returns a user with their credentials
+func (r *UserRepositoryImpl) GetByUsername(ctx context.Context, orgUUID string, username string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"username": username, "org_uuid": orgUUID}).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByMobileNumber returns the users of an organisation with the given mobile number and one of the
+// given roles, with their credentials
+func (r *UserRepositoryImpl) GetByMobileNumber(ctx context.Context, orgUUID string, mobileNumber string, roles []models.Role) ([]*models.User, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"org_uuid": orgUUID, "mobile_number": mobileNumber, "role": bson.M{"$in": roles}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	return users, cursor.Err()
+}
+
+func (r *UserRepositoryImpl) GetByUserUID(ctx context.Context, orgUUID string, uid string) (*models.UserResp, error) {
 	var user models.UserResp
-	err := r.collection.FindOne(ctx, bson.M{"uid": uid}).Decode(&user)
+	err := r.collection.FindOne(ctx, bson.M{"uid": uid, "org_uuid": orgUUID}).Decode(&user)
 	return &user, err
 }
 
-func (r *UserRepositoryImpl) DeleteByUId(ctx context.Context, uId string) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"uid": uId})
-	return err
+// GetOrgUUIDsByUsername returns every organisation that has a user with the given username
+func (r *UserRepositoryImpl) GetOrgUUIDsByUsername(ctx context.Context, username string) ([]string, error) {
+	var orgUUIDs []string
+	err := r.collection.Distinct(ctx, "org_uuid", bson.M{"username": username}).Decode(&orgUUIDs)
+	if err != nil {
+		return nil, err
+	}
+	return orgUUIDs, nil
 }
-func (r *UserRepositoryImpl) DeleteByUserId(ctx context.Context, userId string) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"userid": userId})
+
+func (r *UserRepositoryImpl) DeleteByUId(ctx context.Context, orgUUID string, uId string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"uid": uId, "org_uuid": orgUUID})
 	return err
 }
 
-func (r *UserRepositoryImpl) GetAllUsers(ctx context.Context) ([]*models.UserResp, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+// DeleteByUserId deletes a user and returns the deleted record
+func (r *UserRepositoryImpl) DeleteByUserId(ctx context.Context, orgUUID string, userId string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOneAndDelete(ctx, bson.M{"userid": userId, "org_uuid": orgUUID}).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userKeyset pages users oldest first
+var userKeyset = keyset{field: "created_time", tie: "uid", ascending: true}
+
+// GetAllUsers returns one page of an organisation's users and the cursor of the next page
+func (r *UserRepositoryImpl) GetAllUsers(ctx context.Context, orgUUID string, page models.PageRequest) ([]models.UserResp, string, error) {
+	return findPage(ctx, r.collection, bson.M{"org_uuid": orgUUID}, userKeyset, page, func(user *models.UserResp) (string, string) {
+		return user.CreatedTime, user.UId
+	})
+}
+
+func (r *UserRepositoryImpl) CountUsers(ctx context.Context, orgUUID string) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"org_uuid": orgUUID})
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// CountUsersByRole returns the number of users of an organisation holding the given role, whatever their status
+func (r *UserRepositoryImpl) CountUsersByRole(ctx context.Context, orgUUID string, role models.Role) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"org_uuid": orgUUID, "role": role})
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// GetActiveUsersByRole returns the active users of an organisation holding the given role
+func (r *UserRepositoryImpl) GetActiveUsersByRole(ctx context.Context, orgUUID string, role models.Role) ([]*models.UserResp, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"org_uuid": orgUUID, "role": role, "status": models.Active})
 	if err != nil {
 		return nil, err
 	}
@@ -144,87 +317,191 @@ func (r *UserRepositoryImpl) GetAllUsers(ctx context.Context) ([]*models.UserRes
 func (r *UserRepositoryImpl) Update(ctx context.Context, user *models.User, authUserName string) (*models.UserResp, error) {
 	// Update the UpdatedTime field
 	var eUser models.User
-	err := r.collection.FindOne(ctx, bson.M{"uid": user.UId}).Decode(&eUser)
+	err := r.collection.FindOne(ctx, bson.M{"uid": user.UId, "org_uuid": user.OrgUUID}).Decode(&eUser)
 	if err != nil {
 		return nil, err
 	}
-	if user.Password != "" {
-		hashedPassword, err := HashPassword(user.Password)
-		if err != nil {
-			return nil, err
-		}
-		user.Password = hashedPassword // Set the hashed password
-	}
+	// Passwords are changed through SetPassword only
+	user.Password = eUser.Password
+	user.MustChangePassword = eUser.MustChangePassword
 
-	// Generate a diff between the existing user and the incoming user
-	var updateComments []string
-	if eUser.UserId != user.UserId {
-		updateComments = append(updateComments, "user id  changed from '"+eUser.UserId+"' to '"+user.UserId+"'")
-	}
-	if eUser.Username != user.Username {
-		updateComments = append(updateComments, "user name changed from '"+eUser.Username+"' to '"+user.Username+"'")
-	}
-	if eUser.Password != user.Password {
-		updateComments = append(updateComments, "password updated")
-	}
-	if eUser.Role != user.Role {
-		updateComments = append(updateComments, "role changed from '"+string(eUser.Role)+"' to '"+string(user.Role)+"'")
-	}
-	if eUser.Status != user.Status {
-		updateComments = append(updateComments, "status changed from '"+string(eUser.Status)+"' to '"+string(user.Status)+"'")
-	}
-	if eUser.Remarks != user.Remarks {
-		updateComments = append(updateComments, "remarks changed from '"+eUser.Remarks+"' to '"+user.Remarks+"'")
-	}
-	if eUser.MobileNumber != user.MobileNumber {
-		updateComments = append(updateComments, "mobile number changed from '"+eUser.MobileNumber+"' to '"+user.MobileNumber+"'")
-	}
+	// The request only carries the editable fields; keep the rest of the stored user
+	user.CreatedTime = eUser.CreatedTime
+	user.OrgStatus = eUser.OrgStatus
 
 	user.UpdatedTime = time.Now().UTC().Format(time.RFC3339)
-	user.UpdateHistory = append(user.UpdateHistory, models.UpdateHistory{
-		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
-		UpdatedComments: strings.Join(updateComments, ", "),
-		UpdateBy:        authUserName,
-	})
+	user.UpdateHistory = append(eUser.UpdateHistory, models.NewUpdateHistory(user.UpdatedTime, authUserName, models.DiffUpdate(&eUser, user)))
+
+	// The token version is only ever incremented and the password history only appended to, so both are left out of $set
+	user.TokenVersion = 0
+	user.PasswordHistory = nil
+	update := bson.M{"$set": user, "$inc": bson.M{"token_version": 1}} // Update the user document and revoke issued tokens
 
 	// Perform the update operation
 	_, err = r.collection.UpdateOne(
 		ctx,
-		bson.M{"uid": user.UId}, // Filter by uId
-		bson.M{"$set": user},    // Update the user document
+		bson.M{"uid": user.UId, "org_uuid": user.OrgUUID}, // Filter by uId within the organisation
+		update,
 	)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrUserExists
+		}
 		return nil, err
 	}
 	// Convert to UserResp
 	createdUserResp := models.UserResp{
-		UId:           user.UId,
-		UserId:        user.UserId,
-		Username:      user.Username,
-		Role:          user.Role,
-		Status:        user.Status,
-		MobileNumber:  user.MobileNumber,
-		Remarks:       user.Remarks,
-		OrgUUID:       user.OrgUUID,
-		CreatedTime:   user.CreatedTime,
-		OrgStatus:     user.OrgStatus,
-		UpdatedTime:   user.UpdatedTime,
-		UpdateHistory: user.UpdateHistory}
+		UId:                user.UId,
+		UserId:             user.UserId,
+		Username:           user.Username,
+		Role:               user.Role,
+		Status:             user.Status,
+		MobileNumber:       user.MobileNumber,
+		BaseLocation:       user.BaseLocation,
+		Remarks:            user.Remarks,
+		OrgUUID:            user.OrgUUID,
+		CreatedTime:        user.CreatedTime,
+		OrgStatus:          user.OrgStatus,
+		UpdatedTime:        user.UpdatedTime,
+		UpdateHistory:      user.UpdateHistory,
+		MustChangePassword: user.MustChangePassword}
 	return &createdUserResp, nil
 }
+
+// AppendUpdateHistory records an event in the update history of the user with the given username
+func (r *UserRepositoryImpl) AppendUpdateHistory(ctx context.Context, orgUUID string, username string, entry models.UpdateHistory) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"username": username, "org_uuid": orgUUID},
+		bson.M{"$push": bson.M{"update_history": entry}},
+	)
+	return err
+}
+
 func (r *UserRepositoryImpl) UpdateUsersStatusByOrgUUID(ctx context.Context, orgUUID string, status models.UserStatus) error {
 	_, err := r.collection.UpdateMany(
 		ctx,
-		bson.M{"org_uuid": orgUUID},              // Filter by org_uuid
-		bson.M{"$set": bson.M{"status": status}}, // Update the status field
+		bson.M{"org_uuid": orgUUID}, // Filter by org_uuid
+		bson.M{"$set": bson.M{"status": status}, "$inc": bson.M{"token_version": 1}}, // Update the status and revoke issued tokens
 	)
 	return err
 }
 
-func (r *UserRepositoryImpl) UpdateUserStatus(ctx context.Context, userId string, status string) error {
-	filter := bson.M{"userid": userId}
-	update := bson.M{"$set": bson.M{"status": status, "updated_time": time.Now().UTC().Format(time.RFC3339)}}
+func (r *UserRepositoryImpl) UpdateUserStatus(ctx context.Context, orgUUID string, userId string, status string) error {
+	filter := bson.M{"userid": userId, "org_uuid": orgUUID}
+	update := bson.M{
+		"$set": bson.M{"status": status, "updated_time": time.Now().UTC().Format(time.RFC3339)},
+		"$inc": bson.M{"token_version": 1},
+	}
 
 	_, err := r.collection.UpdateOne(ctx, filter, update)
 	return err
 }
+
+// SetTwoFactorPending stores a TOTP secret awaiting confirmation, replacing any earlier pending secret
+func (r *UserRepositoryImpl) SetTwoFactorPending(ctx context.Context, orgUUID string, uId string, secret string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID},
+		bson.M{"$set": bson.M{"two_factor.pending_secret": secret}},
+	)
+	return err
+}
+
+// EnableTwoFactor confirms the pending TOTP secret of a user, replacing any earlier enrolment
+func (r *UserRepositoryImpl) EnableTwoFactor(ctx context.Context, orgUUID string, uId string, twoFactor *models.TwoFactor, history models.UpdateHistory) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID},
+		bson.M{"$set": bson.M{"two_factor": twoFactor}, "$push": bson.M{"update_history": history}},
+	)
+	return err
+}
+
+// UseTwoFactorStep records the time step of an accepted TOTP code. It reports false when that step or a
+// later one was already used, so each code is accepted once.
+func (r *UserRepositoryImpl) UseTwoFactorStep(ctx context.Context, orgUUID string, uId string, step int64) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID, "two_factor.last_used_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"two_factor.last_used_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UseRecoveryCode consumes a recovery code, reporting false when the user has no such unused code
+func (r *UserRepositoryImpl) UseRecoveryCode(ctx context.Context, orgUUID string, uId string, codeHash string, history models.UpdateHistory) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID, "two_factor.recovery_code_hashes": codeHash},
+		bson.M{"$pull": bson.M{"two_factor.recovery_code_hashes": codeHash}, "$push": bson.M{"update_history": history}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// SetRecoveryCodes replaces the recovery codes of an enrolled user
+func (r *UserRepositoryImpl) SetRecoveryCodes(ctx context.Context, orgUUID string, uId string, codeHashes []string, history models.UpdateHistory) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID, "two_factor.enabled": true},
+		bson.M{"$set": bson.M{"two_factor.recovery_code_hashes": codeHashes}, "$push": bson.M{"update_history": history}},
+	)
+	return err
+}
+
+// ResetTwoFactor removes a user's two-factor enrolment and revokes their issued tokens
+func (r *UserRepositoryImpl) ResetTwoFactor(ctx context.Context, orgUUID string, uId string, history models.UpdateHistory) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID},
+		bson.M{
+			"$unset": bson.M{"two_factor": ""},
+			"$inc":   bson.M{"token_version": 1},
+			"$push":  bson.M{"update_history": history},
+		},
+	)
+	return err
+}
+
+// SetLoginOTP stores a newly sent SMS login code, replacing any earlier one
+func (r *UserRepositoryImpl) SetLoginOTP(ctx context.Context, orgUUID string, uId string, otp *models.LoginOTP) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID},
+		bson.M{"$set": bson.M{"login_otp": otp}},
+	)
+	return err
+}
+
+// RecordLoginOTPFailure counts a wrong guess against the outstanding SMS login code
+func (r *UserRepositoryImpl) RecordLoginOTPFailure(ctx context.Context, orgUUID string, uId string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID},
+		bson.M{"$inc": bson.M{"login_otp.attempts": 1}},
+	)
+	return err
+}
+
+// UseLoginOTP consumes the outstanding SMS login code, reporting false when it is no longer the one
+// with the given hash, so each code is accepted once
+func (r *UserRepositoryImpl) UseLoginOTP(ctx context.Context, orgUUID string, uId string, codeHash string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"uid": uId, "org_uuid": orgUUID, "login_otp.code_hash": codeHash},
+		bson.M{
+			"$unset": bson.M{"login_otp.code_hash": "", "login_otp.expires_at": ""},
+			"$set":   bson.M{"login_otp.attempts": 0},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
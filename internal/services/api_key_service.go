@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnknownPermission is returned when an API key is requested with a permission keys cannot be granted
+var ErrUnknownPermission = errors.New("unknown API key permission")
+
+// APIKeyService manages the API keys partner systems use to call the API for an organisation
+type APIKeyService struct {
+	repo *repositories.APIKeyRepositoryImpl
+}
+
+func NewAPIKeyService(repo *repositories.APIKeyRepositoryImpl) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// CreateKey creates an API key for an organisation. Only its hash is stored, so the returned key cannot
+// be shown again.
+func (s *APIKeyService) CreateKey(ctx context.Context, orgUUID string, req models.APIKeyReq, createdBy string) (*models.APIKeyCreatedResponse, error) {
+	permissions := []models.Permission{}
+	for _, permission := range req.Permissions {
+		if !slices.Contains(models.APIKeyPermissions, permission) {
+			return nil, ErrUnknownPermission
+		}
+		if !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+
+	key, keyHash, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	record := models.APIKey{
+		KeyId:       uuid.New().String(),
+		OrgUUID:     orgUUID,
+		Name:        req.Name,
+		Prefix:      prefix,
+		KeyHash:     keyHash,
+		Permissions: permissions,
+		CreatedBy:   createdBy,
+		CreatedTime: now.Format(time.RFC3339),
+	}
+	record.ExpiresAt = keyExpiry(now, req.ExpiresInDays)
+	if err := s.repo.Create(ctx, &record); err != nil {
+		return nil, err
+	}
+	return &models.APIKeyCreatedResponse{Key: key, APIKey: record}, nil
+}
+
+// keyExpiry returns when a key created at now with a lifetime of days expires; never when days is not positive
+func keyExpiry(now time.Time, days int) *time.Time {
+	if days <= 0 {
+		return nil
+	}
+	expiresAt := now.AddDate(0, 0, days)
+	return &expiresAt
+}
+
+// GetKeys returns all API keys of an organisation, including revoked and expired ones
+func (s *APIKeyService) GetKeys(ctx context.Context, orgUUID string) ([]models.APIKey, error) {
+	return s.repo.GetAllByOrg(ctx, orgUUID)
+}
+
+// RevokeKey stops an API key from being accepted. It returns the key before and after it was revoked.
+func (s *APIKeyService) RevokeKey(ctx context.Context, orgUUID string, keyId string, revokedBy string) (*models.APIKey, *models.APIKey, error) {
+	revoked, err := s.repo.Revoke(ctx, orgUUID, keyId, time.Now().UTC().Format(time.RFC3339), revokedBy)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only unrevoked keys are revoked, so the key had no revocation before
+	before := *revoked
+	before.RevokedTime, before.RevokedBy = "", ""
+	return &before, revoked, nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyExpiry(t *testing.T) {
+	now := time.Date(2023, 4, 12, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		days int
+		want *time.Time
+	}{
+		{"no lifetime", 0, nil},
+		{"negative lifetime", -1, nil},
+		{"one day", 1, timePtr(time.Date(2023, 4, 13, 10, 0, 0, 0, time.UTC))},
+		{"one year", 365, timePtr(time.Date(2024, 4, 11, 10, 0, 0, 0, time.UTC))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keyExpiry(now, tt.days)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("keyExpiry(%d) = %v, want %v", tt.days, got, tt.want)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrNoFieldExecutives is returned when the organisation has no active field executive to assign to
+var ErrNoFieldExecutives = errors.New("no active field executive available for assignment")
+
+// ErrAssignmentContended is returned when concurrent assignments kept moving the round-robin cursor
+var ErrAssignmentContended = errors.New("round-robin assignment kept conflicting with concurrent assignments")
+
+// closedProspectStatuses are the statuses that no longer count towards an executive's workload
+var closedProspectStatuses = []models.ProspectStatus{models.Approved, models.Rejected, models.Completed, models.Cancelled}
+
+// assignableProspectStatuses are the statuses a batch auto-assignment picks up
+var assignableProspectStatuses = []models.ProspectStatus{models.Pending, models.RePending}
+
+// AssignmentStrategy picks one field executive out of the candidates for a prospect
+type AssignmentStrategy interface {
+	Pick(ctx context.Context, org *models.Organisation, prospect *models.Prospect, candidates []*models.UserResp) (*models.UserResp, error)
+}
+
+// pickReleaser is implemented by strategies that record their picks on the organisation, so a pick
+// whose prospect was never stored can be taken back
+type pickReleaser interface {
+	Release(ctx context.Context, org *models.Organisation, assignee *models.UserResp, candidates []*models.UserResp) error
+}
+
+// noRelease is the release of a pick that recorded nothing
+func noRelease(context.Context) {}
+
+type AssignmentService struct {
+	prospectRepo *repositories.ProspectRepositoryImpl
+	userRepo     *repositories.UserRepositoryImpl
+	orgRepo      *repositories.OrganisationRepositoryImpl
+	strategies   map[models.AssignmentStrategy]AssignmentStrategy
+}
+
+func NewAssignmentService(prospectRepo *repositories.ProspectRepositoryImpl, userRepo *repositories.UserRepositoryImpl, orgRepo *repositories.OrganisationRepositoryImpl) *AssignmentService {
+	leastOpen := &leastOpenCasesStrategy{prospectRepo: prospectRepo}
+	return &AssignmentService{
+		prospectRepo: prospectRepo,
+		userRepo:     userRepo,
+		orgRepo:      orgRepo,
+		strategies: map[models.AssignmentStrategy]AssignmentStrategy{
+			models.AssignRoundRobin:     &roundRobinStrategy{orgRepo: orgRepo},
+			models.AssignLeastOpenCases: leastOpen,
+			models.AssignNearest:        &nearestStrategy{fallback: leastOpen},
+		},
+	}
+}
+
+// AutoAssign sets the assignee of a prospect using its organisation's strategy. It returns nil
+// without changing the prospect when the organisation assigns manually. The caller must call the
+// returned release when the assigned prospect is not stored.
+func (s *AssignmentService) AutoAssign(ctx context.Context, prospect *models.Prospect, actor string) (*models.UserResp, func(context.Context), error) {
+	org, err := s.orgRepo.GetOrganisationByUUID(ctx, prospect.OrgUUID)
+	if err != nil {
+		return nil, noRelease, err
+	}
+	strategy, ok := s.strategies[org.AssignmentStrategy]
+	if !ok {
+		return nil, noRelease, nil
+	}
+
+	candidates, err := s.userRepo.GetActiveUsersByRole(ctx, prospect.OrgUUID, models.FieldExecutive)
+	if err != nil {
+		return nil, noRelease, err
+	}
+	if len(candidates) == 0 {
+		return nil, noRelease, ErrNoFieldExecutives
+	}
+
+	assignee, err := strategy.Pick(ctx, org, prospect, candidates)
+	if err != nil {
+		return nil, noRelease, err
+	}
+	release := noRelease
+	if releaser, ok := strategy.(pickReleaser); ok {
+		release = func(ctx context.Context) {
+			if err := releaser.Release(ctx, org, assignee, candidates); err != nil {
+				log.Printf("Failed to release assignment of prospect %s: %v", prospect.UId, err)
+			}
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	before := *prospect
+	prospect.AssignedTo = assignee.UId
+	prospect.AssignedToName = assignee.Username
+	prospect.AssignedBy = actor
+	prospect.AssignedTime = now
+	prospect.UpdateHistory = append(prospect.UpdateHistory, models.UpdateHistory{
+		UpdatedTime:     now,
+		UpdatedComments: "Auto-assigned to '" + assignee.Username + "' (" + string(org.AssignmentStrategy) + ")",
+		UpdateBy:        actor,
+		Changes:         models.Diff(before, prospect),
+	})
+	return assignee, release, nil
+}
+
+// AssignBatch auto-assigns unassigned Pending/RePending prospects of an organisation. When uids is
+// empty every such prospect is considered. Prospects are assigned one at a time so each pick sees
+// the workload created by the previous one. Prospects that another request assigned or moved after
+// they were read are skipped.
+func (s *AssignmentService) AssignBatch(ctx context.Context, orgUUID string, uids []string, actor string) ([]*models.Prospect, error) {
+	prospects, err := s.prospectRepo.GetUnassigned(ctx, orgUUID, uids, assignableProspectStatuses)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := []*models.Prospect{}
+	for _, prospect := range prospects {
+		before := *prospect
+		assignee, release, err := s.AutoAssign(ctx, prospect, actor)
+		if err != nil {
+			return assigned, err
+		}
+		if assignee == nil {
+			break
+		}
+		prospect.UpdatedBy = actor
+		prospect.UpdatedTime = time.Now().UTC().Format(time.RFC3339)
+		if err := s.prospectRepo.Update(ctx, orgUUID, &before, prospect); err != nil {
+			release(ctx)
+			if errors.Is(err, repositories.ErrProspectChanged) {
+				continue
+			}
+			return assigned, err
+		}
+		assigned = append(assigned, prospect)
+	}
+	return assigned, nil
+}
+
+// roundRobinStrategy cycles through executives ordered by UId, remembering the last pick on the organisation
+type roundRobinStrategy struct {
+	orgRepo *repositories.OrganisationRepositoryImpl
+}
+
+// roundRobinAttempts bounds how often a pick is retried when concurrent picks keep moving the cursor
+const roundRobinAttempts = 5
+
+func (st *roundRobinStrategy) Pick(ctx context.Context, org *models.Organisation, prospect *models.Prospect, candidates []*models.UserResp) (*models.UserResp, error) {
+	sorted := sortedByUId(candidates)
+	for attempt := 0; attempt < roundRobinAttempts; attempt++ {
+		if attempt > 0 {
+			current, err := st.orgRepo.GetOrganisationByUUID(ctx, org.OrgUUID)
+			if err != nil {
+				return nil, err
+			}
+			org.AssignmentCursor = current.AssignmentCursor
+		}
+		next := nextRoundRobin(sorted, org.AssignmentCursor)
+		moved, err := st.orgRepo.SetAssignmentCursor(ctx, org.OrgUUID, org.AssignmentCursor, next.UId)
+		if err != nil {
+			return nil, err
+		}
+		if moved {
+			org.AssignmentCursor = next.UId
+			return next, nil
+		}
+	}
+	return nil, ErrAssignmentContended
+}
+
+// Release moves the cursor back before an assignee whose prospect was never stored, so the next
+// pick lands on them again. It leaves the cursor alone once a later pick has moved it on.
+func (st *roundRobinStrategy) Release(ctx context.Context, org *models.Organisation, assignee *models.UserResp, candidates []*models.UserResp) error {
+	_, err := st.orgRepo.SetAssignmentCursor(ctx, org.OrgUUID, assignee.UId, previousRoundRobin(sortedByUId(candidates), assignee.UId))
+	return err
+}
+
+func sortedByUId(candidates []*models.UserResp) []*models.UserResp {
+	sorted := append([]*models.UserResp(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UId < sorted[j].UId })
+	return sorted
+}
+
+// nextRoundRobin returns the first executive after the cursor, wrapping around to the first one
+func nextRoundRobin(sorted []*models.UserResp, cursor string) *models.UserResp {
+	for _, candidate := range sorted {
+		if candidate.UId > cursor {
+			return candidate
+		}
+	}
+	return sorted[0]
+}
+
+// previousRoundRobin returns a cursor from which nextRoundRobin picks uid again
+func previousRoundRobin(sorted []*models.UserResp, uid string) string {
+	prev := ""
+	for _, candidate := range sorted {
+		if candidate.UId >= uid {
+			break
+		}
+		prev = candidate.UId
+	}
+	return prev
+}
+
+// leastOpenCasesStrategy picks the executive holding the fewest prospects that are not yet closed
+type leastOpenCasesStrategy struct {
+	prospectRepo *repositories.ProspectRepositoryImpl
+}
+
+func (st *leastOpenCasesStrategy) Pick(ctx context.Context, org *models.Organisation, prospect *models.Prospect, candidates []*models.UserResp) (*models.UserResp, error) {
+	uids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		uids[i] = candidate.UId
+	}
+	counts, err := st.prospectRepo.CountByAssignee(ctx, org.OrgUUID, uids, closedProspectStatuses)
+	if err != nil {
+		return nil, err
+	}
+
+	return fewestOpenCases(candidates, counts), nil
+}
+
+// fewestOpenCases returns the executive with the lowest count, the lower UId winning ties
+func fewestOpenCases(candidates []*models.UserResp, counts map[string]int) *models.UserResp {
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if counts[candidate.UId] < counts[best.UId] ||
+			(counts[candidate.UId] == counts[best.UId] && candidate.UId < best.UId) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// nearestStrategy picks the executive whose base location is closest to the prospect's address,
+// falling back when the prospect or every executive lacks coordinates
+type nearestStrategy struct {
+	fallback AssignmentStrategy
+}
+
+func (st *nearestStrategy) Pick(ctx context.Context, org *models.Organisation, prospect *models.Prospect, candidates []*models.UserResp) (*models.UserResp, error) {
+	target := prospect.ResidentialLocation
+	if target == nil {
+		target = prospect.OfficeLocation
+	}
+	if target == nil {
+		return st.fallback.Pick(ctx, org, prospect, candidates)
+	}
+
+	if best := nearestTo(*target, candidates); best != nil {
+		return best, nil
+	}
+	return st.fallback.Pick(ctx, org, prospect, candidates)
+}
+
+// nearestTo returns the executive based closest to target, or nil when none has a base location
+func nearestTo(target models.GeoPoint, candidates []*models.UserResp) *models.UserResp {
+	var best *models.UserResp
+	bestDistance := math.Inf(1)
+	for _, candidate := range candidates {
+		if candidate.BaseLocation == nil {
+			continue
+		}
+		if distance := DistanceKm(target, *candidate.BaseLocation); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
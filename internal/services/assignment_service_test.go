@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+
+	"fverify_be/internal/models"
+)
+
+func executives(uids ...string) []*models.UserResp {
+	users := make([]*models.UserResp, len(uids))
+	for i, uid := range uids {
+		users[i] = &models.UserResp{UId: uid}
+	}
+	return users
+}
+
+func TestNextRoundRobin(t *testing.T) {
+	sorted := sortedByUId(executives("c", "a", "b"))
+	tests := []struct {
+		name   string
+		cursor string
+		want   string
+	}{
+		{"no cursor", "", "a"},
+		{"after first", "a", "b"},
+		{"after middle", "b", "c"},
+		{"wraps after last", "c", "a"},
+		{"cursor between executives", "aa", "b"},
+		{"cursor past every executive", "z", "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextRoundRobin(sorted, tt.cursor); got.UId != tt.want {
+				t.Errorf("nextRoundRobin(%q) = %s, want %s", tt.cursor, got.UId, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousRoundRobinPicksAgain(t *testing.T) {
+	sorted := sortedByUId(executives("a", "b", "c"))
+	for _, uid := range []string{"a", "b", "c"} {
+		t.Run(uid, func(t *testing.T) {
+			if got := nextRoundRobin(sorted, previousRoundRobin(sorted, uid)); got.UId != uid {
+				t.Errorf("after release of %s the next pick is %s", uid, got.UId)
+			}
+		})
+	}
+}
+
+func TestFewestOpenCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[string]int
+		want   string
+	}{
+		{"no open cases", map[string]int{}, "a"},
+		{"fewest wins", map[string]int{"a": 3, "b": 1, "c": 2}, "b"},
+		{"tie goes to lower uid", map[string]int{"a": 2, "b": 1, "c": 1}, "b"},
+		{"missing count is zero", map[string]int{"a": 1, "b": 1}, "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fewestOpenCases(executives("c", "b", "a"), tt.counts); got.UId != tt.want {
+				t.Errorf("fewestOpenCases() = %s, want %s", got.UId, tt.want)
+			}
+		})
+	}
+}
+
+func TestNearestTo(t *testing.T) {
+	bangalore := models.GeoPoint{Latitude: 12.97, Longitude: 77.59}
+	mysore := &models.GeoPoint{Latitude: 12.30, Longitude: 76.64}
+	chennai := &models.GeoPoint{Latitude: 13.08, Longitude: 80.27}
+	tests := []struct {
+		name       string
+		candidates []*models.UserResp
+		want       string
+	}{
+		{"closest wins", []*models.UserResp{{UId: "a", BaseLocation: chennai}, {UId: "b", BaseLocation: mysore}}, "b"},
+		{"executives without a base are skipped", []*models.UserResp{{UId: "a"}, {UId: "b", BaseLocation: chennai}}, "b"},
+		{"no base locations", []*models.UserResp{{UId: "a"}, {UId: "b"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nearestTo(bangalore, tt.candidates)
+			if (got == nil && tt.want != "") || (got != nil && got.UId != tt.want) {
+				t.Errorf("nearestTo() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+)
+
+// AuditService answers queries on the audit log. Events are written by the audit middleware.
+type AuditService struct {
+	repo *repositories.AuditRepositoryImpl
+}
+
+func NewAuditService(repo *repositories.AuditRepositoryImpl) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// GetEvents returns a page of an organisation's audit events matching the filter, newest first
+func (s *AuditService) GetEvents(ctx context.Context, orgUUID string, filter models.AuditFilter, page models.PageRequest) (*models.Page[models.AuditEvent], error) {
+	items, next, err := s.repo.Find(ctx, orgUUID, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.Page[models.AuditEvent]{Items: items, NextCursor: next}
+	if page.IncludeTotal {
+		total, err := s.repo.Count(ctx, orgUUID, filter)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+	return result, nil
+}
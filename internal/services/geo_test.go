@@ -0,0 +1,33 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"fverify_be/internal/models"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b models.GeoPoint
+		want float64
+	}{
+		{"same point", models.GeoPoint{Latitude: 12.97, Longitude: 77.59}, models.GeoPoint{Latitude: 12.97, Longitude: 77.59}, 0},
+		{"one degree of latitude", models.GeoPoint{Latitude: 0, Longitude: 0}, models.GeoPoint{Latitude: 1, Longitude: 0}, 111.19},
+		{"one degree of longitude at the equator", models.GeoPoint{Latitude: 0, Longitude: 0}, models.GeoPoint{Latitude: 0, Longitude: 1}, 111.19},
+		{"across the antimeridian", models.GeoPoint{Latitude: 0, Longitude: 179.5}, models.GeoPoint{Latitude: 0, Longitude: -179.5}, 111.19},
+		{"Bangalore to Chennai", models.GeoPoint{Latitude: 12.9716, Longitude: 77.5946}, models.GeoPoint{Latitude: 13.0827, Longitude: 80.2707}, 290.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceKm(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 0.5 {
+				t.Errorf("DistanceKm() = %.2f, want %.2f", got, tt.want)
+			}
+			if back := DistanceKm(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+				t.Errorf("DistanceKm() is not symmetric: %.6f and %.6f", got, back)
+			}
+		})
+	}
+}
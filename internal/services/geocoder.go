@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fverify_be/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrAddressNotFound is returned when the geocoder has no match for an address
+var ErrAddressNotFound = errors.New("address could not be geocoded")
+
+// Geocoder resolves a postal address to coordinates
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (*models.GeoPoint, error)
+}
+
+// NewGeocoderFromConfig returns the geocoder selected by geocoding.provider, or nil when geocoding is disabled
+func NewGeocoderFromConfig() Geocoder {
+	switch viper.GetString("geocoding.provider") {
+	case "nominatim":
+		baseURL := viper.GetString("geocoding.nominatim.url")
+		if baseURL == "" {
+			baseURL = "https://nominatim.openstreetmap.org"
+		}
+		return &NominatimGeocoder{
+			baseURL:   baseURL,
+			userAgent: viper.GetString("geocoding.nominatim.userAgent"),
+			client:    &http.Client{Timeout: 10 * time.Second},
+		}
+	default:
+		return nil
+	}
+}
+
+// NominatimGeocoder uses the OpenStreetMap Nominatim search API
+type NominatimGeocoder struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+}
+
+func (g *NominatimGeocoder) Geocode(ctx context.Context, address string) (*models.GeoPoint, error) {
+	query := url.Values{"q": {address}, "format": {"json"}, "limit": {"1"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.userAgent != "" {
+		req.Header.Set("User-Agent", g.userAgent)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("geocoder returned " + resp.Status)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrAddressNotFound
+	}
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return nil, err
+	}
+	lon, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &models.GeoPoint{Latitude: lat, Longitude: lon}, nil
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const defaultAnchorIntervalMinutes = 60
+
+// HistoryService verifies prospect history chains and periodically anchors their heads
+type HistoryService struct {
+	prospectRepo   *repositories.ProspectRepositoryImpl
+	anchorRepo     *repositories.HistoryAnchorRepositoryImpl
+	anchorInterval time.Duration
+}
+
+// NewHistoryService creates the history service. Chains are anchored every
+// history.anchorIntervalMinutes, or hourly when it is not configured.
+func NewHistoryService(prospectRepo *repositories.ProspectRepositoryImpl, anchorRepo *repositories.HistoryAnchorRepositoryImpl) *HistoryService {
+	minutes := viper.GetInt("history.anchorIntervalMinutes")
+	if minutes <= 0 {
+		minutes = defaultAnchorIntervalMinutes
+	}
+	return &HistoryService{
+		prospectRepo:   prospectRepo,
+		anchorRepo:     anchorRepo,
+		anchorInterval: time.Duration(minutes) * time.Minute,
+	}
+}
+
+// VerifyProspect recomputes a prospect's history chain and compares it with every anchor that recorded
+// the prospect. It reports the first entry that fails; entries written before chaining was introduced
+// may only precede the chain. The organisation's anchors from the first one that recorded the prospect
+// up to the newest are checked against their digests and each other, and, when publishedDigest is given,
+// the chain must run through the anchor with that digest.
+func (s *HistoryService) VerifyProspect(ctx context.Context, orgUUID string, uid string, publishedDigest string) (*models.HistoryVerification, error) {
+	prospect, err := s.prospectRepo.GetByID(ctx, orgUUID, uid)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+
+	// Anchors are read from the older of the prospect's first anchor and the published one
+	var since string
+	first, err := s.anchorRepo.GetFirstForProspect(ctx, orgUUID, uid)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	var published *models.HistoryAnchor
+	if publishedDigest != "" {
+		published, err = s.anchorRepo.GetByDigest(ctx, orgUUID, publishedDigest)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+	}
+	for _, anchor := range []*models.HistoryAnchor{first, published} {
+		if anchor != nil && (since == "" || anchor.Time < since) {
+			since = anchor.Time
+		}
+	}
+	var chain []models.HistoryAnchor
+	if since != "" {
+		if chain, err = s.anchorRepo.GetSince(ctx, orgUUID, since); err != nil {
+			return nil, err
+		}
+	}
+
+	history := prospect.UpdateHistory
+	result := &models.HistoryVerification{
+		ProspectUId: uid,
+		Entries:     len(history),
+		Head:        prospect.HistoryHead,
+	}
+	if len(chain) > 0 {
+		result.LatestDigest = chain[len(chain)-1].Digest
+	}
+	// The earliest failure wins, whichever check finds it
+	broken := func(index int, reason string) {
+		if result.BrokenLink != nil && result.BrokenLink.Index <= index {
+			return
+		}
+		result.BrokenLink = &models.HistoryBrokenLink{Index: index, Reason: reason}
+		if index < len(history) {
+			entry := history[index]
+			result.BrokenLink.Entry = &entry
+		}
+	}
+
+	prev := ""
+	for i, entry := range history {
+		if entry.Hash == "" {
+			if prev != "" {
+				broken(i, "entry is not sealed")
+				break
+			}
+			result.Unsealed++
+			continue
+		}
+		if entry.PrevHash != prev {
+			broken(i, "previous hash does not match the preceding entry")
+			break
+		}
+		if models.HistoryHash(uid, entry) != entry.Hash {
+			broken(i, "entry does not match its hash")
+			break
+		}
+		prev = entry.Hash
+	}
+	if result.BrokenLink == nil && prospect.HistoryHead != prev {
+		broken(len(history), "history does not end at the recorded chain head")
+	}
+
+	for _, anchor := range chain {
+		for _, head := range anchor.Heads {
+			if head.ProspectUId != uid {
+				continue
+			}
+			result.AnchorsChecked++
+			result.LastAnchorTime = anchor.Time
+			switch {
+			case head.Length > len(history):
+				broken(len(history), fmt.Sprintf("entries anchored at %s are missing", anchor.Time))
+			case head.Length > 0 && history[head.Length-1].Hash != head.Hash:
+				broken(head.Length-1, fmt.Sprintf("entry differs from the chain anchored at %s", anchor.Time))
+			}
+		}
+	}
+
+	result.BrokenAnchor = verifyAnchorChain(chain, publishedDigest, published != nil)
+	result.Valid = result.BrokenLink == nil && result.BrokenAnchor == nil
+	return result, nil
+}
+
+// verifyAnchorChain checks that each anchor matches its digest and points at the anchor before it, and
+// returns the first that does not. A chain that was rewritten as a whole still fails to reach a
+// published digest, so found tells whether the anchor with publishedDigest exists.
+func verifyAnchorChain(chain []models.HistoryAnchor, publishedDigest string, found bool) *models.HistoryBrokenAnchor {
+	for i := range chain {
+		anchor := &chain[i]
+		if anchorDigest(anchor) != anchor.Digest {
+			return &models.HistoryBrokenAnchor{AnchorId: anchor.AnchorId, Time: anchor.Time, Reason: "anchor does not match its digest"}
+		}
+		if i > 0 && anchor.PrevDigest != chain[i-1].Digest {
+			return &models.HistoryBrokenAnchor{AnchorId: anchor.AnchorId, Time: anchor.Time, Reason: "previous digest does not match the preceding anchor"}
+		}
+	}
+	if publishedDigest != "" && !found {
+		return &models.HistoryBrokenAnchor{Reason: "no anchor has the published digest"}
+	}
+	return nil
+}
+
+// AnchorOrganisation records the chain heads of the organisation's prospects that changed since its
+// previous anchor. It returns nil when nothing changed.
+func (s *HistoryService) AnchorOrganisation(ctx context.Context, orgUUID string) (*models.HistoryAnchor, error) {
+	prevDigest, since := "", ""
+	latest, err := s.anchorRepo.GetLatest(ctx, orgUUID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	if latest != nil {
+		prevDigest, since = latest.Digest, latest.Time
+	}
+
+	// Read the heads after taking the time, so a change made meanwhile is picked up again next time
+	now := time.Now().UTC().Format(time.RFC3339)
+	heads, err := s.prospectRepo.GetHistoryHeads(ctx, orgUUID, since)
+	if err != nil {
+		return nil, err
+	}
+	if len(heads) == 0 {
+		return nil, nil
+	}
+
+	anchor := &models.HistoryAnchor{
+		AnchorId:   uuid.New().String(),
+		OrgUUID:    orgUUID,
+		Time:       now,
+		PrevDigest: prevDigest,
+		Heads:      heads,
+	}
+	anchor.Digest = anchorDigest(anchor)
+	if err := s.anchorRepo.Insert(ctx, anchor); err != nil {
+		return nil, err
+	}
+	return anchor, nil
+}
+
+// anchorDigest hashes everything an anchor vouches for, including the digest of the anchor before it
+func anchorDigest(anchor *models.HistoryAnchor) string {
+	content, _ := json.Marshal(struct {
+		OrgUUID    string               `json:"org_uuid"`
+		Time       string               `json:"time"`
+		PrevDigest string               `json:"prev_digest"`
+		Heads      []models.HistoryHead `json:"heads"`
+	}{anchor.OrgUUID, anchor.Time, anchor.PrevDigest, anchor.Heads})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AnchorAll anchors every organisation that owns prospects, logging the new digests so they can be
+// copied out of the database
+func (s *HistoryService) AnchorAll(ctx context.Context) error {
+	orgUUIDs, err := s.prospectRepo.GetOrgUUIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, orgUUID := range orgUUIDs {
+		anchor, err := s.AnchorOrganisation(ctx, orgUUID)
+		if err != nil {
+			log.Printf("Failed to anchor prospect history of organisation %s: %v", orgUUID, err)
+			continue
+		}
+		if anchor != nil {
+			log.Printf("Anchored prospect history of organisation %s: %d prospects, digest %s", orgUUID, len(anchor.Heads), anchor.Digest)
+		}
+	}
+	return nil
+}
+
+// RunAnchoring anchors all organisations once and then at every interval until ctx is cancelled
+func (s *HistoryService) RunAnchoring(ctx context.Context) {
+	ticker := time.NewTicker(s.anchorInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.AnchorAll(ctx); err != nil {
+			log.Printf("Failed to anchor prospect history: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
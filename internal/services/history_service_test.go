@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+
+	"fverify_be/internal/models"
+)
+
+// anchorChain returns n correctly chained anchors of one organisation
+func anchorChain(n int) []models.HistoryAnchor {
+	chain := make([]models.HistoryAnchor, n)
+	prev := ""
+	for i := range chain {
+		chain[i] = models.HistoryAnchor{
+			AnchorId:   string(rune('a' + i)),
+			OrgUUID:    "org-1",
+			Time:       "2023-04-12T1" + string(rune('0'+i)) + ":00:00Z",
+			PrevDigest: prev,
+			Heads:      []models.HistoryHead{{ProspectUId: "prospect-1", Length: i + 1, Hash: "hash"}},
+		}
+		chain[i].Digest = anchorDigest(&chain[i])
+		prev = chain[i].Digest
+	}
+	return chain
+}
+
+func TestVerifyAnchorChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		change     func([]models.HistoryAnchor)
+		published  string
+		found      bool
+		wantAnchor string
+		wantReason string
+	}{
+		{"intact", func([]models.HistoryAnchor) {}, "", false, "", ""},
+		{"published digest found", func([]models.HistoryAnchor) {}, "digest", true, "", ""},
+		{"published digest missing", func([]models.HistoryAnchor) {}, "digest", false, "", "no anchor has the published digest"},
+		{"head rewritten", func(c []models.HistoryAnchor) { c[1].Heads[0].Hash = "forged" }, "", false, "b", "anchor does not match its digest"},
+		{"time rewritten", func(c []models.HistoryAnchor) { c[2].Time = "2023-04-12T19:00:00Z" }, "", false, "c", "anchor does not match its digest"},
+		{
+			"anchor resealed after rewrite", func(c []models.HistoryAnchor) {
+				c[1].Heads[0].Hash = "forged"
+				c[1].Digest = anchorDigest(&c[1])
+			}, "", false, "c", "previous digest does not match the preceding anchor",
+		},
+		{"anchor removed", func(c []models.HistoryAnchor) { copy(c[1:], c[2:]) }, "", false, "c", "previous digest does not match the preceding anchor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := anchorChain(4)
+			tt.change(chain)
+			broken := verifyAnchorChain(chain, tt.published, tt.found)
+			if tt.wantReason == "" {
+				if broken != nil {
+					t.Fatalf("verifyAnchorChain() = %+v, want nil", broken)
+				}
+				return
+			}
+			if broken == nil {
+				t.Fatalf("verifyAnchorChain() = nil, want %q", tt.wantReason)
+			}
+			if broken.AnchorId != tt.wantAnchor || broken.Reason != tt.wantReason {
+				t.Errorf("verifyAnchorChain() = %+v, want anchor %q with %q", broken, tt.wantAnchor, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrAccountLocked is returned for logins to an account locked after too many failed attempts
+	ErrAccountLocked = errors.New("account is temporarily locked after too many failed login attempts")
+	// ErrLoginThrottled is returned when a login is attempted before the backoff of earlier failures has passed
+	ErrLoginThrottled = errors.New("too many failed login attempts, try again later")
+)
+
+// LoginBlockedError wraps ErrAccountLocked or ErrLoginThrottled with the time until a login may be attempted again
+type LoginBlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string { return e.Err.Error() }
+
+func (e *LoginBlockedError) Unwrap() error { return e.Err }
+
+// LoginGuard limits failed logins per username within an organisation and per client IP. Each failure
+// doubles the wait before the next attempt; reaching the threshold locks the key for the lockout period.
+type LoginGuard struct {
+	attemptRepo      *repositories.LoginAttemptRepositoryImpl
+	userRepo         *repositories.UserRepositoryImpl
+	accountThreshold int
+	ipThreshold      int
+	backoffBase      time.Duration
+	backoffMax       time.Duration
+	lockout          time.Duration
+	window           time.Duration
+}
+
+// NewLoginGuard creates the login guard from auth.lockout
+func NewLoginGuard(attemptRepo *repositories.LoginAttemptRepositoryImpl, userRepo *repositories.UserRepositoryImpl) *LoginGuard {
+	return &LoginGuard{
+		attemptRepo:      attemptRepo,
+		userRepo:         userRepo,
+		accountThreshold: positiveInt("auth.lockout.accountThreshold", 5),
+		ipThreshold:      positiveInt("auth.lockout.ipThreshold", 20),
+		backoffBase:      time.Duration(positiveInt("auth.lockout.backoffBaseSeconds", 1)) * time.Second,
+		backoffMax:       time.Duration(positiveInt("auth.lockout.backoffMaxSeconds", 60)) * time.Second,
+		lockout:          time.Duration(positiveInt("auth.lockout.lockoutMinutes", 15)) * time.Minute,
+		window:           time.Duration(positiveInt("auth.lockout.windowMinutes", 15)) * time.Minute,
+	}
+}
+
+func positiveInt(key string, fallback int) int {
+	if value := viper.GetInt(key); value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func accountKey(orgUUID string, username string) string {
+	return "account:" + orgUUID + ":" + username
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns a LoginBlockedError when the account or the client IP may not attempt a login yet
+func (g *LoginGuard) Check(ctx context.Context, orgUUID string, username string, ip string) error {
+	now := time.Now().UTC()
+	for _, key := range []string{accountKey(orgUUID, username), ipKey(ip)} {
+		attempt, err := g.attemptRepo.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if attempt == nil {
+			continue
+		}
+		if lockedAt(attempt, now) {
+			if attempt.Scope == models.LoginAttemptAccount {
+				return &LoginBlockedError{Err: ErrAccountLocked, RetryAfter: attempt.LockedUntil.Sub(now)}
+			}
+			return &LoginBlockedError{Err: ErrLoginThrottled, RetryAfter: attempt.LockedUntil.Sub(now)}
+		}
+		if next := attempt.LastFailure.Add(g.backoff(attempt.Failures)); next.After(now) {
+			return &LoginBlockedError{Err: ErrLoginThrottled, RetryAfter: next.Sub(now)}
+		}
+	}
+	return nil
+}
+
+func lockedAt(attempt *models.LoginAttempt, now time.Time) bool {
+	return attempt.LockedUntil != nil && attempt.LockedUntil.After(now)
+}
+
+// backoff is the wait after the given number of consecutive failures: base, 2*base, 4*base... up to the maximum
+func (g *LoginGuard) backoff(failures int) time.Duration {
+	delay := g.backoffBase
+	for i := 1; i < failures && delay < g.backoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, g.backoffMax)
+}
+
+// RecordFailure counts a failed login against the account and the client IP, locking either once it
+// reaches its threshold. Account lockouts are recorded in the user's update history.
+func (g *LoginGuard) RecordFailure(ctx context.Context, orgUUID string, username string, ip string) error {
+	now := time.Now().UTC()
+	account, err := g.attemptRepo.RecordFailure(ctx, accountKey(orgUUID, username), models.LoginAttemptAccount, now, now.Add(g.window))
+	if err != nil {
+		return err
+	}
+	if account.Failures >= g.accountThreshold && !lockedAt(account, now) {
+		until := now.Add(g.lockout)
+		if err := g.attemptRepo.Lock(ctx, account.Key, until); err != nil {
+			return err
+		}
+		log.Printf("Locked login for user %s of organisation %s after %d failed attempts", username, orgUUID, account.Failures)
+		err := g.userRepo.AppendUpdateHistory(ctx, orgUUID, username, models.UpdateHistory{
+			UpdatedTime:     now.Format(time.RFC3339),
+			UpdatedComments: fmt.Sprintf("account locked until %s after %d failed login attempts, last from %s", until.Format(time.RFC3339), account.Failures, ip),
+			UpdateBy:        "system",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	client, err := g.attemptRepo.RecordFailure(ctx, ipKey(ip), models.LoginAttemptIP, now, now.Add(g.window))
+	if err != nil {
+		return err
+	}
+	if client.Failures >= g.ipThreshold && !lockedAt(client, now) {
+		log.Printf("Locked logins from %s after %d failed attempts", ip, client.Failures)
+		return g.attemptRepo.Lock(ctx, client.Key, now.Add(g.lockout))
+	}
+	return nil
+}
+
+// RecordSuccess clears the failed attempts of an account. The client IP keeps its count so that a valid
+// login cannot be used to reset guessing against other accounts.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, orgUUID string, username string) error {
+	return g.attemptRepo.Reset(ctx, accountKey(orgUUID, username))
+}
+
+// Unlock clears the failed attempts and any lockout of an account and records who unlocked it
+func (g *LoginGuard) Unlock(ctx context.Context, orgUUID string, username string, authUserName string) error {
+	if err := g.attemptRepo.Reset(ctx, accountKey(orgUUID, username)); err != nil {
+		return err
+	}
+	return g.userRepo.AppendUpdateHistory(ctx, orgUUID, username, models.UpdateHistory{
+		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
+		UpdatedComments: "account unlocked",
+		UpdateBy:        authUserName,
+	})
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fverify_be/internal/models"
+	"log"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/spf13/viper"
+)
+
+const (
+	exifCaptureLimit         = 256 << 10 // EXIF is an APP1 segment of at most 64 KB; the rest covers the segments before it
+	defaultMaxCaptureDistKm  = 0.5
+	defaultCaptureSkewMinute = 15
+)
+
+// prefixBuffer keeps the first limit bytes written to it and silently discards the rest
+type prefixBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// extractExif reads the capture time and GPS position of a JPEG. EXIF timestamps carry no zone,
+// so the wall-clock time is interpreted in media.exifTimezone (server local time when unset).
+func extractExif(data []byte) (*time.Time, *models.GeoPoint) {
+	x, err := exif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil
+	}
+
+	var captured *time.Time
+	if dt, err := x.DateTime(); err == nil {
+		loc := time.Local
+		if name := viper.GetString("media.exifTimezone"); name != "" {
+			if configured, err := time.LoadLocation(name); err == nil {
+				loc = configured
+			}
+		}
+		t := time.Date(dt.Year(), dt.Month(), dt.Day(), dt.Hour(), dt.Minute(), dt.Second(), 0, loc).UTC()
+		captured = &t
+	}
+
+	var position *models.GeoPoint
+	if lat, long, err := x.LatLong(); err == nil {
+		position = &models.GeoPoint{Latitude: lat, Longitude: long}
+	}
+	return captured, position
+}
+
+// targetLocation returns which address a media category is expected to be photographed at
+func targetLocation(prospect *models.Prospect, category models.MediaCategory) (field string, address string, location *models.GeoPoint) {
+	switch category {
+	case models.ResidenceFront, models.ResidenceInterior:
+		return "residential_location", prospect.ResidentialAddress, prospect.ResidentialLocation
+	case models.OfficeFront, models.OfficeInterior:
+		return "office_location", prospect.OfficeAddress, prospect.OfficeLocation
+	}
+	return "", "", nil
+}
+
+// assessEvidence compares a photo's EXIF capture time and position against the prospect's visit
+// window and address, filling in the capture fields and flags of the media record
+func (s *MediaService) assessEvidence(ctx context.Context, orgUUID string, prospect *models.Prospect, media *models.ProspectMedia, exifData []byte) {
+	// ID cards and documents are usually scans, so capture evidence is not meaningful for them
+	if media.ContentType != "image/jpeg" || media.Category == models.IDCard || media.Category == models.Document {
+		return
+	}
+
+	captured, position := extractExif(exifData)
+	s.judgeCapture(ctx, orgUUID, prospect, media, captured, position)
+}
+
+// judgeCapture records a photo's capture time and position on the media record and flags what is
+// missing or does not match the prospect
+func (s *MediaService) judgeCapture(ctx context.Context, orgUUID string, prospect *models.Prospect, media *models.ProspectMedia, captured *time.Time, position *models.GeoPoint) {
+	uploaded, _ := time.Parse(time.RFC3339, media.UploadedTime)
+
+	if captured == nil {
+		media.Flags = append(media.Flags, models.FlagNoCaptureTime)
+	} else {
+		media.CaptureTime = captured.Format(time.RFC3339)
+		if start, end := s.visitWindow(prospect, uploaded); captured.Before(start) || captured.After(end) {
+			media.Flags = append(media.Flags, models.FlagOutsideVisitWindow)
+		}
+	}
+
+	field, address, target := targetLocation(prospect, media.Category)
+	if position == nil {
+		media.Flags = append(media.Flags, models.FlagNoGeotag)
+		return
+	}
+	media.CaptureLocation = position
+	if field == "" {
+		return
+	}
+
+	if target == nil && address != "" && s.geocoder != nil {
+		geocoded, err := s.geocoder.Geocode(ctx, address)
+		if err != nil {
+			log.Printf("Failed to geocode %s of prospect %s: %v", field, prospect.UId, err)
+		} else if err := s.prospectRepo.SetLocation(ctx, orgUUID, prospect.UId, field, *geocoded); err != nil {
+			log.Printf("Failed to save %s of prospect %s: %v", field, prospect.UId, err)
+		} else {
+			target = geocoded
+		}
+	}
+	if target == nil {
+		media.Flags = append(media.Flags, models.FlagNoTargetLocation)
+		return
+	}
+
+	distance := DistanceKm(*position, *target)
+	media.DistanceKm = &distance
+	if distance > s.maxCaptureDistanceKm {
+		media.Flags = append(media.Flags, models.FlagLocationMismatch)
+	}
+}
+
+// visitWindow is the period in which field photos of a prospect are expected to be taken: from
+// the first visit check-in (or the assignment, or creation, when there is none) until the upload,
+// widened by the allowed clock skew
+func (s *MediaService) visitWindow(prospect *models.Prospect, uploaded time.Time) (time.Time, time.Time) {
+	startValue := prospect.AssignedTime
+	if len(prospect.Visits) > 0 {
+		startValue = prospect.Visits[0].CheckInTime
+	}
+	if startValue == "" {
+		startValue = prospect.CreatedTime
+	}
+	start, err := time.Parse(time.RFC3339, startValue)
+	if err != nil {
+		start = uploaded
+	}
+	return start.Add(-s.captureSkew), uploaded.Add(s.captureSkew)
+}
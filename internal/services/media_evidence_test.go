@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"fverify_be/internal/models"
+)
+
+func TestJudgeCapture(t *testing.T) {
+	s := &MediaService{maxCaptureDistanceKm: defaultMaxCaptureDistKm, captureSkew: defaultCaptureSkewMinute * time.Minute}
+	home := &models.GeoPoint{Latitude: 12.9716, Longitude: 77.5946}
+	nearHome := &models.GeoPoint{Latitude: 12.9730, Longitude: 77.5950}
+	farAway := &models.GeoPoint{Latitude: 13.0827, Longitude: 80.2707}
+	at := func(value string) *time.Time {
+		parsed, _ := time.Parse(time.RFC3339, value)
+		return &parsed
+	}
+
+	tests := []struct {
+		name      string
+		category  models.MediaCategory
+		location  *models.GeoPoint
+		captured  *time.Time
+		position  *models.GeoPoint
+		wantFlags []models.MediaFlag
+	}{
+		{"matching photo", models.ResidenceFront, home, at("2023-04-12T11:00:00Z"), nearHome, nil},
+		{"no exif", models.ResidenceFront, home, nil, nil, []models.MediaFlag{models.FlagNoCaptureTime, models.FlagNoGeotag}},
+		{"taken before the visit", models.ResidenceFront, home, at("2023-04-12T09:00:00Z"), nearHome, []models.MediaFlag{models.FlagOutsideVisitWindow}},
+		{"taken within the clock skew", models.ResidenceFront, home, at("2023-04-12T09:50:00Z"), nearHome, nil},
+		{"taken after the upload", models.ResidenceFront, home, at("2023-04-12T13:00:00Z"), nearHome, []models.MediaFlag{models.FlagOutsideVisitWindow}},
+		{"taken elsewhere", models.ResidenceFront, home, at("2023-04-12T11:00:00Z"), farAway, []models.MediaFlag{models.FlagLocationMismatch}},
+		{"address never located", models.ResidenceFront, nil, at("2023-04-12T11:00:00Z"), nearHome, []models.MediaFlag{models.FlagNoTargetLocation}},
+		{"other address is not compared", models.OfficeFront, nil, at("2023-04-12T11:00:00Z"), farAway, []models.MediaFlag{models.FlagNoTargetLocation}},
+		{"category without an address", models.OtherMedia, nil, at("2023-04-12T11:00:00Z"), farAway, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prospect := &models.Prospect{
+				UId:                 "prospect-1",
+				ResidentialLocation: tt.location,
+				AssignedTime:        "2023-04-12T10:00:00Z",
+			}
+			media := &models.ProspectMedia{Category: tt.category, UploadedTime: "2023-04-12T12:00:00Z"}
+			s.judgeCapture(context.Background(), "org-1", prospect, media, tt.captured, tt.position)
+
+			if !reflect.DeepEqual(media.Flags, tt.wantFlags) {
+				t.Errorf("Flags = %v, want %v", media.Flags, tt.wantFlags)
+			}
+			if (media.CaptureTime != "") != (tt.captured != nil) || (media.CaptureLocation != nil) != (tt.position != nil) {
+				t.Errorf("capture fields = %q, %v, want them recorded when present", media.CaptureTime, media.CaptureLocation)
+			}
+		})
+	}
+}
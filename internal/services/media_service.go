@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"fverify_be/internal/storage"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrMediaNotFound is returned when the file does not belong to the prospect
+	ErrMediaNotFound = errors.New("media not found")
+	// ErrMediaTooLarge is returned when an upload exceeds media.maxSizeBytes
+	ErrMediaTooLarge = errors.New("file exceeds the maximum upload size")
+	// ErrUnsupportedMediaType is returned when the detected content type is not allowed
+	ErrUnsupportedMediaType = errors.New("file type is not allowed, upload a JPEG, PNG or PDF")
+	// ErrInvalidMediaCategory is returned for an unknown media category
+	ErrInvalidMediaCategory = errors.New("invalid media category")
+)
+
+const (
+	defaultMaxMediaSize    = 10 << 20 // 10 MB
+	defaultMaxMediaRequest = 50 << 20 // 50 MB
+)
+
+// allowedMediaTypes are the content types accepted for upload, detected from the file content
+var allowedMediaTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"application/pdf": true,
+}
+
+var mediaCategories = map[models.MediaCategory]bool{
+	models.ResidenceFront:    true,
+	models.ResidenceInterior: true,
+	models.OfficeFront:       true,
+	models.OfficeInterior:    true,
+	models.IDCard:            true,
+	models.Selfie:            true,
+	models.Document:          true,
+	models.OtherMedia:        true,
+}
+
+type MediaService struct {
+	prospectRepo         *repositories.ProspectRepositoryImpl
+	storage              storage.Storage
+	geocoder             Geocoder
+	maxSize              int64
+	maxRequestSize       int64
+	maxCaptureDistanceKm float64
+	captureSkew          time.Duration
+}
+
+// NewMediaService creates the media service; geocoder may be nil to disable address geocoding
+func NewMediaService(prospectRepo *repositories.ProspectRepositoryImpl, store storage.Storage, geocoder Geocoder) *MediaService {
+	maxSize := viper.GetInt64("media.maxSizeBytes")
+	if maxSize <= 0 {
+		maxSize = defaultMaxMediaSize
+	}
+	maxRequestSize := viper.GetInt64("media.maxRequestBytes")
+	if maxRequestSize <= 0 {
+		maxRequestSize = defaultMaxMediaRequest
+	}
+	maxDistance := viper.GetFloat64("media.maxCaptureDistanceKm")
+	if maxDistance <= 0 {
+		maxDistance = defaultMaxCaptureDistKm
+	}
+	skewMinutes := viper.GetInt("media.captureSkewMinutes")
+	if skewMinutes <= 0 {
+		skewMinutes = defaultCaptureSkewMinute
+	}
+	return &MediaService{
+		prospectRepo:         prospectRepo,
+		storage:              store,
+		geocoder:             geocoder,
+		maxSize:              maxSize,
+		maxRequestSize:       maxRequestSize,
+		maxCaptureDistanceKm: maxDistance,
+		captureSkew:          time.Duration(skewMinutes) * time.Minute,
+	}
+}
+
+// MaxRequestSize is the largest upload request accepted, covering all of its files together
+func (s *MediaService) MaxRequestSize() int64 {
+	return s.maxRequestSize
+}
+
+// Upload validates and stores a file for a prospect and records its metadata
+func (s *MediaService) Upload(ctx context.Context, orgUUID string, prospectUId string, category models.MediaCategory, fileName string, r io.Reader, size int64, actor string) (*models.ProspectMedia, error) {
+	if !mediaCategories[category] {
+		return nil, ErrInvalidMediaCategory
+	}
+	if size > s.maxSize {
+		return nil, ErrMediaTooLarge
+	}
+	prospect, err := s.prospectRepo.GetByID(ctx, orgUUID, prospectUId)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+
+	// Sniff the content type from the first bytes rather than trusting the client's header
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !allowedMediaTypes[contentType] {
+		return nil, ErrUnsupportedMediaType
+	}
+
+	media := models.ProspectMedia{
+		UId:          uuid.New().String(),
+		FileName:     path.Base(fileName),
+		Category:     category,
+		ContentType:  contentType,
+		Size:         size,
+		UploadedBy:   actor,
+		UploadedTime: time.Now().UTC().Format(time.RFC3339),
+	}
+	media.StorageKey = orgUUID + "/" + prospectUId + "/" + media.UId
+
+	hasher := sha256.New()
+	exifData := &prefixBuffer{limit: exifCaptureLimit}
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), io.LimitReader(r, s.maxSize-int64(n))), io.MultiWriter(hasher, exifData))
+	if err := s.storage.Put(ctx, media.StorageKey, body, size, contentType); err != nil {
+		return nil, err
+	}
+	media.Checksum = hex.EncodeToString(hasher.Sum(nil))
+	s.assessEvidence(ctx, orgUUID, prospect, &media, exifData.Bytes())
+
+	err = s.prospectRepo.AddMedia(ctx, orgUUID, prospectUId, media, models.UpdateHistory{
+		UpdatedTime:     media.UploadedTime,
+		UpdatedComments: "Uploaded " + string(category) + " file '" + media.FileName + "'",
+		UpdateBy:        actor,
+	})
+	if err != nil {
+		// Do not leave an orphaned object behind when the metadata could not be saved
+		_ = s.storage.Delete(ctx, media.StorageKey)
+		return nil, err
+	}
+	return &media, nil
+}
+
+// ListMedia returns the metadata of every file uploaded for a prospect
+func (s *MediaService) ListMedia(ctx context.Context, orgUUID string, prospectUId string) ([]models.ProspectMedia, error) {
+	prospect, err := s.prospectRepo.GetByID(ctx, orgUUID, prospectUId)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+	if prospect.Media == nil {
+		return []models.ProspectMedia{}, nil
+	}
+	return prospect.Media, nil
+}
+
+// OpenMedia returns a file's metadata and a reader over its content; the caller must close the reader
+func (s *MediaService) OpenMedia(ctx context.Context, orgUUID string, prospectUId string, mediaUId string) (*models.ProspectMedia, io.ReadCloser, error) {
+	media, err := s.findMedia(ctx, orgUUID, prospectUId, mediaUId)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.storage.Get(ctx, media.StorageKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return media, content, nil
+}
+
+// DeleteMedia removes a file from storage and from the prospect, and returns its metadata
+func (s *MediaService) DeleteMedia(ctx context.Context, orgUUID string, prospectUId string, mediaUId string, actor string) (*models.ProspectMedia, error) {
+	media, err := s.findMedia(ctx, orgUUID, prospectUId, mediaUId)
+	if err != nil {
+		return nil, err
+	}
+	err = s.prospectRepo.RemoveMedia(ctx, orgUUID, prospectUId, mediaUId, models.UpdateHistory{
+		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
+		UpdatedComments: "Deleted " + string(media.Category) + " file '" + media.FileName + "'",
+		UpdateBy:        actor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.Delete(ctx, media.StorageKey); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+func (s *MediaService) findMedia(ctx context.Context, orgUUID string, prospectUId string, mediaUId string) (*models.ProspectMedia, error) {
+	prospect, err := s.prospectRepo.GetByID(ctx, orgUUID, prospectUId)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+	for i := range prospect.Media {
+		if prospect.Media[i].UId == mediaUId {
+			return &prospect.Media[i], nil
+		}
+	}
+	return nil, ErrMediaNotFound
+}
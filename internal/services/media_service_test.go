@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"fverify_be/internal/models"
+)
+
+func TestUploadRejectsBeforeReading(t *testing.T) {
+	s := &MediaService{maxSize: 10}
+	tests := []struct {
+		name     string
+		category models.MediaCategory
+		size     int64
+		want     error
+	}{
+		{"unknown category", models.MediaCategory("Holiday"), 1, ErrInvalidMediaCategory},
+		{"too large", models.ResidenceFront, 11, ErrMediaTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Upload(context.Background(), "org-1", "prospect-1", tt.category, "photo.jpg", strings.NewReader("x"), tt.size, "field_exec")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Upload() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
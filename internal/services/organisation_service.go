@@ -26,8 +26,22 @@ func (s *OrganisationService) UpdateOrganisation(ctx context.Context, org_id str
 func (s *OrganisationService) DeleteOrganisation(ctx context.Context, org_id string) error {
 	return s.repo.Delete(ctx, org_id)
 }
-func (s *OrganisationService) GetAllOrganisations(ctx context.Context) ([]*models.Organisation, error) {
-	return s.repo.GetAllOrganisations(ctx)
+
+// GetAllOrganisations returns a page of organisations, with the total when requested
+func (s *OrganisationService) GetAllOrganisations(ctx context.Context, page models.PageRequest) (*models.Page[models.Organisation], error) {
+	items, next, err := s.repo.GetAllOrganisations(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.Page[models.Organisation]{Items: items, NextCursor: next}
+	if page.IncludeTotal {
+		total, err := s.repo.CountOrganisations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+	return result, nil
 }
 func (s *OrganisationService) IsOrgActive(ctx context.Context, org_id string) (bool, *models.Organisation) {
 	return s.repo.IsOrgActive(ctx, org_id)
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"fverify_be/internal/sms"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrInvalidOTP is returned for wrong, expired or already used SMS login codes, and for numbers that
+	// do not belong to a user who may log in by SMS
+	ErrInvalidOTP = errors.New("invalid or expired login code")
+	// ErrOTPRateLimited is returned when a login code is requested too soon after the previous ones
+	ErrOTPRateLimited = errors.New("too many login codes requested, try again later")
+	// ErrOTPLoginDisabled is returned while no SMS backend is configured
+	ErrOTPLoginDisabled = errors.New("SMS login is not enabled")
+)
+
+const (
+	otpDigits          = 6
+	otpSendWindow      = time.Hour
+	otpSentMessage     = "If the number is registered, a login code has been sent"
+	defaultOTPLoginApp = "FVerify"
+)
+
+// OTPLoginService logs users in with a code sent by SMS to their mobile number
+type OTPLoginService struct {
+	userRepo       *repositories.UserRepositoryImpl
+	guard          *LoginGuard
+	sender         sms.Sender
+	roles          []models.Role
+	ttl            time.Duration
+	resendInterval time.Duration
+	maxSends       int
+	maxAttempts    int
+	appName        string
+}
+
+// NewOTPLoginService creates the SMS login service from auth.otpLogin. Only users holding one of
+// auth.otpLogin.roles, Field Executives by default, may log in by SMS. Without a sender SMS login is
+// disabled.
+func NewOTPLoginService(userRepo *repositories.UserRepositoryImpl, guard *LoginGuard, sender sms.Sender) *OTPLoginService {
+	roles := []models.Role{models.FieldExecutive}
+	if configured := viper.GetStringSlice("auth.otpLogin.roles"); len(configured) > 0 {
+		roles = roles[:0]
+		for _, role := range configured {
+			roles = append(roles, models.Role(role))
+		}
+	}
+	appName := viper.GetString("auth.otpLogin.appName")
+	if appName == "" {
+		appName = defaultOTPLoginApp
+	}
+	return &OTPLoginService{
+		userRepo:       userRepo,
+		guard:          guard,
+		sender:         sender,
+		roles:          roles,
+		ttl:            time.Duration(positiveInt("auth.otpLogin.ttlMinutes", 5)) * time.Minute,
+		resendInterval: time.Duration(positiveInt("auth.otpLogin.resendSeconds", 60)) * time.Second,
+		maxSends:       positiveInt("auth.otpLogin.maxSendsPerHour", 5),
+		maxAttempts:    positiveInt("auth.otpLogin.maxAttempts", 5),
+		appName:        appName,
+	}
+}
+
+// lookup returns the user who may log in by SMS with a mobile number, or nil when there is none.
+// A number shared by several such users cannot be used to log in.
+func (s *OTPLoginService) lookup(ctx context.Context, orgUUID string, mobileNumber string) (*models.User, error) {
+	users, err := s.userRepo.GetByMobileNumber(ctx, orgUUID, strings.TrimSpace(mobileNumber), s.roles)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) > 1 {
+		log.Printf("Refused SMS login for %s in organisation %s: the number belongs to %d users", mobileNumber, orgUUID, len(users))
+	}
+	if len(users) != 1 || users[0].Status == models.InActive {
+		return nil, nil
+	}
+	return users[0], nil
+}
+
+// RequestCode sends a login code to a mobile number. The response is the same whether or not the number
+// belongs to a user, but resends are refused with a LoginBlockedError until the resend interval has passed
+// or while the hourly limit is reached, as are requests for locked accounts.
+func (s *OTPLoginService) RequestCode(ctx context.Context, orgUUID string, mobileNumber string, ip string) (*models.OTPSentResponse, error) {
+	if s.sender == nil {
+		return nil, ErrOTPLoginDisabled
+	}
+	response := &models.OTPSentResponse{Message: otpSentMessage, ExpiresIn: int(s.ttl.Seconds())}
+	user, err := s.lookup(ctx, orgUUID, mobileNumber)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return response, nil
+	}
+	if err := s.guard.Check(ctx, orgUUID, user.Username, ip); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	otp := &models.LoginOTP{WindowStart: now}
+	if previous := user.LoginOTP; previous != nil {
+		if next := previous.LastSent.Add(s.resendInterval); next.After(now) {
+			return nil, &LoginBlockedError{Err: ErrOTPRateLimited, RetryAfter: next.Sub(now)}
+		}
+		if end := previous.WindowStart.Add(otpSendWindow); end.After(now) {
+			if previous.Sends >= s.maxSends {
+				return nil, &LoginBlockedError{Err: ErrOTPRateLimited, RetryAfter: end.Sub(now)}
+			}
+			otp.WindowStart, otp.Sends = previous.WindowStart, previous.Sends
+		}
+	}
+
+	code, err := newOTPCode()
+	if err != nil {
+		return nil, err
+	}
+	otp.CodeHash = hashOneTimeCode(code)
+	otp.ExpiresAt = now.Add(s.ttl)
+	otp.LastSent = now
+	otp.Sends++
+	if err := s.userRepo.SetLoginOTP(ctx, orgUUID, user.UId, otp); err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf("%s is your %s login code. It expires in %d minutes. Do not share it with anyone.", code, s.appName, int(s.ttl.Minutes()))
+	if err := s.sender.Send(ctx, user.MobileNumber, message); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// VerifyCode checks a login code and returns the user it was sent to. Wrong codes count as failed logins,
+// and a code stops working after the maximum number of wrong guesses.
+func (s *OTPLoginService) VerifyCode(ctx context.Context, orgUUID string, mobileNumber string, code string, ip string) (*models.User, error) {
+	if s.sender == nil {
+		return nil, ErrOTPLoginDisabled
+	}
+	user, err := s.lookup(ctx, orgUUID, mobileNumber)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidOTP
+	}
+	if err := s.guard.Check(ctx, orgUUID, user.Username, ip); err != nil {
+		return nil, err
+	}
+
+	codeHash := hashOneTimeCode(code)
+	if !s.validCode(user.LoginOTP, codeHash) {
+		if user.LoginOTP != nil && user.LoginOTP.CodeHash != "" {
+			if err := s.userRepo.RecordLoginOTPFailure(ctx, orgUUID, user.UId); err != nil {
+				log.Printf("Failed to record wrong login code for %s: %v", user.Username, err)
+			}
+		}
+		if guardErr := s.guard.RecordFailure(ctx, orgUUID, user.Username, ip); guardErr != nil {
+			log.Printf("Failed to record failed SMS login for %s: %v", user.Username, guardErr)
+		}
+		return nil, ErrInvalidOTP
+	}
+	used, err := s.userRepo.UseLoginOTP(ctx, orgUUID, user.UId, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidOTP
+	}
+	if err := s.guard.RecordSuccess(ctx, orgUUID, user.Username); err != nil {
+		log.Printf("Failed to clear failed logins for %s: %v", user.Username, err)
+	}
+	return user, nil
+}
+
+func (s *OTPLoginService) validCode(otp *models.LoginOTP, codeHash string) bool {
+	if otp == nil || otp.CodeHash == "" || otp.Attempts >= s.maxAttempts || time.Now().UTC().After(otp.ExpiresAt) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(codeHash), []byte(otp.CodeHash)) == 1
+}
+
+// newOTPCode returns a random numeric code that is easy to type on a phone
+func newOTPCode() (string, error) {
+	limit := big.NewInt(1)
+	for range otpDigits {
+		limit.Mul(limit, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n), nil
+}
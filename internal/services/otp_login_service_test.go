@@ -0,0 +1,113 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"fverify_be/internal/models"
+
+	"github.com/spf13/viper"
+)
+
+// withCodeHashKey runs a test with codeHashKey set to key
+func withCodeHashKey(t *testing.T, key string) {
+	previous := codeHashKey
+	codeHashKey = []byte(key)
+	t.Cleanup(func() { codeHashKey = previous })
+}
+
+func TestLoadCodeHashKey(t *testing.T) {
+	t.Cleanup(func() { viper.Set("auth.codeHashSecret", nil) })
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"missing", "", true},
+		{"too short", "0123456789abcdef0123456789abcde", true},
+		{"long enough", "0123456789abcdef0123456789abcdef", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withCodeHashKey(t, "")
+			viper.Set("auth.codeHashSecret", tt.secret)
+			if err := LoadCodeHashKey(); (err != nil) != tt.wantErr {
+				t.Errorf("LoadCodeHashKey() error = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashOneTimeCode(t *testing.T) {
+	withCodeHashKey(t, "0123456789abcdef0123456789abcdef")
+	base := hashOneTimeCode("ABCD-EFGH")
+	tests := []struct {
+		name string
+		code string
+		same bool
+	}{
+		{"lower case", "abcd-efgh", true},
+		{"without dash", "ABCDEFGH", true},
+		{"with spaces", " ABCD EFGH ", true},
+		{"other code", "ABCD-EFGI", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hashOneTimeCode(tt.code); (got == base) != tt.same {
+				t.Errorf("hashOneTimeCode(%q) == hashOneTimeCode(%q) is %v, want %v", tt.code, "ABCD-EFGH", got == base, tt.same)
+			}
+		})
+	}
+
+	t.Run("other key", func(t *testing.T) {
+		withCodeHashKey(t, "fedcba9876543210fedcba9876543210")
+		if hashOneTimeCode("ABCD-EFGH") == base {
+			t.Errorf("hash does not depend on the key")
+		}
+	})
+}
+
+func TestValidCode(t *testing.T) {
+	withCodeHashKey(t, "0123456789abcdef0123456789abcdef")
+	s := &OTPLoginService{maxAttempts: 3}
+	live := func(change func(*models.LoginOTP)) *models.LoginOTP {
+		otp := &models.LoginOTP{CodeHash: hashOneTimeCode("123456"), ExpiresAt: time.Now().UTC().Add(time.Minute)}
+		change(otp)
+		return otp
+	}
+	tests := []struct {
+		name string
+		otp  *models.LoginOTP
+		code string
+		want bool
+	}{
+		{"right code", live(func(*models.LoginOTP) {}), "123456", true},
+		{"wrong code", live(func(*models.LoginOTP) {}), "123457", false},
+		{"below the attempt limit", live(func(o *models.LoginOTP) { o.Attempts = 2 }), "123456", true},
+		{"attempts exhausted", live(func(o *models.LoginOTP) { o.Attempts = 3 }), "123456", false},
+		{"expired", live(func(o *models.LoginOTP) { o.ExpiresAt = time.Now().UTC().Add(-time.Second) }), "123456", false},
+		{"already used", live(func(o *models.LoginOTP) { o.CodeHash = "" }), "123456", false},
+		{"never sent", nil, "123456", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.validCode(tt.otp, hashOneTimeCode(tt.code)); got != tt.want {
+				t.Errorf("validCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOTPCode(t *testing.T) {
+	digits := regexp.MustCompile(`^[0-9]{6}$`)
+	for range 20 {
+		code, err := newOTPCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !digits.MatchString(code) {
+			t.Errorf("newOTPCode() = %q, want %d digits", code, otpDigits)
+		}
+	}
+}
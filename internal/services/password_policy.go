@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"log"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// ErrWeakPassword is returned, wrapped in a PasswordPolicyError, for passwords that break the policy
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// maxPasswordBytes is the longest password bcrypt accepts
+const maxPasswordBytes = 72
+
+// ErrInvalidPasswordPolicy is returned for password policies with out of range settings
+var ErrInvalidPasswordPolicy = fmt.Errorf("password policy must have a minimum length of 1 to %d and a history size of 0 to %d", maxPasswordBytes, repositories.MaxPasswordHistory)
+
+// PasswordPolicyError lists every rule a password does not meet
+type PasswordPolicyError struct {
+	Violations []models.PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string { return ErrWeakPassword.Error() }
+
+func (e *PasswordPolicyError) Unwrap() error { return ErrWeakPassword }
+
+//go:embed common_passwords.txt
+var commonPasswords []byte
+
+// defaultPasswordPolicy applies to organisations without their own policy unless auth.passwordPolicy is configured
+var defaultPasswordPolicy = models.PasswordPolicy{
+	MinLength:    8,
+	RequireUpper: true,
+	RequireLower: true,
+	RequireDigit: true,
+	HistorySize:  5,
+}
+
+// PasswordPolicies resolves and applies the password policy of an organisation
+type PasswordPolicies struct {
+	orgRepo  *repositories.OrganisationRepositoryImpl
+	fallback models.PasswordPolicy
+	denylist map[string]bool
+}
+
+// NewPasswordPolicies loads the default policy from auth.passwordPolicy and the denylist of common
+// passwords, extended by the file at auth.passwordDenylistFile if set
+func NewPasswordPolicies(orgRepo *repositories.OrganisationRepositoryImpl) *PasswordPolicies {
+	p := &PasswordPolicies{orgRepo: orgRepo, fallback: defaultPasswordPolicy, denylist: map[string]bool{}}
+	if viper.IsSet("auth.passwordPolicy") {
+		p.fallback = models.PasswordPolicy{
+			MinLength:     viper.GetInt("auth.passwordPolicy.min_length"),
+			RequireUpper:  viper.GetBool("auth.passwordPolicy.require_upper"),
+			RequireLower:  viper.GetBool("auth.passwordPolicy.require_lower"),
+			RequireDigit:  viper.GetBool("auth.passwordPolicy.require_digit"),
+			RequireSymbol: viper.GetBool("auth.passwordPolicy.require_symbol"),
+			HistorySize:   viper.GetInt("auth.passwordPolicy.history_size"),
+		}
+		if err := ValidatePasswordPolicy(&p.fallback); err != nil {
+			log.Printf("Ignoring invalid auth.passwordPolicy: %v", err)
+			p.fallback = defaultPasswordPolicy
+		}
+	}
+	p.addDenylist(commonPasswords)
+	if file := viper.GetString("auth.passwordDenylistFile"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Failed to read password denylist %s: %v", file, err)
+		} else {
+			p.addDenylist(data)
+		}
+	}
+	return p
+}
+
+func (p *PasswordPolicies) addDenylist(data []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			p.denylist[strings.ToLower(word)] = true
+		}
+	}
+}
+
+// ValidatePasswordPolicy checks that the settings of a policy are in range
+func ValidatePasswordPolicy(policy *models.PasswordPolicy) error {
+	if policy.MinLength < 1 || policy.MinLength > maxPasswordBytes || policy.HistorySize < 0 || policy.HistorySize > repositories.MaxPasswordHistory {
+		return ErrInvalidPasswordPolicy
+	}
+	return nil
+}
+
+// Policy returns the password policy of an organisation
+func (p *PasswordPolicies) Policy(ctx context.Context, orgUUID string) (models.PasswordPolicy, error) {
+	org, err := p.orgRepo.GetOrganisationByUUID(ctx, orgUUID)
+	if err != nil {
+		return models.PasswordPolicy{}, err
+	}
+	if org.PasswordPolicy != nil {
+		return *org.PasswordPolicy, nil
+	}
+	return p.fallback, nil
+}
+
+// Check returns a PasswordPolicyError when a password breaks the organisation's policy. previousHashes
+// are the user's recent password hashes, newest last; pass nil for new users.
+func (p *PasswordPolicies) Check(ctx context.Context, orgUUID string, password string, previousHashes []string) error {
+	policy, err := p.Policy(ctx, orgUUID)
+	if err != nil {
+		return err
+	}
+
+	var violations []models.PasswordViolation
+	violate := func(rule string, message string) {
+		violations = append(violations, models.PasswordViolation{Rule: rule, Message: message})
+	}
+
+	if len([]rune(password)) < policy.MinLength {
+		violate("min_length", fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		violate("max_length", fmt.Sprintf("must be at most %d bytes long", maxPasswordBytes))
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		violate("uppercase", "must contain an upper-case letter")
+	}
+	if policy.RequireLower && !lower {
+		violate("lowercase", "must contain a lower-case letter")
+	}
+	if policy.RequireDigit && !digit {
+		violate("digit", "must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		violate("symbol", "must contain a symbol")
+	}
+	if p.denylist[strings.ToLower(password)] {
+		violate("denylist", "is too common")
+	}
+	if reusesPassword(password, previousHashes, policy.HistorySize) {
+		violate("reuse", fmt.Sprintf("must differ from the last %d passwords", policy.HistorySize))
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// passwordHashes returns the recent password hashes of a user, including the current one for users
+// whose history predates password policies
+func passwordHashes(user *models.User) []string {
+	if len(user.PasswordHistory) == 0 {
+		return []string{user.Password}
+	}
+	return user.PasswordHistory
+}
+
+// reusesPassword reports whether the password matches one of the newest historySize hashes
+func reusesPassword(password string, hashes []string, historySize int) bool {
+	if historySize <= 0 {
+		return false
+	}
+	if len(hashes) > historySize {
+		hashes = hashes[len(hashes)-historySize:]
+	}
+	for _, hash := range hashes {
+		if repositories.CheckPassword(hash, password) == nil {
+			return true
+		}
+	}
+	return false
+}
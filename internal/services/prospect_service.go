@@ -2,40 +2,216 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fverify_be/internal/models"
 	"fverify_be/internal/repositories"
+	"log"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrProspectNotFound is returned when the prospect does not exist in the caller's organisation
+	ErrProspectNotFound = errors.New("prospect not found")
+	// ErrInvalidAssignee is returned when the assignee is not an active field executive of the organisation
+	ErrInvalidAssignee = errors.New("assignee must be an active field executive of your organisation")
+	// ErrAlreadyAssigned is returned when assigning a prospect that already has an assignee
+	ErrAlreadyAssigned = errors.New("prospect is already assigned, use reassign instead")
+	// ErrNotAssigned is returned when reassigning or unassigning a prospect with no assignee
+	ErrNotAssigned = errors.New("prospect is not assigned")
+	// ErrProspectClosed is returned when editing a prospect whose verification has been decided or cancelled
+	ErrProspectClosed = errors.New("prospect can not be edited once it is Approved, Rejected, Completed or Cancelled")
 )
 
 type ProspectService struct {
-	repo *repositories.ProspectRepositoryImpl
+	repo              *repositories.ProspectRepositoryImpl
+	userRepo          *repositories.UserRepositoryImpl
+	assignmentService *AssignmentService
 }
 
-func NewProspectService(repo *repositories.ProspectRepositoryImpl) *ProspectService {
-	return &ProspectService{repo: repo}
+func NewProspectService(repo *repositories.ProspectRepositoryImpl, userRepo *repositories.UserRepositoryImpl, assignmentService *AssignmentService) *ProspectService {
+	return &ProspectService{repo: repo, userRepo: userRepo, assignmentService: assignmentService}
 }
 
 func (s *ProspectService) CreateProspect(ctx context.Context, prospect *models.Prospect) error {
-	return s.repo.Create(ctx, prospect)
+	// Auto-assignment is best effort; the prospect is still created unassigned if it fails
+	_, release, err := s.assignmentService.AutoAssign(ctx, prospect, "System")
+	if err != nil {
+		log.Printf("Auto-assignment skipped for prospect %s: %v", prospect.UId, err)
+	}
+	if err := s.repo.Create(ctx, prospect); err != nil {
+		release(ctx)
+		return err
+	}
+	return nil
+}
+
+// AutoAssignProspects runs the organisation's assignment strategy over unassigned prospects
+func (s *ProspectService) AutoAssignProspects(ctx context.Context, orgUUID string, uids []string, actor string) ([]*models.Prospect, error) {
+	return s.assignmentService.AssignBatch(ctx, orgUUID, uids, actor)
+}
+
+func (s *ProspectService) GetProspectByID(ctx context.Context, orgUUID string, id string) (*models.Prospect, error) {
+	return s.repo.GetByID(ctx, orgUUID, id)
 }
 
-func (s *ProspectService) GetProspectByID(ctx context.Context, id string) (*models.Prospect, error) {
-	return s.repo.GetByID(ctx, id)
+func (s *ProspectService) UpdateProspect(ctx context.Context, orgUUID string, before *models.Prospect, prospect *models.Prospect) error {
+	if slices.Contains(closedProspectStatuses, before.Status) {
+		return ErrProspectClosed
+	}
+	return s.repo.Update(ctx, orgUUID, before, prospect)
 }
 
-func (s *ProspectService) UpdateProspect(ctx context.Context, prospect *models.Prospect) error {
-	return s.repo.Update(ctx, prospect)
+func (s *ProspectService) DeleteProspect(ctx context.Context, orgUUID string, id string) error {
+	return s.repo.Delete(ctx, orgUUID, id)
 }
 
-func (s *ProspectService) DeleteProspect(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+func (s *ProspectService) ListProspects(ctx context.Context, orgUUID string) ([]*models.Prospect, error) {
+	return s.repo.FindAll(ctx, orgUUID)
 }
 
-func (s *ProspectService) ListProspects(ctx context.Context) ([]*models.Prospect, error) {
-	return s.repo.FindAll(ctx)
+// GetProspects returns a page of prospects matching the filter, with the total when requested
+func (s *ProspectService) GetProspects(ctx context.Context, orgUUID string, filter models.ProspectFilter, page models.PageRequest) (*models.Page[models.Prospect], error) {
+	items, next, err := s.repo.GetProspects(ctx, orgUUID, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.Page[models.Prospect]{Items: items, NextCursor: next}
+	if page.IncludeTotal {
+		total, err := s.repo.GetProspectsCount(ctx, orgUUID, filter)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+	return result, nil
 }
-func (s *ProspectService) GetProspects(ctx context.Context, skip int, limit int) ([]models.Prospect, error) {
-	return s.repo.GetProspects(ctx, skip, limit)
+func (s *ProspectService) GetProspectsCount(ctx context.Context, orgUUID string, filter models.ProspectFilter) (int, error) {
+	return s.repo.GetProspectsCount(ctx, orgUUID, filter)
 }
-func (s *ProspectService) GetProspectsCount(ctx context.Context) (int, error) {
-	return s.repo.GetProspectsCount(ctx)
+
+// AssignProspect assigns a prospect to a field executive of the same organisation.
+// With reassign set, the prospect must already be assigned; otherwise it must not be.
+func (s *ProspectService) AssignProspect(ctx context.Context, orgUUID string, uid string, assigneeUId string, actor string, reassign bool) (*models.Prospect, error) {
+	prospect, err := s.repo.GetByID(ctx, orgUUID, uid)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+	if reassign && prospect.AssignedTo == "" {
+		return nil, ErrNotAssigned
+	}
+	if !reassign && prospect.AssignedTo != "" {
+		return nil, ErrAlreadyAssigned
+	}
+
+	assignee, err := s.userRepo.GetByUserUID(ctx, orgUUID, assigneeUId)
+	if err != nil || assignee.Role != models.FieldExecutive || assignee.Status == models.InActive ||
+		assignee.Status == models.Disabled || assignee.Status == models.Banned {
+		return nil, ErrInvalidAssignee
+	}
+
+	comment := "Assigned to '" + assignee.Username + "'"
+	if reassign {
+		comment = "Reassigned from '" + prospect.AssignedToName + "' to '" + assignee.Username + "'"
+	}
+	before := *prospect
+	prospect.AssignedTo = assignee.UId
+	prospect.AssignedToName = assignee.Username
+	prospect.AssignedBy = actor
+	prospect.AssignedTime = time.Now().UTC().Format(time.RFC3339)
+	return prospect, s.saveWithHistory(ctx, orgUUID, before, prospect, actor, comment)
+}
+
+// UnassignProspect removes the current assignee from a prospect
+func (s *ProspectService) UnassignProspect(ctx context.Context, orgUUID string, uid string, actor string) (*models.Prospect, error) {
+	prospect, err := s.repo.GetByID(ctx, orgUUID, uid)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+	if prospect.AssignedTo == "" {
+		return nil, ErrNotAssigned
+	}
+
+	comment := "Unassigned from '" + prospect.AssignedToName + "'"
+	before := *prospect
+	prospect.AssignedTo = ""
+	prospect.AssignedToName = ""
+	prospect.AssignedBy = actor
+	prospect.AssignedTime = time.Now().UTC().Format(time.RFC3339)
+	return prospect, s.saveWithHistory(ctx, orgUUID, before, prospect, actor, comment)
+}
+
+// saveWithHistory stamps the update fields, appends a history entry with the fields changed since before
+// and persists the prospect. The comment is kept as given since it says why, e.g. a transition's reason.
+func (s *ProspectService) saveWithHistory(ctx context.Context, orgUUID string, before models.Prospect, prospect *models.Prospect, actor string, comment string) error {
+	prospect.UpdatedBy = actor
+	prospect.UpdatedTime = time.Now().UTC().Format(time.RFC3339)
+	prospect.UpdateHistory = append(prospect.UpdateHistory, models.UpdateHistory{
+		UpdatedTime:     prospect.UpdatedTime,
+		UpdatedComments: comment,
+		UpdateBy:        actor,
+		Changes:         models.Diff(before, prospect),
+	})
+	return s.repo.Update(ctx, orgUUID, &before, prospect)
+}
+
+// TransitionProspect moves a prospect to a new status if the workflow allows it for the given role under
+// the organisation's role policy, and records the reason in the prospect's update history
+func (s *ProspectService) TransitionProspect(ctx context.Context, orgUUID string, uid string, to models.ProspectStatus, policy models.RolePolicy, role models.Role, actor string, reason string) (*models.Prospect, error) {
+	prospect, err := s.repo.GetByID(ctx, orgUUID, uid)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+	if err := checkStatusChange(prospect, to, policy, role); err != nil {
+		return nil, err
+	}
+
+	before := *prospect
+	from := prospect.Status
+	prospect.Status = to
+	if to == models.Approved || to == models.Rejected {
+		prospect.ReviewedBy = actor
+		prospect.ReviewedTime = time.Now().UTC().Format(time.RFC3339)
+	}
+	if err := s.saveWithHistory(ctx, orgUUID, before, prospect, actor, "Status changed from '"+string(from)+"' to '"+string(to)+"': "+reason); err != nil {
+		return nil, err
+	}
+	return prospect, nil
+}
+
+// checkStatusChange applies the workflow to a status change and requires a completed visit before submission
+func checkStatusChange(prospect *models.Prospect, to models.ProspectStatus, policy models.RolePolicy, role models.Role) error {
+	if err := CheckProspectTransition(prospect.Status, to, policy, role); err != nil {
+		return err
+	}
+	if to == models.Submitted && !HasCompletedVisit(prospect) {
+		return ErrNoCompletedVisit
+	}
+	return nil
+}
+
+// BackfillOrgUUID assigns an organisation to prospects created before org scoping,
+// using the organisation of the user named in created_by. Creators whose username
+// exists in more than one organisation are skipped and logged for manual review.
+func (s *ProspectService) BackfillOrgUUID(ctx context.Context) error {
+	creators, err := s.repo.GetCreatorsWithoutOrg(ctx)
+	if err != nil {
+		return err
+	}
+	for _, createdBy := range creators {
+		orgUUIDs, err := s.userRepo.GetOrgUUIDsByUsername(ctx, createdBy)
+		if err != nil {
+			return err
+		}
+		if len(orgUUIDs) != 1 {
+			log.Printf("Skipping org backfill for prospects created by '%s': found %d matching organisations", createdBy, len(orgUUIDs))
+			continue
+		}
+		updated, err := s.repo.SetOrgUUIDForCreator(ctx, createdBy, orgUUIDs[0])
+		if err != nil {
+			return err
+		}
+		log.Printf("Backfilled org for %d prospects created by '%s'", updated, createdBy)
+	}
+	return nil
 }
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"fverify_be/internal/models"
+)
+
+var (
+	// ErrInvalidTransition is returned when the workflow has no edge between the two statuses
+	ErrInvalidTransition = errors.New("status transition is not allowed by the verification workflow")
+	// ErrTransitionForbidden is returned when the edge exists but the caller's role may not perform it
+	ErrTransitionForbidden = errors.New("your role is not permitted to perform this status transition")
+)
+
+// ProspectTransition is an edge of the verification workflow and the permission needed to take it
+type ProspectTransition struct {
+	From       models.ProspectStatus `json:"from" example:"Pending"`
+	To         models.ProspectStatus `json:"to" example:"OnVisit"`
+	Permission models.Permission     `json:"permission" example:"prospect.visit"`
+}
+
+// prospectTransitions declares the verification workflow. Any move not listed here is rejected.
+var prospectTransitions = []ProspectTransition{
+	// Field visit
+	{From: models.Pending, To: models.OnVisit, Permission: models.PermProspectVisit},
+	{From: models.RePending, To: models.OnVisit, Permission: models.PermProspectVisit},
+	{From: models.OnVisit, To: models.Progressve, Permission: models.PermProspectVisit},
+	{From: models.Progressve, To: models.Submitted, Permission: models.PermProspectSubmit},
+
+	// Rescheduling
+	{From: models.Pending, To: models.Postponed, Permission: models.PermProspectPostpone},
+	{From: models.OnVisit, To: models.Postponed, Permission: models.PermProspectVisitPostpone},
+	{From: models.RePending, To: models.Postponed, Permission: models.PermProspectPostpone},
+	{From: models.Postponed, To: models.Pending, Permission: models.PermProspectResume},
+
+	// Review
+	{From: models.Submitted, To: models.UnderReview, Permission: models.PermProspectReview},
+	{From: models.UnderReview, To: models.Approved, Permission: models.PermProspectApprove},
+	{From: models.UnderReview, To: models.Rejected, Permission: models.PermProspectReject},
+	{From: models.UnderReview, To: models.RePending, Permission: models.PermProspectReview},
+	{From: models.Approved, To: models.Completed, Permission: models.PermProspectComplete},
+	{From: models.Rejected, To: models.Completed, Permission: models.PermProspectComplete},
+
+	// Cancellation before a decision is made
+	{From: models.Pending, To: models.Cancelled, Permission: models.PermProspectCancel},
+	{From: models.RePending, To: models.Cancelled, Permission: models.PermProspectCancel},
+	{From: models.Postponed, To: models.Cancelled, Permission: models.PermProspectCancel},
+}
+
+// ProspectTransitions returns the declared workflow
+func ProspectTransitions() []ProspectTransition {
+	return prospectTransitions
+}
+
+// CheckProspectTransition verifies that role may move a prospect from one status to another under policy
+func CheckProspectTransition(from, to models.ProspectStatus, policy models.RolePolicy, role models.Role) error {
+	for _, transition := range prospectTransitions {
+		if transition.From != from || transition.To != to {
+			continue
+		}
+		if !policy.Allows(role, transition.Permission) {
+			return ErrTransitionForbidden
+		}
+		return nil
+	}
+	return ErrInvalidTransition
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"fverify_be/internal/models"
+)
+
+func TestCheckProspectTransition(t *testing.T) {
+	defaults := models.RolePolicyFor(nil, nil)
+	tests := []struct {
+		name   string
+		from   models.ProspectStatus
+		to     models.ProspectStatus
+		policy models.RolePolicy
+		role   models.Role
+		want   error
+	}{
+		{"field executive starts a visit", models.Pending, models.OnVisit, defaults, models.FieldExecutive, nil},
+		{"operations lead approves", models.UnderReview, models.Approved, defaults, models.OperationsLead, nil},
+		{"field executive cannot approve", models.UnderReview, models.Approved, defaults, models.FieldExecutive, ErrTransitionForbidden},
+		{"no edge", models.Pending, models.Approved, defaults, models.Owner, ErrInvalidTransition},
+		{"no way back from completed", models.Completed, models.Pending, defaults, models.Owner, ErrInvalidTransition},
+		{"same status", models.Pending, models.Pending, defaults, models.Owner, ErrInvalidTransition},
+		{
+			"override grants the edge", models.UnderReview, models.Approved,
+			models.RolePolicyFor(models.RolePolicy{models.FieldLead: {models.PermProspectApprove}}, nil), models.FieldLead, nil,
+		},
+		{
+			"override removes the edge", models.Pending, models.OnVisit,
+			models.RolePolicyFor(models.RolePolicy{models.FieldExecutive: {models.PermProspectRead}}, nil), models.FieldExecutive, ErrTransitionForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckProspectTransition(tt.from, tt.to, tt.policy, tt.role); !errors.Is(err, tt.want) {
+				t.Errorf("CheckProspectTransition(%s, %s) = %v, want %v", tt.from, tt.to, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProspectTransitionsAreUnique(t *testing.T) {
+	seen := map[[2]models.ProspectStatus]bool{}
+	for _, transition := range ProspectTransitions() {
+		edge := [2]models.ProspectStatus{transition.From, transition.To}
+		if seen[edge] {
+			t.Errorf("transition %s -> %s is declared twice", transition.From, transition.To)
+		}
+		seen[edge] = true
+		if !models.IsValidPermission(transition.Permission) {
+			t.Errorf("transition %s -> %s needs unknown permission %s", transition.From, transition.To, transition.Permission)
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"fverify_be/internal/storage"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/viper"
+)
+
+// ErrReportNotFound is returned when no issued report matches the given id or file
+var ErrReportNotFound = errors.New("report not found")
+
+const (
+	defaultReportBrand   = "FVerify"
+	defaultVerifyBaseURL = "http://localhost:9000"
+	maxReportPhotoBytes  = 10 << 20
+	defaultMaxVerifySize = 20 << 20
+)
+
+// finalProspectStatuses are the statuses for which a report is issued without the DRAFT marking
+var finalProspectStatuses = map[models.ProspectStatus]bool{
+	models.Approved:  true,
+	models.Rejected:  true,
+	models.Completed: true,
+}
+
+type ReportService struct {
+	prospectRepo  *repositories.ProspectRepositoryImpl
+	reportRepo    *repositories.ReportRepositoryImpl
+	orgRepo       *repositories.OrganisationRepositoryImpl
+	storage       storage.Storage
+	signingKey    []byte
+	verifyBaseURL string
+	brandName     string
+	maxVerifySize int64
+}
+
+// NewReportService creates the report service. Reports are signed with reports.signingKey;
+// without it they are still hashed and recorded but carry no signature.
+func NewReportService(prospectRepo *repositories.ProspectRepositoryImpl, reportRepo *repositories.ReportRepositoryImpl, orgRepo *repositories.OrganisationRepositoryImpl, store storage.Storage) *ReportService {
+	signingKey := viper.GetString("reports.signingKey")
+	if signingKey == "" {
+		log.Println("reports.signingKey is not configured, verification reports will not be signed")
+	}
+	verifyBaseURL := strings.TrimRight(viper.GetString("reports.verifyBaseURL"), "/")
+	if verifyBaseURL == "" {
+		verifyBaseURL = defaultVerifyBaseURL
+	}
+	brandName := viper.GetString("reports.brandName")
+	if brandName == "" {
+		brandName = defaultReportBrand
+	}
+	maxVerifySize := viper.GetInt64("reports.maxVerifyBytes")
+	if maxVerifySize <= 0 {
+		maxVerifySize = defaultMaxVerifySize
+	}
+	return &ReportService{
+		prospectRepo:  prospectRepo,
+		reportRepo:    reportRepo,
+		orgRepo:       orgRepo,
+		storage:       store,
+		signingKey:    []byte(signingKey),
+		verifyBaseURL: verifyBaseURL,
+		brandName:     brandName,
+		maxVerifySize: maxVerifySize,
+	}
+}
+
+// MaxVerifySize is the largest request accepted for verifying an uploaded report file
+func (s *ReportService) MaxVerifySize() int64 {
+	return s.maxVerifySize
+}
+
+// GenerateReport renders the verification report of a prospect as a PDF and records it for later verification
+func (s *ReportService) GenerateReport(ctx context.Context, orgUUID string, uid string, actor string) (*models.VerificationReport, []byte, error) {
+	prospect, err := s.prospectRepo.GetByID(ctx, orgUUID, uid)
+	if err != nil {
+		return nil, nil, ErrProspectNotFound
+	}
+	orgName := ""
+	if org, err := s.orgRepo.GetOrganisationByUUID(ctx, orgUUID); err == nil {
+		orgName = org.OrgName
+	}
+
+	report := &models.VerificationReport{
+		ReportId:       uuid.New().String(),
+		ProspectUId:    prospect.UId,
+		ProspectId:     prospect.ProspectId,
+		ProspectStatus: prospect.Status,
+		OrgUUID:        orgUUID,
+		IssuedBy:       actor,
+		IssuedTime:     time.Now().UTC().Format(time.RFC3339),
+	}
+	report.ContentHash, err = contentHash(report, prospect)
+	if err != nil {
+		return nil, nil, err
+	}
+	report.Signature = s.sign(report.ContentHash)
+
+	pdf, err := s.render(ctx, report, prospect, orgName)
+	if err != nil {
+		return nil, nil, err
+	}
+	pdfHash := sha256.Sum256(pdf)
+	report.PdfHash = hex.EncodeToString(pdfHash[:])
+
+	if err := s.reportRepo.Create(ctx, report); err != nil {
+		return nil, nil, err
+	}
+	return report, pdf, nil
+}
+
+// VerifyReport checks an issued report by id, and the content hash printed on it when given
+func (s *ReportService) VerifyReport(ctx context.Context, reportId string, hash string) (*models.ReportVerificationResp, error) {
+	report, err := s.reportRepo.GetByReportID(ctx, reportId)
+	if err != nil {
+		return nil, ErrReportNotFound
+	}
+	if hash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(report.ContentHash)) != 1 {
+		return &models.ReportVerificationResp{Valid: false, Reason: "Content hash does not match report", Report: report}, nil
+	}
+	return s.checkSignature(report), nil
+}
+
+// VerifyPdf checks that an uploaded file is byte-for-byte a report issued by this service
+func (s *ReportService) VerifyPdf(ctx context.Context, r io.Reader) (*models.ReportVerificationResp, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return nil, err
+	}
+	report, err := s.reportRepo.GetByPdfHash(ctx, hex.EncodeToString(hasher.Sum(nil)))
+	if err != nil {
+		return &models.ReportVerificationResp{Valid: false, Reason: "File does not match any issued report"}, nil
+	}
+	return s.checkSignature(report), nil
+}
+
+func (s *ReportService) checkSignature(report *models.VerificationReport) *models.ReportVerificationResp {
+	if len(s.signingKey) > 0 && !hmac.Equal([]byte(s.sign(report.ContentHash)), []byte(report.Signature)) {
+		return &models.ReportVerificationResp{Valid: false, Reason: "Signature does not match issuer key", Report: report}
+	}
+	return &models.ReportVerificationResp{Valid: true, Report: report}
+}
+
+func (s *ReportService) sign(hash string) string {
+	if len(s.signingKey) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// contentHash fingerprints the report identity together with the prospect record it was built from
+func contentHash(report *models.VerificationReport, prospect *models.Prospect) (string, error) {
+	content, err := json.Marshal(struct {
+		ReportId   string           `json:"report_id"`
+		IssuedBy   string           `json:"issued_by"`
+		IssuedTime string           `json:"issued_time"`
+		Prospect   *models.Prospect `json:"prospect"`
+	}{report.ReportId, report.IssuedBy, report.IssuedTime, prospect})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *ReportService) verifyURL(report *models.VerificationReport) string {
+	return s.verifyBaseURL + "/api/v1/reports/" + url.PathEscape(report.ReportId) + "/verify?hash=" + report.ContentHash
+}
+
+// approver returns who approved or rejected the prospect, falling back to the
+// update history for prospects reviewed before the reviewer was recorded
+func approver(prospect *models.Prospect) (string, string) {
+	if prospect.ReviewedBy != "" {
+		return prospect.ReviewedBy, prospect.ReviewedTime
+	}
+	for i := len(prospect.UpdateHistory) - 1; i >= 0; i-- {
+		entry := prospect.UpdateHistory[i]
+		if strings.Contains(entry.UpdatedComments, "to '"+string(models.Approved)+"'") ||
+			strings.Contains(entry.UpdatedComments, "to '"+string(models.Rejected)+"'") {
+			return entry.UpdateBy, entry.UpdatedTime
+		}
+	}
+	return "", ""
+}
+
+func (s *ReportService) render(ctx context.Context, report *models.VerificationReport, prospect *models.Prospect, orgName string) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(s.brandName+" verification report "+prospect.ProspectId, true)
+	pdf.SetCreator(s.brandName, true)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 7)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 4, tr("Report "+report.ReportId+"  |  SHA-256 "+report.ContentHash), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("Page %d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	contentWidth := pageWidth - left - right
+
+	section := func(title string) {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.SetFillColor(230, 236, 245)
+		pdf.CellFormat(contentWidth, 7, tr(title), "", 1, "L", true, 0, "")
+		pdf.Ln(1)
+	}
+	row := func(label, value string, verified *bool) {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(55, 6, tr(label), "B", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		if verified == nil {
+			pdf.CellFormat(contentWidth-55, 6, tr(value), "B", 1, "L", false, 0, "")
+			return
+		}
+		pdf.CellFormat(contentWidth-85, 6, tr(value), "B", 0, "L", false, 0, "")
+		mark := "Not verified"
+		if *verified {
+			mark = "Verified"
+			pdf.SetTextColor(20, 120, 40)
+		} else {
+			pdf.SetTextColor(180, 30, 30)
+		}
+		pdf.CellFormat(30, 6, mark, "B", 1, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	// Header
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(contentWidth, 9, tr(s.brandName), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	if orgName != "" {
+		pdf.CellFormat(contentWidth, 6, tr(orgName), "", 1, "L", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(contentWidth, 8, "Field Verification Report", "", 1, "L", false, 0, "")
+	if !finalProspectStatuses[prospect.Status] {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.SetTextColor(200, 30, 30)
+		pdf.CellFormat(contentWidth, 6, "DRAFT - verification has not been concluded", "", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	section("Report")
+	row("Report ID", report.ReportId, nil)
+	row("Issued", report.IssuedTime+" by "+report.IssuedBy, nil)
+	row("Prospect ID", prospect.ProspectId, nil)
+	row("Status", string(prospect.Status), nil)
+	row("Assigned to", prospect.AssignedToName, nil)
+	reviewer, reviewedTime := approver(prospect)
+	if reviewer != "" {
+		label := "Approved by"
+		if prospect.Status == models.Rejected {
+			label = "Reviewed by"
+		}
+		row(label, reviewer+" on "+reviewedTime, nil)
+	}
+
+	section("Applicant")
+	row("Applicant name", prospect.ApplicantName, &prospect.NameVerified)
+	row("Mobile number", prospect.MobileNumber, &prospect.MobileVerified)
+	row("Gender", prospect.Gender, nil)
+	row("Age", fmt.Sprint(prospect.Age), nil)
+	row("Residential address", prospect.ResidentialAddress, &prospect.ResAddressVerified)
+	row("Years of stay", fmt.Sprint(prospect.YearsOfStay), nil)
+	row("Family members", fmt.Sprint(prospect.NumberOfFamilyMembers), nil)
+	row("Reference", strings.TrimSpace(prospect.ReferenceName+" ("+prospect.ReferenceRelation+") "+prospect.ReferenceMobile), nil)
+
+	section("Employment")
+	row("Employment type", string(prospect.EmploymentType), nil)
+	row("Office address", prospect.OfficeAddress, &prospect.OffAddressVerified)
+	row("Years in current office", fmt.Sprint(prospect.YearsInCurrentOffice), nil)
+	row("Role", prospect.Role, &prospect.RoleVerified)
+	row("Employee ID", prospect.EmpId, &prospect.EmpIdVerified)
+	row("Previous experience", fmt.Sprint(prospect.PreviousExperience), nil)
+	row("Gross / net salary", fmt.Sprintf("%.2f / %.2f", prospect.GrossSalary, prospect.NetSalary), nil)
+	row("Colleague", strings.TrimSpace(prospect.ColleagueName+" ("+prospect.ColleagueDesignation+") "+prospect.ColleagueMobile), nil)
+	if prospect.Remarks != "" {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(contentWidth, 6, "Remarks", "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(contentWidth, 5, tr(prospect.Remarks), "", "L", false)
+	}
+
+	if len(prospect.Visits) > 0 {
+		section("Visits")
+		for _, visit := range prospect.Visits {
+			summary := string(visit.Target) + " visit by " + visit.VisitedByName + ", checked in " + visit.CheckInTime
+			if visit.CheckInDistanceKm != nil {
+				summary += fmt.Sprintf(" (%.2f km)", *visit.CheckInDistanceKm)
+			}
+			if visit.Completed() {
+				summary += ", checked out " + visit.CheckOutTime
+				if visit.CheckOutDistanceKm != nil {
+					summary += fmt.Sprintf(" (%.2f km)", *visit.CheckOutDistanceKm)
+				}
+				summary += ", " + (time.Duration(visit.DurationSeconds) * time.Second).String()
+			} else {
+				summary += ", not checked out"
+			}
+			pdf.SetFont("Helvetica", "", 8)
+			pdf.MultiCell(contentWidth, 5, tr(summary), "", "L", false)
+		}
+	}
+
+	s.renderPhotos(ctx, pdf, tr, prospect, section, contentWidth)
+
+	section("Timeline")
+	for _, entry := range prospect.UpdateHistory {
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(38, 5, tr(entry.UpdatedTime), "", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 5, tr(entry.UpdateBy), "", 0, "L", false, 0, "")
+		pdf.MultiCell(contentWidth-68, 5, tr(entry.UpdatedComments), "", "L", false)
+	}
+
+	// Authenticity block: the QR code links to the public verification endpoint
+	qr, err := qrcode.Encode(s.verifyURL(report), qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+	section("Authenticity")
+	if pdf.GetY() > 230 {
+		pdf.AddPage()
+	}
+	top := pdf.GetY()
+	pdf.RegisterImageOptionsReader("verify-qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	pdf.ImageOptions("verify-qr", left, top, 35, 35, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, s.verifyURL(report))
+	pdf.SetLeftMargin(left + 40)
+	pdf.SetY(top)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.MultiCell(contentWidth-40, 4, "Scan the code or open the link below to confirm this report was issued by "+tr(s.brandName)+" and has not been altered.", "", "L", false)
+	pdf.Ln(1)
+	pdf.SetFont("Courier", "", 7)
+	pdf.MultiCell(contentWidth-40, 4, "Content SHA-256: "+report.ContentHash, "", "L", false)
+	if report.Signature != "" {
+		pdf.MultiCell(contentWidth-40, 4, "Signature (HMAC-SHA256): "+report.Signature, "", "L", false)
+	}
+	pdf.MultiCell(contentWidth-40, 4, s.verifyURL(report), "", "L", false)
+	pdf.SetLeftMargin(left)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderPhotos embeds the uploaded images with their capture details. Images are re-encoded
+// as JPEG so an unusual source encoding cannot break the document.
+func (s *ReportService) renderPhotos(ctx context.Context, pdf *gofpdf.Fpdf, tr func(string) string, prospect *models.Prospect, section func(string), contentWidth float64) {
+	photos := []models.ProspectMedia{}
+	for _, media := range prospect.Media {
+		if media.ContentType == "image/jpeg" || media.ContentType == "image/png" {
+			photos = append(photos, media)
+		}
+	}
+	if len(photos) == 0 {
+		return
+	}
+	section("Photos")
+	left, _, _, _ := pdf.GetMargins()
+	for _, photo := range photos {
+		data, width, height, err := s.loadPhoto(ctx, photo)
+		if pdf.GetY() > 200 {
+			pdf.AddPage()
+		}
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(contentWidth, 5, tr(string(photo.Category)+" - "+photo.FileName), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 8)
+		details := "Uploaded " + photo.UploadedTime + " by " + photo.UploadedBy
+		if photo.CaptureTime != "" {
+			details += ", captured " + photo.CaptureTime
+		}
+		if photo.DistanceKm != nil {
+			details += fmt.Sprintf(", %.2f km from address", *photo.DistanceKm)
+		}
+		pdf.CellFormat(contentWidth, 4, tr(details), "", 1, "L", false, 0, "")
+		if len(photo.Flags) > 0 {
+			flags := make([]string, len(photo.Flags))
+			for i, flag := range photo.Flags {
+				flags[i] = string(flag)
+			}
+			pdf.SetTextColor(180, 30, 30)
+			pdf.CellFormat(contentWidth, 4, tr("Flags: "+strings.Join(flags, ", ")), "", 1, "L", false, 0, "")
+			pdf.SetTextColor(0, 0, 0)
+		}
+		if err != nil {
+			log.Printf("Report for prospect %s: skipping photo %s: %v", prospect.UId, photo.UId, err)
+			pdf.CellFormat(contentWidth, 5, "(image unavailable)", "", 1, "L", false, 0, "")
+			continue
+		}
+		// Fit within 80mm height and the content width, keeping the aspect ratio
+		w, h := contentWidth, contentWidth*float64(height)/float64(width)
+		if h > 80 {
+			w, h = 80*float64(width)/float64(height), 80
+		}
+		top := pdf.GetY() + 1
+		pdf.RegisterImageOptionsReader(photo.UId, gofpdf.ImageOptions{ImageType: "JPG"}, bytes.NewReader(data))
+		pdf.ImageOptions(photo.UId, left, top, w, h, false, gofpdf.ImageOptions{ImageType: "JPG"}, 0, "")
+		pdf.SetY(top + h + 3)
+	}
+}
+
+func (s *ReportService) loadPhoto(ctx context.Context, photo models.ProspectMedia) ([]byte, int, int, error) {
+	content, err := s.storage.Get(ctx, photo.StorageKey)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer content.Close()
+	img, _, err := image.Decode(io.LimitReader(content, maxReportPhotoBytes))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, 0, 0, err
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return nil, 0, 0, errors.New("empty image")
+	}
+	return buf.Bytes(), bounds.Dx(), bounds.Dy(), nil
+}
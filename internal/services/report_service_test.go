@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+
+	"fverify_be/internal/models"
+)
+
+func TestCheckSignature(t *testing.T) {
+	issuer := &ReportService{signingKey: []byte("issuer-key")}
+	hash, err := contentHash(&models.VerificationReport{ReportId: "report-1", IssuedBy: "ops_lead", IssuedTime: "2023-04-12T15:04:05Z"}, &models.Prospect{UId: "prospect-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		service   *ReportService
+		signature string
+		wantValid bool
+	}{
+		{"signed by the issuer", issuer, issuer.sign(hash), true},
+		{"signed with another key", issuer, (&ReportService{signingKey: []byte("other-key")}).sign(hash), false},
+		{"unsigned", issuer, "", false},
+		{"signing disabled", &ReportService{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.service.checkSignature(&models.VerificationReport{ContentHash: hash, Signature: tt.signature})
+			if got.Valid != tt.wantValid {
+				t.Errorf("checkSignature() = %+v, want valid: %v", got, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestContentHashCoversReport(t *testing.T) {
+	report := models.VerificationReport{ReportId: "report-1", IssuedBy: "ops_lead", IssuedTime: "2023-04-12T15:04:05Z"}
+	prospect := models.Prospect{UId: "prospect-1", ApplicantName: "John Doe"}
+	base, _ := contentHash(&report, &prospect)
+	tests := []struct {
+		name   string
+		change func(*models.VerificationReport, *models.Prospect)
+	}{
+		{"report id", func(r *models.VerificationReport, _ *models.Prospect) { r.ReportId = "report-2" }},
+		{"issuer", func(r *models.VerificationReport, _ *models.Prospect) { r.IssuedBy = "admin" }},
+		{"issue time", func(r *models.VerificationReport, _ *models.Prospect) { r.IssuedTime = "2023-04-12T15:04:06Z" }},
+		{"prospect details", func(_ *models.VerificationReport, p *models.Prospect) { p.ApplicantName = "Jane Doe" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, p := report, prospect
+			tt.change(&r, &p)
+			if got, _ := contentHash(&r, &p); got == base {
+				t.Errorf("content hash did not change")
+			}
+		})
+	}
+}
+
+func TestApprover(t *testing.T) {
+	tests := []struct {
+		name     string
+		prospect models.Prospect
+		wantBy   string
+	}{
+		{"recorded reviewer", models.Prospect{ReviewedBy: "ops_lead", ReviewedTime: "2023-04-12T15:04:05Z"}, "ops_lead"},
+		{
+			"latest decision in history", models.Prospect{UpdateHistory: []models.UpdateHistory{
+				{UpdatedComments: "Status changed from 'UnderReview' to 'Rejected': blurred photos", UpdateBy: "ops_lead"},
+				{UpdatedComments: "Status changed from 'UnderReview' to 'Approved': ok", UpdateBy: "admin"},
+				{UpdatedComments: "Status changed from 'Approved' to 'Completed': done", UpdateBy: "owner"},
+			}}, "admin",
+		},
+		{"never reviewed", models.Prospect{UpdateHistory: []models.UpdateHistory{{UpdatedComments: "Created", UpdateBy: "admin"}}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if by, _ := approver(&tt.prospect); by != tt.wantBy {
+				t.Errorf("approver() = %q, want %q", by, tt.wantBy)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBlankRoleName is returned when a custom role name is only whitespace
+	ErrBlankRoleName = errors.New("role name must not be blank")
+	// ErrBuiltInRoleName is returned when a custom role is given the name of a built-in role
+	ErrBuiltInRoleName = errors.New("custom roles can not use the name of a built-in role")
+	// ErrInvalidPermission is returned when a custom role is given a permission that does not exist
+	ErrInvalidPermission = errors.New("unknown permission")
+	// ErrRoleInUse is returned when a custom role that users still hold is deleted
+	ErrRoleInUse = errors.New("the role is still held by users")
+	// ErrPermissionNotHeld is returned when a custom role would grant, or already grants, a permission the
+	// caller's own role does not hold
+	ErrPermissionNotHeld = errors.New("custom roles can only grant permissions your own role holds")
+)
+
+// RoleService manages the custom roles organisations define besides the built-in roles
+type RoleService struct {
+	repo     *repositories.CustomRoleRepositoryImpl
+	userRepo *repositories.UserRepositoryImpl
+}
+
+func NewRoleService(repo *repositories.CustomRoleRepositoryImpl, userRepo *repositories.UserRepositoryImpl) *RoleService {
+	return &RoleService{repo: repo, userRepo: userRepo}
+}
+
+// CreateRole defines a custom role for an organisation. The role may only grant permissions the caller's
+// role holds under the organisation's policy.
+func (s *RoleService) CreateRole(ctx context.Context, orgUUID string, req models.CustomRoleReq, policy models.RolePolicy, actorRole models.Role, createdBy string) (*models.CustomRole, error) {
+	name := models.Role(strings.TrimSpace(string(req.Name)))
+	if name == "" {
+		return nil, ErrBlankRoleName
+	}
+	if models.IsBuiltInRole(name) {
+		return nil, ErrBuiltInRoleName
+	}
+	permissions, err := customRolePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.HoldsAll(actorRole, permissions) {
+		return nil, ErrPermissionNotHeld
+	}
+
+	role := models.CustomRole{
+		RoleId:      uuid.New().String(),
+		OrgUUID:     orgUUID,
+		Name:        name,
+		Permissions: permissions,
+		CreatedBy:   createdBy,
+		CreatedTime: time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := s.repo.Create(ctx, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetRoles returns the custom roles of an organisation
+func (s *RoleService) GetRoles(ctx context.Context, orgUUID string) ([]models.CustomRole, error) {
+	return s.repo.GetAllByOrg(ctx, orgUUID)
+}
+
+// UpdateRolePermissions replaces the permissions of a custom role and returns the role before and after.
+// Its users are held to the new permissions from their next request. The caller's role must hold both the
+// old and the new permissions.
+func (s *RoleService) UpdateRolePermissions(ctx context.Context, orgUUID string, roleId string, req models.CustomRolePermissionsReq, policy models.RolePolicy, actorRole models.Role, updatedBy string) (*models.CustomRole, *models.CustomRole, error) {
+	permissions, err := customRolePermissions(req.Permissions)
+	if err != nil {
+		return nil, nil, err
+	}
+	before, err := s.repo.GetByID(ctx, orgUUID, roleId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !policy.HoldsAll(actorRole, permissions) || !policy.HoldsAll(actorRole, before.Permissions) {
+		return nil, nil, ErrPermissionNotHeld
+	}
+	if err := s.repo.UpdatePermissions(ctx, orgUUID, roleId, permissions, time.Now().UTC().Format(time.RFC3339), updatedBy); err != nil {
+		return nil, nil, err
+	}
+	after, err := s.repo.GetByID(ctx, orgUUID, roleId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// DeleteRole removes a custom role that no user holds any more and returns it. The caller's role must
+// hold every permission the role grants.
+func (s *RoleService) DeleteRole(ctx context.Context, orgUUID string, roleId string, policy models.RolePolicy, actorRole models.Role) (*models.CustomRole, error) {
+	role, err := s.repo.GetByID(ctx, orgUUID, roleId)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.HoldsAll(actorRole, role.Permissions) {
+		return nil, ErrPermissionNotHeld
+	}
+	holders, err := s.userRepo.CountUsersByRole(ctx, orgUUID, role.Name)
+	if err != nil {
+		return nil, err
+	}
+	if holders > 0 {
+		return nil, ErrRoleInUse
+	}
+	if err := s.repo.Delete(ctx, orgUUID, roleId); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// Policy returns the permissions of an organisation's built-in and custom roles
+func (s *RoleService) Policy(ctx context.Context, org *models.Organisation) (models.RolePolicy, []models.CustomRole, error) {
+	customRoles, err := s.repo.GetAllByOrg(ctx, org.OrgUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return org.RolePolicy(customRoles), customRoles, nil
+}
+
+// customRolePermissions validates and de-duplicates the permissions of a custom role
+func customRolePermissions(requested []models.Permission) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	for _, permission := range requested {
+		if !models.IsValidPermission(permission) {
+			return nil, ErrInvalidPermission
+		}
+		if !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
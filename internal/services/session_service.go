@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when a rotated refresh token is presented again; the session is revoked
+	ErrRefreshTokenReused = errors.New("refresh token has already been used, the session has been revoked")
+	// ErrSessionUserInactive is returned when refreshing the session of a user or organisation that is no longer active
+	ErrSessionUserInactive = errors.New("user or organisation is no longer active")
+)
+
+const defaultRefreshTokenTTLHours = 30 * 24
+
+type SessionService struct {
+	sessionRepo *repositories.SessionRepositoryImpl
+	userRepo    *repositories.UserRepositoryImpl
+	orgRepo     *repositories.OrganisationRepositoryImpl
+	refreshTTL  time.Duration
+}
+
+// NewSessionService creates the session service; refresh tokens live for auth.refreshTokenTTLHours
+func NewSessionService(sessionRepo *repositories.SessionRepositoryImpl, userRepo *repositories.UserRepositoryImpl, orgRepo *repositories.OrganisationRepositoryImpl) *SessionService {
+	hours := viper.GetInt("auth.refreshTokenTTLHours")
+	if hours <= 0 {
+		hours = defaultRefreshTokenTTLHours
+	}
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		orgRepo:     orgRepo,
+		refreshTTL:  time.Duration(hours) * time.Hour,
+	}
+}
+
+// StartSession opens a session for an authenticated user and issues its first access and refresh tokens
+func (s *SessionService) StartSession(ctx context.Context, orgUUID string, userUId string, userAgent string, ip string) (*models.TokenResponse, error) {
+	user, err := s.userRepo.GetByUserUID(ctx, orgUUID, userUId)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, tokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	session := &models.Session{
+		SessionId:        uuid.New().String(),
+		UserUId:          user.UId,
+		OrgUUID:          user.OrgUUID,
+		CurrentTokenHash: tokenHash,
+		UsedTokenHashes:  []string{},
+		UserAgent:        userAgent,
+		IPAddress:        ip,
+		CreatedTime:      now.Format(time.RFC3339),
+		ExpiresAt:        now.Add(s.refreshTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.tokens(user, session.SessionId, refreshToken)
+}
+
+// Refresh rotates a refresh token and issues a new access token carrying the user's current details.
+// A token that was already rotated signals theft, so the whole session is revoked.
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string) (*models.TokenResponse, error) {
+	tokenHash := hashRefreshToken(refreshToken)
+	nextToken, nextHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.sessionRepo.Rotate(ctx, tokenHash, nextHash, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		// The lookup returns no session when the token was never rotated
+		holder, _ := s.sessionRepo.GetByUsedTokenHash(ctx, tokenHash)
+		if errors.Is(checkRefresh(holder, tokenHash, time.Now().UTC()), ErrRefreshTokenReused) {
+			log.Printf("Refresh token reuse detected for session %s of user %s, revoking session", holder.SessionId, holder.UserUId)
+			if err := s.sessionRepo.Revoke(ctx, holder.SessionId, "Refresh token reuse"); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByUserUID(ctx, session.OrgUUID, session.UserUId)
+	if err != nil || user.Status != models.Active {
+		_ = s.sessionRepo.Revoke(ctx, session.SessionId, "User inactive")
+		return nil, ErrSessionUserInactive
+	}
+	if org, err := s.orgRepo.GetOrganisationByUUID(ctx, session.OrgUUID); err != nil || org.Status != models.OrgActive {
+		_ = s.sessionRepo.Revoke(ctx, session.SessionId, "Organisation inactive")
+		return nil, ErrSessionUserInactive
+	}
+	return s.tokens(user, session.SessionId, nextToken)
+}
+
+// Logout revokes the session of a refresh token
+func (s *SessionService) Logout(ctx context.Context, refreshToken string) error {
+	session, err := s.sessionRepo.GetByTokenHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+	return s.sessionRepo.Revoke(ctx, session.SessionId, "Logout")
+}
+
+// RevokeUserSessions logs a user out of every session, e.g. after their password changed
+func (s *SessionService) RevokeUserSessions(ctx context.Context, orgUUID string, userUId string, reason string) error {
+	return s.sessionRepo.RevokeUserSessions(ctx, orgUUID, userUId, reason)
+}
+
+func (s *SessionService) tokens(user *models.UserResp, sessionId string, refreshToken string) (*models.TokenResponse, error) {
+	token, err := auth.GenerateAuthToken(user.UserId, user.Username, user.UId, string(user.Role), string(user.Status), user.MobileNumber, user.OrgUUID, sessionId, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// checkRefresh decides whether the session holding a refresh token accepts it at now. A token the
+// session has already rotated is reuse, even once the session is revoked or expired.
+func checkRefresh(session *models.Session, tokenHash string, now time.Time) error {
+	if session == nil {
+		return ErrInvalidRefreshToken
+	}
+	if slices.Contains(session.UsedTokenHashes, tokenHash) {
+		return ErrRefreshTokenReused
+	}
+	if session.CurrentTokenHash != tokenHash || session.RevokedTime != "" || !now.Before(session.ExpiresAt) {
+		return ErrInvalidRefreshToken
+	}
+	return nil
+}
+
+// newRefreshToken returns a random opaque refresh token and the hash stored in its place
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"fverify_be/internal/models"
+)
+
+func TestCheckRefresh(t *testing.T) {
+	now := time.Date(2023, 4, 12, 10, 0, 0, 0, time.UTC)
+	session := func(change func(*models.Session)) *models.Session {
+		s := &models.Session{
+			SessionId:        "session-1",
+			CurrentTokenHash: hashRefreshToken("third"),
+			UsedTokenHashes:  []string{hashRefreshToken("first"), hashRefreshToken("second")},
+			ExpiresAt:        now.Add(time.Hour),
+		}
+		change(s)
+		return s
+	}
+	unchanged := func(*models.Session) {}
+
+	tests := []struct {
+		name    string
+		session *models.Session
+		token   string
+		want    error
+	}{
+		{"current token", session(unchanged), "third", nil},
+		{"rotated token", session(unchanged), "second", ErrRefreshTokenReused},
+		{"first token", session(unchanged), "first", ErrRefreshTokenReused},
+		{"rotated token of a revoked session", session(func(s *models.Session) { s.RevokedTime = "2023-04-12T09:00:00Z" }), "first", ErrRefreshTokenReused},
+		{"unknown token", session(unchanged), "forged", ErrInvalidRefreshToken},
+		{"no session", nil, "third", ErrInvalidRefreshToken},
+		{"revoked session", session(func(s *models.Session) { s.RevokedTime = "2023-04-12T09:00:00Z" }), "third", ErrInvalidRefreshToken},
+		{"expired session", session(func(s *models.Session) { s.ExpiresAt = now }), "third", ErrInvalidRefreshToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkRefresh(tt.session, hashRefreshToken(tt.token), now); !errors.Is(err, tt.want) {
+				t.Errorf("checkRefresh() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"fverify_be/internal/auth"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrInvalidChallenge is returned for expired or invalid two-factor challenge tokens
+	ErrInvalidChallenge = errors.New("invalid or expired login challenge, please log in again")
+	// ErrInvalidTwoFactorCode is returned for wrong, expired or already used TOTP and recovery codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorEnrolmentRequired is returned when completing a login before enrolling a required second factor
+	ErrTwoFactorEnrolmentRequired = errors.New("two-factor enrolment is required before logging in")
+	// ErrTwoFactorEnabled is returned when starting an enrolment for a user who is already enrolled
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled, ask an administrator to reset it")
+	// ErrTwoFactorNotEnabled is returned for operations that need a confirmed enrolment
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrNoPendingEnrolment is returned when confirming an enrolment that was not started
+	ErrNoPendingEnrolment = errors.New("no two-factor enrolment has been started")
+)
+
+const (
+	totpPeriod        = 30
+	totpDigits        = 6
+	totpSkewSteps     = 1 // Accept codes from one period before or after, for clock drift
+	recoveryCodeCount = 10
+)
+
+// TwoFactorService handles TOTP enrolment and the second step of two-factor logins
+type TwoFactorService struct {
+	userRepo *repositories.UserRepositoryImpl
+	orgRepo  *repositories.OrganisationRepositoryImpl
+	guard    *LoginGuard
+	issuer   string
+}
+
+// NewTwoFactorService creates the two-factor service; authenticator apps show auth.totpIssuer as the account issuer
+func NewTwoFactorService(userRepo *repositories.UserRepositoryImpl, orgRepo *repositories.OrganisationRepositoryImpl, guard *LoginGuard) *TwoFactorService {
+	issuer := viper.GetString("auth.totpIssuer")
+	if issuer == "" {
+		issuer = "FVerify"
+	}
+	return &TwoFactorService{userRepo: userRepo, orgRepo: orgRepo, guard: guard, issuer: issuer}
+}
+
+// Challenge returns the challenge a user must complete after their password was accepted, or nil when
+// the user neither enrolled a second factor nor holds a role their organisation requires one for
+func (s *TwoFactorService) Challenge(ctx context.Context, user *models.User) (*models.TwoFactorChallenge, error) {
+	enabled := user.TwoFactor != nil && user.TwoFactor.Enabled
+	if !enabled {
+		org, err := s.orgRepo.GetOrganisationByUUID(ctx, user.OrgUUID)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(org.TwoFactorRoles, user.Role) {
+			return nil, nil
+		}
+	}
+	token, err := auth.GenerateChallengeToken(user.UId, user.OrgUUID, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorChallenge{
+		TwoFactorRequired:  true,
+		EnrolmentRequired:  !enabled,
+		ChallengeToken:     token,
+		ChallengeExpiresIn: int(auth.ChallengeTTL.Seconds()),
+	}, nil
+}
+
+// challengedUser returns the user a challenge token was issued to, provided nothing changed since
+func (s *TwoFactorService) challengedUser(ctx context.Context, challengeToken string) (*models.User, error) {
+	claims, err := auth.ParseChallengeToken(challengeToken)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	user, err := s.userRepo.GetCredentials(ctx, claims.OrgUUID, claims.UId)
+	if err != nil || user.TokenVersion != claims.TokenVersion || user.Status == models.InActive {
+		return nil, ErrInvalidChallenge
+	}
+	return user, nil
+}
+
+// StartLoginEnrolment starts the enrolment of a user who must enrol to complete their login
+func (s *TwoFactorService) StartLoginEnrolment(ctx context.Context, challengeToken string) (*models.TwoFactorEnrolment, error) {
+	user, err := s.challengedUser(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.StartEnrolment(ctx, user.OrgUUID, user.UId, user.Username)
+}
+
+// CompleteLogin checks the second factor of a challenged login and returns the user. A TOTP code for a
+// pending enrolment confirms it, in which case the new recovery codes are returned too.
+func (s *TwoFactorService) CompleteLogin(ctx context.Context, challengeToken string, code string, ip string) (*models.User, []string, error) {
+	user, err := s.challengedUser(ctx, challengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.guard.Check(ctx, user.OrgUUID, user.Username, ip); err != nil {
+		return nil, nil, err
+	}
+
+	var recoveryCodes []string
+	switch {
+	case user.TwoFactor != nil && user.TwoFactor.Enabled:
+		err = s.verify(ctx, user, code)
+	case user.TwoFactor != nil && user.TwoFactor.PendingSecret != "":
+		recoveryCodes, err = s.confirm(ctx, user, code)
+	default:
+		return nil, nil, ErrTwoFactorEnrolmentRequired
+	}
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if guardErr := s.guard.RecordFailure(ctx, user.OrgUUID, user.Username, ip); guardErr != nil {
+			log.Printf("Failed to record failed two-factor login for %s: %v", user.Username, guardErr)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.guard.RecordSuccess(ctx, user.OrgUUID, user.Username); err != nil {
+		log.Printf("Failed to clear failed logins for %s: %v", user.Username, err)
+	}
+	return user, recoveryCodes, nil
+}
+
+// StartEnrolment creates a new TOTP secret for a user. It only takes effect once confirmed with a code.
+func (s *TwoFactorService) StartEnrolment(ctx context.Context, orgUUID string, uid string, username string) (*models.TwoFactorEnrolment, error) {
+	user, err := s.userRepo.GetCredentials(ctx, orgUUID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor != nil && user.TwoFactor.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+	if err := s.userRepo.SetTwoFactorPending(ctx, orgUUID, uid, secret); err != nil {
+		return nil, err
+	}
+
+	otpURL := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + s.issuer + ":" + username,
+		RawQuery: url.Values{
+			"secret":    {secret},
+			"issuer":    {s.issuer},
+			"algorithm": {"SHA1"},
+			"digits":    {fmt.Sprint(totpDigits)},
+			"period":    {fmt.Sprint(totpPeriod)},
+		}.Encode(),
+	}
+	qr, err := qrcode.Encode(otpURL.String(), qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorEnrolment{Secret: secret, OTPAuthURL: otpURL.String(), QRCodePNG: qr}, nil
+}
+
+// ConfirmEnrolment enables two-factor login for a signed-in user who proves their pending secret with a
+// code, and returns their recovery codes
+func (s *TwoFactorService) ConfirmEnrolment(ctx context.Context, orgUUID string, uid string, code string) ([]string, error) {
+	user, err := s.userRepo.GetCredentials(ctx, orgUUID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor == nil || user.TwoFactor.PendingSecret == "" {
+		return nil, ErrNoPendingEnrolment
+	}
+	return s.confirm(ctx, user, code)
+}
+
+func (s *TwoFactorService) confirm(ctx context.Context, user *models.User, code string) ([]string, error) {
+	step, ok := verifyTOTP(user.TwoFactor.PendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	err = s.userRepo.EnableTwoFactor(ctx, user.OrgUUID, user.UId, &models.TwoFactor{
+		Enabled:            true,
+		Secret:             user.TwoFactor.PendingSecret,
+		EnrolledTime:       now,
+		LastUsedStep:       step,
+		RecoveryCodeHashes: hashes,
+	}, models.UpdateHistory{UpdatedTime: now, UpdatedComments: "two-factor authentication enrolled", UpdateBy: user.Username})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verify accepts an unused TOTP code or consumes a recovery code
+func (s *TwoFactorService) verify(ctx context.Context, user *models.User, code string) error {
+	if step, ok := verifyTOTP(user.TwoFactor.Secret, code, time.Now()); ok {
+		fresh, err := s.userRepo.UseTwoFactorStep(ctx, user.OrgUUID, user.UId, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	used, err := s.userRepo.UseRecoveryCode(ctx, user.OrgUUID, user.UId, hashOneTimeCode(code), models.UpdateHistory{
+		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
+		UpdatedComments: fmt.Sprintf("recovery code used to log in, %d left", max(len(user.TwoFactor.RecoveryCodeHashes)-1, 0)),
+		UpdateBy:        user.Username,
+	})
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of an enrolled user who proves their second factor
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, orgUUID string, uid string, code string) ([]string, error) {
+	user, err := s.userRepo.GetCredentials(ctx, orgUUID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor == nil || !user.TwoFactor.Enabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	step, ok := verifyTOTP(user.TwoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if fresh, err := s.userRepo.UseTwoFactorStep(ctx, orgUUID, uid, step); err != nil || !fresh {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.userRepo.SetRecoveryCodes(ctx, orgUUID, uid, hashes, models.UpdateHistory{
+		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
+		UpdatedComments: "two-factor recovery codes regenerated",
+		UpdateBy:        user.Username,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset removes a user's two-factor enrolment, e.g. after a lost device. Their issued access tokens are revoked.
+func (s *TwoFactorService) Reset(ctx context.Context, orgUUID string, uid string, authUserName string) error {
+	return s.userRepo.ResetTwoFactor(ctx, orgUUID, uid, models.UpdateHistory{
+		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
+		UpdatedComments: "two-factor authentication reset",
+		UpdateBy:        authUserName,
+	})
+}
+
+// verifyTOTP checks a code against the RFC 6238 codes of the current time step and its neighbours and
+// returns the matching step
+func verifyTOTP(secret string, code string, now time.Time) (int64, bool) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkewSteps; step <= current+totpSkewSteps; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the HOTP value (RFC 4226) of a time step
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes stored in their place
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newOneTimeCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i], hashes[i] = code, hashOneTimeCode(code)
+	}
+	return codes, hashes, nil
+}
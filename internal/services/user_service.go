@@ -2,53 +2,272 @@ package services
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
 
 	"fverify_be/internal/models"
 
 	"fverify_be/internal/repositories"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrIncorrectPassword is returned when the current password given to change a password is wrong
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	// ErrSamePassword is returned when the new password equals the current one
+	ErrSamePassword = errors.New("new password must differ from the current password")
+	// ErrInvalidResetCode is returned for unknown, expired or already used password reset codes
+	ErrInvalidResetCode = errors.New("invalid or expired password reset code")
 )
 
+const defaultPasswordResetTTLMinutes = 60
+
+// oneTimeCodeAlphabet leaves out characters that are easily confused when a code is read out
+const oneTimeCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
 type UserService struct {
-	repo *repositories.UserRepositoryImpl
+	repo      *repositories.UserRepositoryImpl
+	guard     *LoginGuard
+	passwords *PasswordPolicies
 }
 
-func NewUserService(repo *repositories.UserRepositoryImpl) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo *repositories.UserRepositoryImpl, guard *LoginGuard, passwords *PasswordPolicies) *UserService {
+	return &UserService{repo: repo, guard: guard, passwords: passwords}
 }
 
+// CreateUser creates a user whose password meets the organisation's password policy
 func (s *UserService) CreateUser(ctx context.Context, user *models.User) (*models.UserResp, error) {
+	if err := s.passwords.Check(ctx, user.OrgUUID, user.Password, nil); err != nil {
+		return nil, err
+	}
 	user.Status = models.Created
 	return s.repo.Create(ctx, user)
 }
 
-func (s *UserService) GetByUserUID(ctx context.Context, uid string) (*models.UserResp, error) {
-	return s.repo.GetByUserUID(ctx, uid)
+func (s *UserService) GetByUserUID(ctx context.Context, orgUUID string, uid string) (*models.UserResp, error) {
+	return s.repo.GetByUserUID(ctx, orgUUID, uid)
 }
 
-func (s *UserService) GetByUserID(ctx context.Context, userId string) (*models.UserResp, error) {
-	return s.repo.GetByUserID(ctx, userId)
+func (s *UserService) GetByUserID(ctx context.Context, orgUUID string, userId string) (*models.UserResp, error) {
+	return s.repo.GetByUserID(ctx, orgUUID, userId)
 }
-func (s *UserService) GetAllUsers(ctx context.Context) ([]*models.UserResp, error) {
-	return s.repo.GetAllUsers(ctx)
+
+// GetAllUsers returns a page of the organisation's users, with the total when requested
+func (s *UserService) GetAllUsers(ctx context.Context, orgUUID string, page models.PageRequest) (*models.Page[models.UserResp], error) {
+	items, next, err := s.repo.GetAllUsers(ctx, orgUUID, page)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.Page[models.UserResp]{Items: items, NextCursor: next}
+	if page.IncludeTotal {
+		total, err := s.repo.CountUsers(ctx, orgUUID)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+	return result, nil
 }
 
-func (s *UserService) DeleteByUId(ctx context.Context, uId string) error {
-	return s.repo.DeleteByUId(ctx, uId)
+func (s *UserService) DeleteByUId(ctx context.Context, orgUUID string, uId string) error {
+	return s.repo.DeleteByUId(ctx, orgUUID, uId)
 }
 
-func (s *UserService) DeleteByUserId(ctx context.Context, userId string) error {
-	return s.repo.DeleteByUserId(ctx, userId)
+// GetUserRecord returns the stored record of a user, including their credentials
+func (s *UserService) GetUserRecord(ctx context.Context, orgUUID string, uid string) (*models.User, error) {
+	return s.repo.GetCredentials(ctx, orgUUID, uid)
 }
 
+// DeleteByUserId deletes a user and returns the deleted record
+func (s *UserService) DeleteByUserId(ctx context.Context, orgUUID string, userId string) (*models.User, error) {
+	return s.repo.DeleteByUserId(ctx, orgUUID, userId)
+}
+
+// UpdateUser updates a user
 func (s *UserService) UpdateUser(ctx context.Context, user *models.User, authUserName string) (*models.UserResp, error) {
 	return s.repo.Update(ctx, user, authUserName)
 }
-func (s *UserService) LoginUser(ctx context.Context, username, password string, org_id string) (*models.User, error) {
-	return s.repo.ValidateUser(ctx, username, password, org_id)
+
+// LoginUser validates the credentials of a user. Logins are refused with a LoginBlockedError while the
+// account or the client IP is backing off or locked after failed attempts.
+func (s *UserService) LoginUser(ctx context.Context, username, password string, org_id string, ip string) (*models.User, error) {
+	if err := s.guard.Check(ctx, org_id, username, ip); err != nil {
+		return nil, err
+	}
+	user, err := s.repo.ValidateUser(ctx, username, password, org_id)
+	if err != nil {
+		if guardErr := s.guard.RecordFailure(ctx, org_id, username, ip); guardErr != nil {
+			log.Printf("Failed to record failed login for %s: %v", username, guardErr)
+		}
+		return nil, err
+	}
+	if err := s.guard.RecordSuccess(ctx, org_id, username); err != nil {
+		log.Printf("Failed to clear failed logins for %s: %v", username, err)
+	}
+	return user, nil
+}
+
+// UnlockUser clears the failed login attempts and lockout of a user
+func (s *UserService) UnlockUser(ctx context.Context, orgUUID string, uid string, authUserName string) error {
+	user, err := s.repo.GetByUserUID(ctx, orgUUID, uid)
+	if err != nil {
+		return err
+	}
+	return s.guard.Unlock(ctx, orgUUID, user.Username, authUserName)
+}
+
+// ChangePassword sets a new password for a user who knows their current one. Wrong current passwords
+// count as failed logins.
+func (s *UserService) ChangePassword(ctx context.Context, orgUUID string, username string, req models.ChangePasswordRequest, ip string) (*models.User, error) {
+	if err := s.guard.Check(ctx, orgUUID, username, ip); err != nil {
+		return nil, err
+	}
+	user, err := s.repo.ValidateUser(ctx, username, req.CurrentPassword, orgUUID)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		if guardErr := s.guard.RecordFailure(ctx, orgUUID, username, ip); guardErr != nil {
+			log.Printf("Failed to record failed password change for %s: %v", username, guardErr)
+		}
+		return nil, ErrIncorrectPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return nil, ErrSamePassword
+	}
+	if err := s.passwords.Check(ctx, orgUUID, req.NewPassword, passwordHashes(user)); err != nil {
+		return nil, err
+	}
+	err = s.repo.SetPassword(ctx, orgUUID, user.UId, req.NewPassword, models.UpdateHistory{
+		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
+		UpdatedComments: "password changed",
+		UpdateBy:        username,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// IssuePasswordReset creates a one-time code with which the user can set a new password. Only the hash
+// of the code is stored; issuing a new code replaces the previous one.
+func (s *UserService) IssuePasswordReset(ctx context.Context, orgUUID string, uid string, authUserName string) (*models.PasswordResetCodeResponse, error) {
+	code, err := newOneTimeCode()
+	if err != nil {
+		return nil, err
+	}
+	minutes := viper.GetInt("auth.passwordResetTTLMinutes")
+	if minutes <= 0 {
+		minutes = defaultPasswordResetTTLMinutes
+	}
+	now := time.Now().UTC()
+	reset := &models.PasswordReset{
+		CodeHash:  hashOneTimeCode(code),
+		ExpiresAt: now.Add(time.Duration(minutes) * time.Minute),
+		IssuedBy:  authUserName,
+	}
+	err = s.repo.SetPasswordReset(ctx, orgUUID, uid, reset, models.UpdateHistory{
+		UpdatedTime:     now.Format(time.RFC3339),
+		UpdatedComments: "password reset code issued",
+		UpdateBy:        authUserName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.PasswordResetCodeResponse{Code: code, ExpiresAt: reset.ExpiresAt.Format(time.RFC3339)}, nil
+}
+
+// ResetPassword sets a new password using a reset code, which is consumed. Wrong codes count as failed logins.
+func (s *UserService) ResetPassword(ctx context.Context, orgUUID string, req models.ResetPasswordRequest, ip string) (*models.User, error) {
+	if err := s.guard.Check(ctx, orgUUID, req.Username, ip); err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetByUsername(ctx, orgUUID, req.Username)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	if user == nil || !validResetCode(user.PasswordReset, req.Code) {
+		if guardErr := s.guard.RecordFailure(ctx, orgUUID, req.Username, ip); guardErr != nil {
+			log.Printf("Failed to record failed password reset for %s: %v", req.Username, guardErr)
+		}
+		return nil, ErrInvalidResetCode
+	}
+	if err := s.passwords.Check(ctx, orgUUID, req.NewPassword, passwordHashes(user)); err != nil {
+		return nil, err
+	}
+	// The code is consumed by the write itself, so concurrent requests with the same code can not both succeed
+	err = s.repo.ResetPassword(ctx, orgUUID, user.UId, user.PasswordReset.CodeHash, req.NewPassword, models.UpdateHistory{
+		UpdatedTime:     time.Now().UTC().Format(time.RFC3339),
+		UpdatedComments: "password reset with a code issued by " + user.PasswordReset.IssuedBy,
+		UpdateBy:        user.Username,
+	})
+	if errors.Is(err, repositories.ErrResetCodeUsed) {
+		return nil, ErrInvalidResetCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guard.RecordSuccess(ctx, orgUUID, req.Username); err != nil {
+		log.Printf("Failed to clear failed logins for %s: %v", req.Username, err)
+	}
+	return user, nil
 }
-func (s *UserService) SetPassword(ctx context.Context, uId string, newPassword string) error {
-	return s.repo.SetPassword(ctx, uId, newPassword)
+
+func validResetCode(reset *models.PasswordReset, code string) bool {
+	if reset == nil || time.Now().UTC().After(reset.ExpiresAt) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashOneTimeCode(code)), []byte(reset.CodeHash)) == 1
+}
+
+// newOneTimeCode returns a random code formatted as XXXX-XXXX
+func newOneTimeCode() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, 9)
+	for i, b := range buf {
+		if i == 4 {
+			code = append(code, '-')
+		}
+		code = append(code, oneTimeCodeAlphabet[int(b)%len(oneTimeCodeAlphabet)])
+	}
+	return string(code), nil
 }
-func (s *UserService) UpdateUserStatus(ctx context.Context, userId string, status string) error {
-	return s.repo.UpdateUserStatus(ctx, userId, status)
+
+// codeHashKey keys the hashes of one-time codes, so stored hashes can not be reversed by trying every code
+var codeHashKey []byte
+
+// LoadCodeHashKey reads auth.codeHashSecret, the key of the hashes stored for SMS login, password reset
+// and recovery codes. Changing it invalidates every outstanding code.
+func LoadCodeHashKey() error {
+	secret := viper.GetString("auth.codeHashSecret")
+	if len(secret) < 32 {
+		return errors.New("auth.codeHashSecret must be at least 32 characters")
+	}
+	codeHashKey = []byte(secret)
+	return nil
+}
+
+// hashOneTimeCode hashes a code with HMAC-SHA256 under codeHashKey, ignoring case, spaces and dashes
+func hashOneTimeCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	mac := hmac.New(sha256.New, codeHashKey)
+	mac.Write([]byte(normalized))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *UserService) UpdateUserStatus(ctx context.Context, orgUUID string, userId string, status string) error {
+	return s.repo.UpdateUserStatus(ctx, orgUUID, userId, status)
 }
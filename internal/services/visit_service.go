@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fverify_be/internal/models"
+	"fverify_be/internal/repositories"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var (
+	// ErrVisitNotFound is returned when the visit does not belong to the prospect
+	ErrVisitNotFound = errors.New("visit not found")
+	// ErrVisitInProgress is returned when checking in while a visit of the same user is still open
+	ErrVisitInProgress = errors.New("a visit is already in progress, check out first")
+	// ErrVisitCompleted is returned when checking out of a visit that has already been checked out
+	ErrVisitCompleted = errors.New("visit has already been checked out")
+	// ErrNotVisitOwner is returned when a user other than the one who checked in tries to check out
+	ErrNotVisitOwner = errors.New("only the field executive who checked in can check out")
+	// ErrNotAssignee is returned when a field executive acts on a prospect not assigned to them
+	ErrNotAssignee = errors.New("prospect is not assigned to you")
+	// ErrVisitNotAllowed is returned when checking in to a prospect that is not being visited
+	ErrVisitNotAllowed = errors.New("visits can only be checked in while the prospect is OnVisit or in progress")
+	// ErrInvalidVisitTarget is returned for an unknown visit target
+	ErrInvalidVisitTarget = errors.New("invalid visit target, use Residence or Office")
+	// ErrInvalidDeviceTime is returned when the reported device time is not RFC3339
+	ErrInvalidDeviceTime = errors.New("device_time must be an RFC3339 timestamp")
+	// ErrNoCompletedVisit is returned when submitting a prospect without a completed field visit
+	ErrNoCompletedVisit = errors.New("prospect needs at least one completed visit before it can be submitted")
+)
+
+// visitStatuses are the statuses in which a field executive may check in
+var visitStatuses = map[models.ProspectStatus]bool{
+	models.OnVisit:    true,
+	models.Progressve: true,
+}
+
+type VisitService struct {
+	prospectRepo *repositories.ProspectRepositoryImpl
+}
+
+func NewVisitService(prospectRepo *repositories.ProspectRepositoryImpl) *VisitService {
+	return &VisitService{prospectRepo: prospectRepo}
+}
+
+// CheckIn starts a visit by the prospect's assigned field executive
+func (s *VisitService) CheckIn(ctx context.Context, orgUUID string, prospectUId string, req models.VisitCheckInReq, actorUId string, actor string) (*models.ProspectVisit, error) {
+	if req.Target != models.VisitResidence && req.Target != models.VisitOffice {
+		return nil, ErrInvalidVisitTarget
+	}
+	if err := validateDeviceTime(req.DeviceTime); err != nil {
+		return nil, err
+	}
+	prospect, err := s.prospectRepo.GetByID(ctx, orgUUID, prospectUId)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+	if prospect.AssignedTo != actorUId {
+		return nil, ErrNotAssignee
+	}
+	if !visitStatuses[prospect.Status] {
+		return nil, ErrVisitNotAllowed
+	}
+	for _, visit := range prospect.Visits {
+		if visit.VisitedBy == actorUId && !visit.Completed() {
+			return nil, ErrVisitInProgress
+		}
+	}
+
+	visit := models.ProspectVisit{
+		UId:               uuid.New().String(),
+		Target:            req.Target,
+		VisitedBy:         actorUId,
+		VisitedByName:     actor,
+		CheckInTime:       time.Now().UTC().Format(time.RFC3339),
+		CheckInDeviceTime: req.DeviceTime,
+		CheckInLocation:   *req.Location,
+		CheckInDistanceKm: distanceFromTarget(prospect, req.Target, *req.Location),
+		CheckInNotes:      req.Notes,
+	}
+	err = s.prospectRepo.AddVisit(ctx, orgUUID, prospectUId, visit, models.UpdateHistory{
+		UpdatedTime:     visit.CheckInTime,
+		UpdatedComments: "Checked in to " + string(req.Target) + " visit" + distanceNote(visit.CheckInDistanceKm),
+		UpdateBy:        actor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &visit, nil
+}
+
+// CheckOut completes a visit, recording its duration and the distance from the visited address. It returns
+// the visit before and after the check-out.
+func (s *VisitService) CheckOut(ctx context.Context, orgUUID string, prospectUId string, visitUId string, req models.VisitCheckOutReq, actorUId string, actor string) (*models.ProspectVisit, *models.ProspectVisit, error) {
+	if err := validateDeviceTime(req.DeviceTime); err != nil {
+		return nil, nil, err
+	}
+	prospect, err := s.prospectRepo.GetByID(ctx, orgUUID, prospectUId)
+	if err != nil {
+		return nil, nil, ErrProspectNotFound
+	}
+	var visit *models.ProspectVisit
+	for i := range prospect.Visits {
+		if prospect.Visits[i].UId == visitUId {
+			visit = &prospect.Visits[i]
+			break
+		}
+	}
+	if visit == nil {
+		return nil, nil, ErrVisitNotFound
+	}
+	if visit.VisitedBy != actorUId {
+		return nil, nil, ErrNotVisitOwner
+	}
+	if visit.Completed() {
+		return nil, nil, ErrVisitCompleted
+	}
+
+	before := *visit
+	now := time.Now().UTC()
+	location := *req.Location
+	visit.CheckOutTime = now.Format(time.RFC3339)
+	visit.CheckOutDeviceTime = req.DeviceTime
+	visit.CheckOutLocation = &location
+	visit.CheckOutDistanceKm = distanceFromTarget(prospect, visit.Target, location)
+	visit.CheckOutNotes = req.Notes
+	if checkedIn, err := time.Parse(time.RFC3339, visit.CheckInTime); err == nil {
+		visit.DurationSeconds = int64(now.Sub(checkedIn).Seconds())
+	}
+
+	err = s.prospectRepo.CompleteVisit(ctx, orgUUID, prospectUId, *visit, models.UpdateHistory{
+		UpdatedTime:     visit.CheckOutTime,
+		UpdatedComments: fmt.Sprintf("Checked out of %s visit after %s%s", visit.Target, time.Duration(visit.DurationSeconds)*time.Second, distanceNote(visit.CheckOutDistanceKm)),
+		UpdateBy:        actor,
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Another request checked the visit out between the read and the update
+		return nil, nil, ErrVisitCompleted
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &before, visit, nil
+}
+
+// ListVisits returns every visit recorded for a prospect
+func (s *VisitService) ListVisits(ctx context.Context, orgUUID string, prospectUId string) ([]models.ProspectVisit, error) {
+	prospect, err := s.prospectRepo.GetByID(ctx, orgUUID, prospectUId)
+	if err != nil {
+		return nil, ErrProspectNotFound
+	}
+	if prospect.Visits == nil {
+		return []models.ProspectVisit{}, nil
+	}
+	return prospect.Visits, nil
+}
+
+// HasCompletedVisit reports whether a prospect has at least one checked-out visit
+func HasCompletedVisit(prospect *models.Prospect) bool {
+	for _, visit := range prospect.Visits {
+		if visit.Completed() {
+			return true
+		}
+	}
+	return false
+}
+
+func validateDeviceTime(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		return ErrInvalidDeviceTime
+	}
+	return nil
+}
+
+// distanceFromTarget measures how far a position is from the visited address, when its coordinates are known
+func distanceFromTarget(prospect *models.Prospect, target models.VisitTarget, position models.GeoPoint) *float64 {
+	location := prospect.ResidentialLocation
+	if target == models.VisitOffice {
+		location = prospect.OfficeLocation
+	}
+	if location == nil {
+		return nil
+	}
+	distance := DistanceKm(position, *location)
+	return &distance
+}
+
+func distanceNote(distance *float64) string {
+	if distance == nil {
+		return ""
+	}
+	return fmt.Sprintf(" %.2f km from the address", *distance)
+}
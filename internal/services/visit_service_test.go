@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"fverify_be/internal/models"
+)
+
+func TestHasCompletedVisit(t *testing.T) {
+	open := models.ProspectVisit{CheckInTime: "2023-04-12T10:00:05Z"}
+	done := models.ProspectVisit{CheckInTime: "2023-04-12T10:00:05Z", CheckOutTime: "2023-04-12T10:25:40Z"}
+	tests := []struct {
+		name   string
+		visits []models.ProspectVisit
+		want   bool
+	}{
+		{"no visits", nil, false},
+		{"only checked in", []models.ProspectVisit{open}, false},
+		{"checked out", []models.ProspectVisit{done}, true},
+		{"checked out and revisiting", []models.ProspectVisit{done, open}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasCompletedVisit(&models.Prospect{Visits: tt.visits}); got != tt.want {
+				t.Errorf("HasCompletedVisit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmissionNeedsCompletedVisit(t *testing.T) {
+	policy := models.RolePolicyFor(nil, nil)
+	done := []models.ProspectVisit{{CheckInTime: "2023-04-12T10:00:05Z", CheckOutTime: "2023-04-12T10:25:40Z"}}
+	tests := []struct {
+		name   string
+		from   models.ProspectStatus
+		to     models.ProspectStatus
+		role   models.Role
+		visits []models.ProspectVisit
+		want   error
+	}{
+		{"submit after a visit", models.Progressve, models.Submitted, models.FieldExecutive, done, nil},
+		{"submit without a visit", models.Progressve, models.Submitted, models.FieldExecutive, nil, ErrNoCompletedVisit},
+		{"submit during a visit", models.Progressve, models.Submitted, models.FieldExecutive, []models.ProspectVisit{{CheckInTime: "2023-04-12T10:00:05Z"}}, ErrNoCompletedVisit},
+		{"workflow checked first", models.Progressve, models.Submitted, models.Admin, nil, ErrTransitionForbidden},
+		{"other moves need no visit", models.Pending, models.OnVisit, models.FieldExecutive, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prospect := &models.Prospect{Status: tt.from, Visits: tt.visits}
+			if err := checkStatusChange(prospect, tt.to, policy, tt.role); !errors.Is(err, tt.want) {
+				t.Errorf("checkStatusChange() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
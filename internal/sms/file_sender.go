@@ -0,0 +1,45 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSender appends each message as a JSON line to a file, so tests and local tools can read the codes sent
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OutboxMessage is one line of a FileSender's file
+type OutboxMessage struct {
+	To       string `json:"to"`
+	Message  string `json:"message"`
+	SentTime string `json:"sent_time"`
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(ctx context.Context, to string, message string) error {
+	line, err := json.Marshal(OutboxMessage{To: to, Message: message, SentTime: time.Now().UTC().Format(time.RFC3339)})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,56 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// HTTPConfig configures a gateway that accepts messages as JSON posts
+type HTTPConfig struct {
+	URL       string
+	AuthToken string
+	Sender    string
+	Timeout   time.Duration
+}
+
+// HTTPSender posts {"to", "message", "sender"} to an SMS gateway, authenticating with a bearer token
+type HTTPSender struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+func NewHTTPSender(config HTTPConfig) (*HTTPSender, error) {
+	if config.URL == "" {
+		return nil, errors.New("sms.http.url is required for the http sms backend")
+	}
+	return &HTTPSender{config: config, client: &http.Client{Timeout: config.Timeout}}, nil
+}
+
+func (s *HTTPSender) Send(ctx context.Context, to string, message string) error {
+	body, err := json.Marshal(map[string]string{"to": to, "message": message, "sender": s.config.Sender})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.AuthToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("sms gateway returned " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Sender delivers text messages to mobile numbers
+type Sender interface {
+	Send(ctx context.Context, to string, message string) error
+}
+
+// NewFromConfig builds the sender selected by sms.backend ("log", "file" or "http"). It returns a nil
+// Sender when no backend is configured, so codes are never written to the log by accident.
+func NewFromConfig() (Sender, error) {
+	switch backend := viper.GetString("sms.backend"); backend {
+	case "":
+		return nil, nil
+	case "log":
+		return LogSender{}, nil
+	case "file":
+		path := viper.GetString("sms.file.path")
+		if path == "" {
+			path = "./sms_outbox.jsonl"
+		}
+		return NewFileSender(path), nil
+	case "http":
+		return NewHTTPSender(HTTPConfig{
+			URL:       viper.GetString("sms.http.url"),
+			AuthToken: viper.GetString("sms.http.authToken"),
+			Sender:    viper.GetString("sms.http.sender"),
+			Timeout:   10 * time.Second,
+		})
+	default:
+		return nil, fmt.Errorf("unknown sms backend %q", backend)
+	}
+}
+
+// LogSender writes messages to the server log instead of sending them, for local development
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, to string, message string) error {
+	log.Printf("SMS to %s: %s", to, message)
+	return nil
+}
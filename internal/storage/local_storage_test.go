@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStorage(filepath.Join(t.TempDir(), "media"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const key = "org-1/prospect-1/media-1"
+	if err := s.Put(ctx, key, strings.NewReader("photo"), 5, "image/jpeg"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	r, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	content, _ := io.ReadAll(r)
+	r.Close()
+	if string(content) != "photo" {
+		t.Errorf("Get() = %q, want %q", content, "photo")
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(s.basePath, "org-1", "prospect-1", ".upload-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrObjectNotFound", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("second Delete() error = %v, want nil", err)
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "media")
+	s, err := NewLocalStorage(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []string{"../outside", "org-1/../../outside", "", "."}
+	for _, key := range tests {
+		t.Run(key, func(t *testing.T) {
+			if err := s.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+				t.Errorf("Put(%q) stored outside the base directory", key)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(base), "outside")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a file was written outside the base directory")
+	}
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/viper"
+)
+
+// ErrObjectNotFound is returned when a key does not exist in the backend
+var ErrObjectNotFound = errors.New("object not found")
+
+// Storage is a blob store for uploaded files
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromConfig builds the backend selected by storage.backend ("local" or "s3")
+func NewFromConfig(ctx context.Context) (Storage, error) {
+	switch backend := viper.GetString("storage.backend"); backend {
+	case "", "local":
+		path := viper.GetString("storage.local.path")
+		if path == "" {
+			path = "./uploads"
+		}
+		return NewLocalStorage(path)
+	case "s3":
+		return NewS3Storage(ctx, S3Config{
+			Endpoint:  viper.GetString("storage.s3.endpoint"),
+			AccessKey: viper.GetString("storage.s3.accessKey"),
+			SecretKey: viper.GetString("storage.s3.secretKey"),
+			Bucket:    viper.GetString("storage.s3.bucket"),
+			Region:    viper.GetString("storage.s3.region"),
+			UseSSL:    viper.GetBool("storage.s3.useSSL"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}